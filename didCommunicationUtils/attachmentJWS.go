@@ -0,0 +1,110 @@
+package didCommunicationUtils
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/joseUtils"
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+)
+
+var (
+	ErrAttachmentWithoutContent = errors.New("the attachment has no inline data (base64 or json)")
+	ErrAttachmentNotSigned      = errors.New("the attachment data has no JWS")
+	ErrAttachmentHashMismatch   = errors.New("the sha256 of the attachment does not match its data")
+)
+
+// SignAttachment sets the "sha256" of the attachment data (the hex encoded SHA-256 of the content)
+// and the "jws", which is a detached JWS of the content with the flattened JSON syntax (RFC 7515, appendix F).
+// The content is the decoded "base64" data or the "json" data serialized as JSON.
+func SignAttachment(attachment *AttachmentV2, key *jwkUtils.JWK) error {
+	return signAttachment(attachment, func(jwsGo *joseUtils.JWSignatureGo) error {
+		return jwsGo.AddSignature(nil, nil, key)
+	})
+}
+
+// SignAttachmentWithKeyManager is the same as SignAttachment but the JWS is signed by the key manager.
+func SignAttachmentWithKeyManager(attachment *AttachmentV2, keyManager joseUtils.KeyManager, kid string) error {
+	return signAttachment(attachment, func(jwsGo *joseUtils.JWSignatureGo) error {
+		return jwsGo.AddSignatureWithKeyManager(nil, nil, keyManager, kid)
+	})
+}
+
+// signAttachment sets the "sha256" and the detached "jws" of the attachment data signed by the function.
+func signAttachment(attachment *AttachmentV2, addSignature func(jwsGo *joseUtils.JWSignatureGo) error) error {
+	content, err := getAttachmentContent(&attachment.Data)
+	if err != nil {
+		return err
+	}
+
+	jwsGo := joseUtils.NewDetachedJWSignature(content)
+	if err = addSignature(jwsGo); err != nil {
+		return err
+	}
+
+	serializedJWS, err := jwsGo.SerializeFlattened(json.Marshal)
+	if err != nil {
+		return err
+	}
+
+	attachment.Data.Sha256 = calculateAttachmentSha256(content)
+	attachment.Data.JWS = serializedJWS
+	return nil
+}
+
+// VerifyAttachment checks together the "sha256" and the detached "jws" of the attachment data against its content
+// (see SignAttachment) by using the public keys in the JWK Set. Both are required.
+func VerifyAttachment(attachment *AttachmentV2, keys *jwkUtils.JWKeySet) error {
+	if len(attachment.Data.JWS) == 0 {
+		return ErrAttachmentNotSigned
+	}
+
+	content, err := getAttachmentContent(&attachment.Data)
+	if err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(attachment.Data.Sha256, calculateAttachmentSha256(content)) {
+		return ErrAttachmentHashMismatch
+	}
+
+	jwsGo, err := joseUtils.DeserializeDetachedJWS(string(attachment.Data.JWS), content)
+	if err != nil {
+		return err
+	}
+
+	_, err = jwsGo.Verify(keys, joseUtils.VerifyAllSignatures)
+	return err
+}
+
+// getAttachmentContent returns the decoded "base64" data (base64url or standard encoding) or the "json" data as JSON.
+func getAttachmentContent(data *AttachmentData) ([]byte, error) {
+	if data.Base64 != "" {
+		encoding := base64.RawURLEncoding
+		if strings.ContainsAny(data.Base64, "+/") {
+			encoding = base64.RawStdEncoding
+		}
+
+		content, err := encoding.DecodeString(strings.TrimRight(data.Base64, "="))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrAttachmentWithoutContent, err)
+		}
+		return content, nil
+	}
+
+	if data.JSON != nil {
+		return json.Marshal(data.JSON)
+	}
+
+	return nil, ErrAttachmentWithoutContent
+}
+
+func calculateAttachmentSha256(content []byte) string {
+	digest := sha256.Sum256(content)
+	return hex.EncodeToString(digest[:])
+}
@@ -0,0 +1,56 @@
+package didCommunicationUtils
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/joseUtils"
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAndVerifyAttachment(t *testing.T) {
+	signKey := newTestJWK(t, jwkUtils.AlgorithmEdDSA, "did:example:practitioner#key-1")
+	keys := jwkUtils.CreateJWKeySet(&[]jwkUtils.JWK{jwkUtils.ExportPublicJWK(signKey)})
+
+	attachments := []*AttachmentV2{
+		{ID: "pdf", MediaType: "application/pdf", Data: AttachmentData{Base64: base64.RawURLEncoding.EncodeToString([]byte("%PDF-1.7"))}},
+		{ID: "fhir", MediaType: "application/fhir+json", Data: AttachmentData{JSON: map[string]interface{}{"resourceType": "Observation"}}},
+	}
+
+	for _, attachment := range attachments {
+		t.Run(attachment.ID, func(t *testing.T) {
+			require.NoError(t, SignAttachment(attachment, signKey))
+			assert.Len(t, attachment.Data.Sha256, 64)
+			assert.NotContains(t, string(attachment.Data.JWS), `"payload"`)
+
+			require.NoError(t, VerifyAttachment(attachment, keys))
+		})
+	}
+
+	// the data and the hash are replaced but the JWS does not match
+	tamperedAttachment := *attachments[0]
+	tamperedAttachment.Data.Base64 = base64.StdEncoding.EncodeToString([]byte("%PDF-1.6"))
+	assert.ErrorIs(t, VerifyAttachment(&tamperedAttachment, keys), ErrAttachmentHashMismatch)
+	tamperedAttachment.Data.Sha256 = calculateAttachmentSha256([]byte("%PDF-1.6"))
+	assert.Error(t, VerifyAttachment(&tamperedAttachment, keys))
+
+	unsignedAttachment := &AttachmentV2{Data: AttachmentData{Links: []string{"https://example.com/report.pdf"}}}
+	assert.ErrorIs(t, VerifyAttachment(unsignedAttachment, keys), ErrAttachmentNotSigned)
+	assert.ErrorIs(t, SignAttachment(unsignedAttachment, signKey), ErrAttachmentWithoutContent)
+}
+
+func TestSignAttachmentWithKeyManager(t *testing.T) {
+	keyManager := joseUtils.NewInMemoryKeyManager()
+	kid, err := keyManager.Create(joseUtils.AlgorithmES256, "")
+	require.NoError(t, err)
+	publicKey, err := keyManager.PublicJWK(kid)
+	require.NoError(t, err)
+
+	attachment := &AttachmentV2{ID: "fhir", Data: AttachmentData{JSON: map[string]interface{}{"resourceType": "Observation"}}}
+	require.NoError(t, SignAttachmentWithKeyManager(attachment, keyManager, kid))
+	assert.NoError(t, VerifyAttachment(attachment, jwkUtils.CreateJWKeySet(&[]jwkUtils.JWK{*publicKey})))
+
+	assert.ErrorIs(t, SignAttachmentWithKeyManager(attachment, keyManager, "unknown"), joseUtils.ErrKeyManagerKeyNotFound)
+}
@@ -25,7 +25,9 @@ const (
 )
 
 var (
-	ErrCodeRequestInvalid = `invalid code request`
+	ErrCodeRequestInvalid          = `invalid code request`
+	ErrCodeRequestInvalidSignature = `the signature of the request is not valid`
+	ErrCodeRequestReplayed         = `the request has already been used`
 )
 
 var (
@@ -0,0 +1,128 @@
+package didCommunicationUtils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/joseUtils"
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+)
+
+var (
+	ErrNestedJWTNotEncrypted = errors.New("the nested JWT must be encrypted after signing it")
+	ErrNestedJWTNotSigned    = errors.New("the encrypted content of the nested JWT must be a signed JWT")
+	ErrNestedJWTContentType  = errors.New("invalid content type (cty) of the nested JWT")
+)
+
+// PackNestedJWT signs the payload claims and then encrypts the compact JWS for the recipients (sign-then-encrypt),
+// as the UHC Request Objects require:
+//   - JWS header: "alg" and "kid" of the signing key and "typ" is "jwt".
+//   - JWE header: "cty" is ContentTypeDIDCommSignedJSON (the content is a signed message) and "typ" is "jwt".
+//
+// It returns the compact JWE for only one recipient or the JSON serialized JWE for several recipients.
+func PackNestedJWT(payload interface{}, signKey *jwkUtils.JWK, recipientKeys []*jwkUtils.JWK) (string, error) {
+	compactJWS, err := joseUtils.SignCompactJWT(joseUtils.Headers{joseUtils.HeaderType: HeaderTypeJWT}, payload, signKey)
+	if err != nil {
+		return "", err
+	}
+
+	return encryptNestedJWT(compactJWS, recipientKeys)
+}
+
+// PackNestedJWTWithKeyManager is the same as PackNestedJWT but the JWS is signed by the key manager.
+func PackNestedJWTWithKeyManager(payload interface{}, keyManager joseUtils.KeyManager, signKid string, recipientKeys []*jwkUtils.JWK) (string, error) {
+	compactJWS, err := joseUtils.SignCompactJWTWithKeyManager(joseUtils.Headers{joseUtils.HeaderType: HeaderTypeJWT}, payload, keyManager, signKid)
+	if err != nil {
+		return "", err
+	}
+
+	return encryptNestedJWT(compactJWS, recipientKeys)
+}
+
+// encryptNestedJWT encrypts the compact JWS for the recipients (see PackNestedJWT).
+func encryptNestedJWT(compactJWS string, recipientKeys []*jwkUtils.JWK) (string, error) {
+	opts := &joseUtils.EncryptOptions{
+		ContentType: ContentTypeDIDCommSignedJSON,
+		Type:        HeaderTypeJWT,
+	}
+
+	jwe, err := joseUtils.EncryptJWE([]byte(compactJWS), recipientKeys, opts)
+	if err != nil {
+		return "", err
+	}
+
+	if len(jwe.Recipients) == 1 {
+		return jwe.CompactSoleRecipientJWE(json.Marshal)
+	}
+	return jwe.SerializeMultiRecipientStringified()
+}
+
+// UnpackNestedJWT decrypts the nested JWT (compact or JSON serialized JWE) and then verifies the signature of the JWS.
+// It fails if the JWT is not encrypted, if the "cty" is not ContentTypeDIDCommSignedJSON or if the decrypted content is not signed.
+// It returns the decoded JWS and the verified JWE and JWS headers in the JWE and JWS fields of DIDCommBodyMetaJAR.
+func UnpackNestedJWT(serializedJWE string, decryptKey *jwkUtils.JWK, verifyKeys *jwkUtils.JWKeySet) (*joseUtils.DataJWT, *DIDCommBodyMetaJAR, error) {
+	if decryptKey == nil {
+		return nil, nil, jwkUtils.ErrMissingKeyMaterial
+	}
+
+	return unpackNestedJWT(serializedJWE, decryptKey.Kid, func(jwe *joseUtils.JWEncryptionGo) ([]byte, error) {
+		return joseUtils.DecryptJWE(jwe, decryptKey)
+	}, verifyKeys)
+}
+
+// UnpackNestedJWTWithKeyManager is the same as UnpackNestedJWT but the JWE is decrypted by the key manager.
+func UnpackNestedJWTWithKeyManager(serializedJWE string, keyManager joseUtils.KeyManager, decryptKid string, verifyKeys *jwkUtils.JWKeySet) (*joseUtils.DataJWT, *DIDCommBodyMetaJAR, error) {
+	return unpackNestedJWT(serializedJWE, decryptKid, func(jwe *joseUtils.JWEncryptionGo) ([]byte, error) {
+		return joseUtils.DecryptJWEWithKeyManager(jwe, keyManager, decryptKid)
+	}, verifyKeys)
+}
+
+// unpackNestedJWT decrypts the nested JWT with the function for the recipient's "kid" and then verifies the JWS.
+func unpackNestedJWT(serializedJWE, decryptKid string, decrypt func(jwe *joseUtils.JWEncryptionGo) ([]byte, error), verifyKeys *jwkUtils.JWKeySet) (*joseUtils.DataJWT, *DIDCommBodyMetaJAR, error) {
+	jwe, err := joseUtils.DeserializeJWE(serializedJWE)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrNestedJWTNotEncrypted, err)
+	}
+
+	if contentType, _ := joseUtils.Headers(jwe.ProtectedHeaders).ContentType(); contentType != ContentTypeDIDCommSignedJSON {
+		return nil, nil, fmt.Errorf("%w: %q", ErrNestedJWTContentType, contentType)
+	}
+
+	plaintext, err := decrypt(jwe)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dataJWT, err := joseUtils.VerifyCompactJWT(string(plaintext), verifyKeys)
+	if errors.Is(err, joseUtils.ErrInvalidJWT) {
+		return nil, nil, ErrNestedJWTNotSigned
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	meta := &DIDCommBodyMetaJAR{}
+	if err = convertHeaders(jwe.ProtectedHeaders, &meta.JWE); err != nil {
+		return nil, nil, err
+	}
+	if meta.JWE.KeyID == nil && decryptKid != "" {
+		// the recipient's "kid" is an unprotected header if there are several recipients
+		recipientKeyID := decryptKid
+		meta.JWE.KeyID = &recipientKeyID
+	}
+
+	if err = convertHeaders(dataJWT.Header, &meta.JWS); err != nil {
+		return nil, nil, err
+	}
+
+	return dataJWT, meta, nil
+}
+
+func convertHeaders(headers map[string]interface{}, headerRequest interface{}) error {
+	headersBytes, err := json.Marshal(headers)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(headersBytes, headerRequest)
+}
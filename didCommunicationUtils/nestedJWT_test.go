@@ -0,0 +1,107 @@
+package didCommunicationUtils
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/joseUtils"
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestJWK returns a new private JWK for the algorithm (see jwkUtils.GenerateJWK) with the "kid".
+func newTestJWK(t *testing.T, alg, kid string) *jwkUtils.JWK {
+	privateJWK, err := jwkUtils.GenerateJWK(alg, "")
+	require.NoError(t, err)
+	privateJWK.Kid = kid
+	return privateJWK
+}
+
+func TestPackAndUnpackNestedJWT(t *testing.T) {
+	signKey := newTestJWK(t, jwkUtils.AlgorithmEdDSA, "did:example:alice#key-1")
+	verifyKeys := jwkUtils.CreateJWKeySet(&[]jwkUtils.JWK{*signKey})
+	bobKey := newTestJWK(t, jwkUtils.AlgorithmX25519, "did:example:bob#key-x25519-1")
+	charlieKey := newTestJWK(t, jwkUtils.AlgorithmX25519, "did:example:charlie#key-x25519-1")
+	bobPublicKey := jwkUtils.ExportPublicJWK(bobKey)
+	charliePublicKey := jwkUtils.ExportPublicJWK(charlieKey)
+
+	payload := map[string]interface{}{"sub": "subjectID", "scope": ScopeOpenidGeneric}
+
+	t.Run("Sole recipient", func(t *testing.T) {
+		nestedJWT, err := PackNestedJWT(payload, signKey, []*jwkUtils.JWK{&bobPublicKey})
+		require.NoError(t, err)
+
+		dataJWT, meta, err := UnpackNestedJWT(nestedJWT, bobKey, verifyKeys)
+		require.NoError(t, err)
+		assert.Equal(t, "subjectID", dataJWT.Payload["sub"])
+		assert.Equal(t, ContentTypeDIDCommSignedJSON, meta.JWE.ContentType)
+		assert.Equal(t, HeaderTypeJWT, meta.JWE.Type)
+		assert.Equal(t, bobKey.Kid, *meta.JWE.KeyID)
+		assert.Equal(t, signKey.Kid, meta.JWS.KeyID)
+		assert.Equal(t, "EdDSA", meta.JWS.Algorithm)
+	})
+
+	t.Run("Multiple recipients", func(t *testing.T) {
+		nestedJWT, err := PackNestedJWT(payload, signKey, []*jwkUtils.JWK{&bobPublicKey, &charliePublicKey})
+		require.NoError(t, err)
+
+		_, meta, err := UnpackNestedJWT(nestedJWT, charlieKey, verifyKeys)
+		require.NoError(t, err)
+		assert.Equal(t, charlieKey.Kid, *meta.JWE.KeyID)
+	})
+
+	t.Run("Signed but not encrypted", func(t *testing.T) {
+		compactJWS, err := joseUtils.SignCompactJWT(joseUtils.Headers{}, payload, signKey)
+		require.NoError(t, err)
+
+		_, _, err = UnpackNestedJWT(compactJWS, bobKey, verifyKeys)
+		assert.ErrorIs(t, err, ErrNestedJWTNotEncrypted)
+	})
+
+	t.Run("Encrypted but not signed", func(t *testing.T) {
+		opts := &joseUtils.EncryptOptions{ContentType: ContentTypeDIDCommSignedJSON}
+		jwe, err := joseUtils.EncryptJWE([]byte(`{"sub":"subjectID"}`), []*jwkUtils.JWK{&bobPublicKey}, opts)
+		require.NoError(t, err)
+		compactJWE, err := jwe.CompactSoleRecipientJWE(json.Marshal)
+		require.NoError(t, err)
+
+		_, _, err = UnpackNestedJWT(compactJWE, bobKey, verifyKeys)
+		assert.ErrorIs(t, err, ErrNestedJWTNotSigned)
+	})
+
+	t.Run("Wrong content type", func(t *testing.T) {
+		compactJWS, err := joseUtils.SignCompactJWT(joseUtils.Headers{}, payload, signKey)
+		require.NoError(t, err)
+		jwe, err := joseUtils.EncryptJWE([]byte(compactJWS), []*jwkUtils.JWK{&bobPublicKey}, nil)
+		require.NoError(t, err)
+		compactJWE, err := jwe.CompactSoleRecipientJWE(json.Marshal)
+		require.NoError(t, err)
+
+		_, _, err = UnpackNestedJWT(compactJWE, bobKey, verifyKeys)
+		assert.ErrorIs(t, err, ErrNestedJWTContentType)
+	})
+}
+
+func TestPackAndUnpackNestedJWTWithKeyManager(t *testing.T) {
+	keyManager := joseUtils.NewInMemoryKeyManager()
+	signKid, err := keyManager.Create(jwkUtils.AlgorithmEdDSA, "")
+	require.NoError(t, err)
+	bobKid, err := keyManager.Create(jwkUtils.AlgorithmX25519, "")
+	require.NoError(t, err)
+
+	signPublicKey, err := keyManager.PublicJWK(signKid)
+	require.NoError(t, err)
+	bobPublicKey, err := keyManager.PublicJWK(bobKid)
+	require.NoError(t, err)
+
+	payload := map[string]interface{}{"sub": "subjectID"}
+	nestedJWT, err := PackNestedJWTWithKeyManager(payload, keyManager, signKid, []*jwkUtils.JWK{bobPublicKey})
+	require.NoError(t, err)
+
+	dataJWT, meta, err := UnpackNestedJWTWithKeyManager(nestedJWT, keyManager, bobKid, jwkUtils.CreateJWKeySet(&[]jwkUtils.JWK{*signPublicKey}))
+	require.NoError(t, err)
+	assert.Equal(t, "subjectID", dataJWT.Payload["sub"])
+	assert.Equal(t, bobKid, *meta.JWE.KeyID)
+	assert.Equal(t, signKid, meta.JWS.KeyID)
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/Universal-Health-Chain/common-utils-golang/didDocumentUtils"
 	"github.com/Universal-Health-Chain/common-utils-golang/joseUtils"
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
 )
 
 // DecodeAndCheckRequestCodeJAR receives a decrypted compactJWT (JWS)
@@ -14,8 +15,13 @@ import (
 // It checks payload fields "iss" and "sub" match with issuerDidKid and subjectDidKid, also "exp" and "nbf" fields.
 // Then the "response_mode", "aud" and "redirect_uri" fields can be checked by the parent function to see if they are allowed.
 func DecodeAndCheckRequestCodeJAR(compactJWT *string, recipientDidDocument *didDocumentUtils.DidDoc) (*DecodedRequestPayloadJAR, *string) {
+	return DecodeAndCheckRequestCodeJARWithReplayCache(compactJWT, recipientDidDocument, nil, nil)
+}
 
-	dataJWT, errMsg := DecodeAndCheckRequestCodeDataJWT(compactJWT, recipientDidDocument)
+// DecodeAndCheckRequestCodeJARWithReplayCache does the same as DecodeAndCheckRequestCodeJAR
+// with the signature and replay checks of DecodeAndCheckRequestCodeDataJWTWithReplayCache.
+func DecodeAndCheckRequestCodeJARWithReplayCache(compactJWT *string, recipientDidDocument *didDocumentUtils.DidDoc, senderKeys *jwkUtils.JWKeySet, replayCache joseUtils.ReplayCache) (*DecodedRequestPayloadJAR, *string) {
+	dataJWT, errMsg := DecodeAndCheckRequestCodeDataJWTWithReplayCache(compactJWT, recipientDidDocument, senderKeys, replayCache)
 	if errMsg != nil {
 		return nil, errMsg
 	}
@@ -41,6 +47,14 @@ func DecodeAndCheckRequestCodeJAR(compactJWT *string, recipientDidDocument *didD
 // It checks payload fields "iss" and "sub" match with issuerDidKid and subjectDidKid, also "exp" and "nbf" fields.
 // Then the "response_mode", "aud" and "redirect_uri" fields can be checked by the parent function to see if they are allowed.
 func DecodeAndCheckRequestCodeDataJWT(compactJWT *string, recipientDidDocument *didDocumentUtils.DidDoc) (*joseUtils.DataJWT, *string) {
+	return DecodeAndCheckRequestCodeDataJWTWithReplayCache(compactJWT, recipientDidDocument, nil, nil)
+}
+
+// DecodeAndCheckRequestCodeDataJWTWithReplayCache does the same as DecodeAndCheckRequestCodeDataJWT and then,
+// if the sender keys or the replay cache are not nil, it verifies the signature of the request with the sender keys
+// (e.g.: from the DID Document of the issuer) and checks its "jti" has not been used before (see CheckRequestReplay).
+// The "jti" is only stored once the signature is verified, so a forged request cannot block a legitimate one.
+func DecodeAndCheckRequestCodeDataJWTWithReplayCache(compactJWT *string, recipientDidDocument *didDocumentUtils.DidDoc, senderKeys *jwkUtils.JWKeySet, replayCache joseUtils.ReplayCache) (*joseUtils.DataJWT, *string) {
 	partsJWT := joseUtils.GetPartsJWT(compactJWT)
 	headerJSON, payloadBytes := joseUtils.GetInflatedDataByPartsJWT(partsJWT)
 	if payloadBytes == nil {
@@ -63,6 +77,14 @@ func DecodeAndCheckRequestCodeDataJWT(compactJWT *string, recipientDidDocument *
 		return nil, &errStr
 	}
 
+	if errMsg := verifyRequestSignature(compactJWT, senderKeys, replayCache); errMsg != nil {
+		return nil, errMsg
+	}
+
+	if errMsg := CheckRequestReplay(payloadJSON, replayCache); errMsg != nil {
+		return nil, errMsg
+	}
+
 	var signatureBytes []byte // empty but not nil
 	if partsJWT.Signature != nil {
 		signatureBytes, err = base64.RawURLEncoding.DecodeString(*partsJWT.Signature)
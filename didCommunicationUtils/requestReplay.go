@@ -0,0 +1,57 @@
+package didCommunicationUtils
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/joseUtils"
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+)
+
+// RequestReplayWindow is how long the "jti" of a request without "exp" is remembered after its "iat" to detect a replay
+// (same as the max lifetime of a request, see CheckTimeValidation).
+const RequestReplayWindow = 60 * time.Minute
+
+const requestReplayPrefix = "request:"
+
+// CheckRequestReplay stores the "jti" of the request payload in the replay cache until its "exp" (or "iat" plus RequestReplayWindow)
+// and returns ErrCodeRequestReplayed if it was already stored. It does nothing if the replay cache is nil.
+// The signature of the request must be verified before, else anyone could store the "jti" of a legitimate request.
+func CheckRequestReplay(payloadJSON map[string]interface{}, replayCache joseUtils.ReplayCache) (errMsg *string) {
+	if replayCache == nil {
+		return nil
+	}
+
+	jti, _ := payloadJSON["jti"].(string)
+	if jti == "" {
+		return &ErrCodeRequestInvalid
+	}
+
+	expiration, _ := payloadJSON["exp"].(float64)
+	issuedAt, _ := payloadJSON["iat"].(float64)
+	expiresAt := joseUtils.GetReplayExpiration(int64(expiration), int64(issuedAt), RequestReplayWindow)
+
+	err := replayCache.CheckAndStore(requestReplayPrefix+jti, expiresAt)
+	if errors.Is(err, joseUtils.ErrReplayDetected) {
+		return &ErrCodeRequestReplayed
+	}
+	if err != nil {
+		return &ErrCodeRequestInvalid
+	}
+
+	// done!
+	return nil
+}
+
+// verifyRequestSignature returns ErrCodeRequestInvalidSignature if the signature of the request cannot be verified
+// with the sender keys. It does nothing if there are no sender keys and no replay cache (the request is only decoded).
+func verifyRequestSignature(compactJWT *string, senderKeys *jwkUtils.JWKeySet, replayCache joseUtils.ReplayCache) (errMsg *string) {
+	if senderKeys == nil && replayCache == nil {
+		return nil
+	}
+
+	if _, err := joseUtils.VerifyCompactJWT(*compactJWT, senderKeys); err != nil {
+		return &ErrCodeRequestInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package didCommunicationUtils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/didDocumentUtils"
+	"github.com/Universal-Health-Chain/common-utils-golang/joseUtils"
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckRequestReplay(t *testing.T) {
+	payloadJSON := map[string]interface{}{
+		"jti": "f0d1e2c3-b4a5-4968-8776-655443322110",
+		"exp": float64(time.Now().Add(time.Minute).Unix()),
+	}
+
+	replayCache := joseUtils.NewInMemoryReplayCache(0)
+	assert.Nil(t, CheckRequestReplay(payloadJSON, replayCache))
+	assert.Equal(t, &ErrCodeRequestReplayed, CheckRequestReplay(payloadJSON, replayCache))
+
+	// the replay cache is optional
+	assert.Nil(t, CheckRequestReplay(payloadJSON, nil))
+
+	delete(payloadJSON, "jti")
+	assert.Equal(t, &ErrCodeRequestInvalid, CheckRequestReplay(payloadJSON, replayCache))
+}
+
+func TestVerifyRequestSignature(t *testing.T) {
+	signKey := newTestJWK(t, jwkUtils.AlgorithmEdDSA, "did:example:alice#key-1")
+	otherKey := newTestJWK(t, jwkUtils.AlgorithmEdDSA, "did:example:alice#key-1")
+	compactJWT, err := joseUtils.SignCompactJWT(joseUtils.Headers{}, map[string]interface{}{"jti": "f0d1e2c3"}, signKey)
+	require.NoError(t, err)
+
+	senderKeys := &jwkUtils.JWKeySet{Keys: []jwkUtils.JWK{jwkUtils.ExportPublicJWK(signKey)}}
+	otherSenderKeys := &jwkUtils.JWKeySet{Keys: []jwkUtils.JWK{jwkUtils.ExportPublicJWK(otherKey)}}
+	replayCache := joseUtils.NewInMemoryReplayCache(0)
+
+	assert.Nil(t, verifyRequestSignature(&compactJWT, senderKeys, replayCache))
+	assert.Equal(t, &ErrCodeRequestInvalidSignature, verifyRequestSignature(&compactJWT, otherSenderKeys, replayCache))
+
+	// the replay cache requires the sender keys
+	assert.Equal(t, &ErrCodeRequestInvalidSignature, verifyRequestSignature(&compactJWT, nil, replayCache))
+	assert.Nil(t, verifyRequestSignature(&compactJWT, nil, nil))
+}
+
+func TestDecodeAndCheckRequestWithReplayCache(t *testing.T) {
+	senderKid := "did:example:alice#key-1"
+	signKey := newTestJWK(t, jwkUtils.AlgorithmEdDSA, senderKid)
+	otherKey := newTestJWK(t, jwkUtils.AlgorithmEdDSA, senderKid)
+	senderKeys := &jwkUtils.JWKeySet{Keys: []jwkUtils.JWK{jwkUtils.ExportPublicJWK(signKey)}}
+
+	recipientDidDoc := &didDocumentUtils.DidDoc{
+		Service: []didDocumentUtils.Service{{
+			ID:              "didcomm",
+			Type:            "DIDCommMessaging",
+			ServiceEndpoint: TestAudienceUrl,
+		}},
+	}
+
+	codePayload := func() interface{} {
+		payload, _ := CreatePayloadForCodeRequestJWT(600, senderKid, "did:example:alice", ResponseModeJWT, TestAudienceUrl, "", PayloadTypeLoginCode)
+		return payload
+	}
+	jsonApiPayload := func() interface{} {
+		payload := CreatePayloadForJsonApiJWT(600, "com.example.organization-name.app-name", "did:example:alice", []ResourceObject{{}}, TestAudienceUrl, "")
+		return payload
+	}
+
+	decoders := []struct {
+		name       string
+		newPayload func() interface{}
+		decode     func(compactJWT *string, senderKeys *jwkUtils.JWKeySet, replayCache joseUtils.ReplayCache) *string
+	}{
+		{"DecodeAndCheckRequestCodeJARWithReplayCache", codePayload,
+			func(compactJWT *string, senderKeys *jwkUtils.JWKeySet, replayCache joseUtils.ReplayCache) *string {
+				_, errMsg := DecodeAndCheckRequestCodeJARWithReplayCache(compactJWT, recipientDidDoc, senderKeys, replayCache)
+				return errMsg
+			}},
+		{"DecodeAndCheckRequestCodeDataJWTWithReplayCache", codePayload,
+			func(compactJWT *string, senderKeys *jwkUtils.JWKeySet, replayCache joseUtils.ReplayCache) *string {
+				_, errMsg := DecodeAndCheckRequestCodeDataJWTWithReplayCache(compactJWT, recipientDidDoc, senderKeys, replayCache)
+				return errMsg
+			}},
+		{"DecodeAndCheckRequestJsonApiPayloadJARWithReplayCache", jsonApiPayload,
+			func(compactJWT *string, senderKeys *jwkUtils.JWKeySet, replayCache joseUtils.ReplayCache) *string {
+				_, errMsg := DecodeAndCheckRequestJsonApiPayloadJARWithReplayCache(compactJWT, recipientDidDoc, TestAudienceUrl, []string{}, senderKeys, replayCache)
+				return errMsg
+			}},
+		{"DecodeAndCheckRequestJsonApiDataJWTWithReplayCache", jsonApiPayload,
+			func(compactJWT *string, senderKeys *jwkUtils.JWKeySet, replayCache joseUtils.ReplayCache) *string {
+				_, errMsg := DecodeAndCheckRequestJsonApiDataJWTWithReplayCache(compactJWT, recipientDidDoc, TestAudienceUrl, []string{}, senderKeys, replayCache)
+				return errMsg
+			}},
+	}
+
+	sign := func(t *testing.T, payload interface{}, key *jwkUtils.JWK) *string {
+		compactJWT, err := joseUtils.SignCompactJWT(joseUtils.Headers{joseUtils.HeaderType: HeaderTypeJWT}, payload, key)
+		require.NoError(t, err)
+		return &compactJWT
+	}
+
+	for _, decoder := range decoders {
+		t.Run(decoder.name, func(t *testing.T) {
+			t.Run("Replayed request - expected error", func(t *testing.T) {
+				replayCache := joseUtils.NewInMemoryReplayCache(0)
+				payload := decoder.newPayload()
+				compactJWT := sign(t, payload, signKey)
+
+				assert.Nil(t, decoder.decode(compactJWT, senderKeys, replayCache))
+				assert.Equal(t, &ErrCodeRequestReplayed, decoder.decode(compactJWT, senderKeys, replayCache))
+			})
+
+			t.Run("Request signed with other key - expected error and the jti is not used", func(t *testing.T) {
+				replayCache := joseUtils.NewInMemoryReplayCache(0)
+				payload := decoder.newPayload()
+				forgedJWT := sign(t, payload, otherKey)
+				assert.Equal(t, &ErrCodeRequestInvalidSignature, decoder.decode(forgedJWT, senderKeys, replayCache))
+
+				// the same request (and "jti") signed by the sender is accepted later
+				compactJWT := sign(t, payload, signKey)
+				assert.Nil(t, decoder.decode(compactJWT, senderKeys, replayCache))
+			})
+
+			t.Run("Replay cache without sender keys - expected error", func(t *testing.T) {
+				replayCache := joseUtils.NewInMemoryReplayCache(0)
+				payload := decoder.newPayload()
+				compactJWT := sign(t, payload, signKey)
+
+				assert.Equal(t, &ErrCodeRequestInvalidSignature, decoder.decode(compactJWT, nil, replayCache))
+				// the rejected request did not use its "jti"
+				assert.Nil(t, decoder.decode(compactJWT, senderKeys, replayCache))
+			})
+		})
+	}
+}
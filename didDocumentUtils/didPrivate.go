@@ -1,5 +1,13 @@
 package didDocumentUtils
 
+import (
+	"encoding/json"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/joseUtils"
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
 type DidPrivateKey struct {
 	Alg             string  `json:"alg,omitempty"` // for Crystals-Dilithium and Crystals-Kyber
 	Crv             *string `json:"crv,omitempty"` // for non-PQC Elliptic Curve keys
@@ -8,4 +16,68 @@ type DidPrivateKey struct {
 	Kid             string  `json:"kid,omitempty" bson:"kid,omitempty"` // the JWK Thumbprint id the keyID (kid) as per RFC
 	D               *string `json:"d,omitempty" bson:"d,omitempty"`     // for Crystals-Dilithium, Crystals-Kyber and Elliptic Curve keys
 	Ds              *string `json:"ds,omitempty" bson:"ds,omitempty"`   // for Dilithium: shake256 of the private key (not the JWK) encoded in raw base64url [RFC4648]
+
+	// WrappedJWK is the private JWK as a compact JWE (see joseUtils.JWKWrapper): the only way to store the key material.
+	WrappedJWK string `json:"wrappedJwk,omitempty" bson:"wrappedJwk,omitempty"`
+}
+
+// NewWrappedDidPrivateKey returns a DidPrivateKey which can be stored: the private JWK is wrapped (encrypted)
+// and only the "alg", "crv", "kty" and "kid" members are in clear.
+func NewWrappedDidPrivateKey(jwk *jwkUtils.JWK, wrapper *joseUtils.JWKWrapper) (*DidPrivateKey, error) {
+	wrappedJWK, err := wrapper.Wrap(jwk)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DidPrivateKey{
+		Alg:        jwk.Alg,
+		Crv:        jwk.Crv,
+		Kty:        jwk.Kty,
+		Kid:        jwk.Kid,
+		WrappedJWK: wrappedJWK,
+	}, nil
+}
+
+// UnwrapJWK decrypts the wrapped private JWK.
+func (didPrivateKey *DidPrivateKey) UnwrapJWK(wrapper *joseUtils.JWKWrapper) (*jwkUtils.JWK, error) {
+	if didPrivateKey.WrappedJWK == "" {
+		return nil, jwkUtils.ErrMissingKeyMaterial
+	}
+
+	return wrapper.Unwrap(didPrivateKey.WrappedJWK)
+}
+
+// UnwrapToKeyManager decrypts the wrapped private JWK, imports it in the key manager and returns its "kid".
+func (didPrivateKey *DidPrivateKey) UnwrapToKeyManager(wrapper *joseUtils.JWKWrapper, keyImporter joseUtils.KeyImporter) (string, error) {
+	if didPrivateKey.WrappedJWK == "" {
+		return "", jwkUtils.ErrMissingKeyMaterial
+	}
+
+	return wrapper.UnwrapToKeyManager(didPrivateKey.WrappedJWK, keyImporter)
+}
+
+// IsWrapped returns false if the private key material is in clear ("d", "ds" or the private key bytes).
+func (didPrivateKey *DidPrivateKey) IsWrapped() bool {
+	return didPrivateKey.D == nil && didPrivateKey.Ds == nil && len(didPrivateKey.PrivateKeyBytes) == 0
+}
+
+// didPrivateKeyData has the DidPrivateKey members without the MarshalJSON and MarshalBSON methods.
+type didPrivateKeyData DidPrivateKey
+
+// MarshalJSON refuses to serialize a private key which is not wrapped (e.g.: in a DidData).
+func (didPrivateKey DidPrivateKey) MarshalJSON() ([]byte, error) {
+	if !didPrivateKey.IsWrapped() {
+		return nil, jwkUtils.ErrUnwrappedPrivateKey
+	}
+
+	return json.Marshal(didPrivateKeyData(didPrivateKey))
+}
+
+// MarshalBSON refuses to store a private key which is not wrapped.
+func (didPrivateKey DidPrivateKey) MarshalBSON() ([]byte, error) {
+	if !didPrivateKey.IsWrapped() {
+		return nil, jwkUtils.ErrUnwrappedPrivateKey
+	}
+
+	return bson.Marshal(didPrivateKeyData(didPrivateKey))
 }
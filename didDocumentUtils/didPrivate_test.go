@@ -0,0 +1,57 @@
+package didDocumentUtils
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/joseUtils"
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestWrappedDidPrivateKey(t *testing.T) {
+	privateJWK, err := jwkUtils.GenerateJWK("ES256", jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+	wrapper, err := joseUtils.NewPassphraseJWKWrapper([]byte("passphrase"), joseUtils.MinPBES2Count)
+	require.NoError(t, err)
+
+	didPrivateKey, err := NewWrappedDidPrivateKey(privateJWK, wrapper)
+	require.NoError(t, err)
+	assert.True(t, didPrivateKey.IsWrapped())
+	assert.Equal(t, privateJWK.Kid, didPrivateKey.Kid)
+
+	didData := DidData{DidPrivateKeys: []DidPrivateKey{*didPrivateKey}}
+	didDataJSON, err := json.Marshal(didData)
+	require.NoError(t, err)
+	assert.NotContains(t, string(didDataJSON), *privateJWK.D)
+
+	_, err = bson.Marshal(didPrivateKey)
+	require.NoError(t, err)
+
+	var decodedDidData DidData
+	require.NoError(t, json.Unmarshal(didDataJSON, &decodedDidData))
+	unwrappedJWK, err := decodedDidData.DidPrivateKeys[0].UnwrapJWK(wrapper)
+	require.NoError(t, err)
+	assert.Equal(t, privateJWK, unwrappedJWK)
+
+	keyManager := joseUtils.NewInMemoryKeyManager()
+	kid, err := decodedDidData.DidPrivateKeys[0].UnwrapToKeyManager(wrapper, keyManager)
+	require.NoError(t, err)
+	assert.Equal(t, privateJWK.Kid, kid)
+}
+
+func TestDidPrivateKey_RefuseUnwrapped(t *testing.T) {
+	privateKey := DidPrivateKey{Kid: "kid", PrivateKeyBytes: []byte{1, 2, 3}}
+
+	_, err := json.Marshal(DidData{DidPrivateKeys: []DidPrivateKey{privateKey}})
+	assert.ErrorIs(t, err, jwkUtils.ErrUnwrappedPrivateKey)
+
+	d := "private"
+	_, err = bson.Marshal(DidPrivateKey{Kid: "kid", D: &d})
+	assert.ErrorIs(t, err, jwkUtils.ErrUnwrappedPrivateKey)
+
+	_, err = (&DidPrivateKey{Kid: "kid"}).UnwrapJWK(nil)
+	assert.ErrorIs(t, err, jwkUtils.ErrMissingKeyMaterial)
+}
@@ -1,22 +1,24 @@
 module github.com/Universal-Health-Chain/common-utils-golang
 
-go 1.21
+go 1.22.0
 
 require (
+	github.com/aaronarduino/goqrsvg v0.0.0-20220419053939-17e843f1dd40
 	github.com/ajstarks/svgo v0.0.0-20211024235047-1546f124cd8b
 	github.com/boombuler/barcode v1.0.1
 	github.com/cenkalti/backoff v2.2.1+incompatible
+	github.com/cloudflare/circl v1.5.0
 	github.com/google/tink/go v1.7.0
 	github.com/google/uuid v1.3.0
 	github.com/joncalhoun/form v1.0.1
+	github.com/lestrrat-go/jwx v1.2.25
 	github.com/mr-tron/base58 v1.2.0
 	github.com/stretchr/testify v1.8.4
 	github.com/trustbloc/edge-core v0.1.8
 	github.com/trustbloc/edv v0.1.30
 	go.mongodb.org/mongo-driver v1.12.1
-	golang.org/x/crypto v0.3.0
-	github.com/aaronarduino/goqrsvg v0.0.0-20220419053939-17e843f1dd40
-	github.com/lestrrat-go/jwx v1.2.25
+	golang.org/x/crypto v0.11.1-0.20230711161743-2e82bdd1719d
+	google.golang.org/protobuf v1.27.1
 )
 
 require (
@@ -51,10 +53,10 @@ require (
 	github.com/xdg-go/scram v1.1.2 // indirect
 	github.com/xdg-go/stringprep v1.0.4 // indirect
 	github.com/youmark/pkcs8 v0.0.0-20181117223130-1be2e3e5546d // indirect
-	golang.org/x/net v0.2.0 // indirect
+	golang.org/x/net v0.10.0 // indirect
 	golang.org/x/sync v0.0.0-20220722155255-886fb9371eb4 // indirect
-	golang.org/x/sys v0.7.0 // indirect
-	golang.org/x/text v0.7.0 // indirect
+	golang.org/x/sys v0.10.0 // indirect
+	golang.org/x/text v0.11.0 // indirect
 	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 	lukechampine.com/blake3 v1.1.7 // indirect
@@ -0,0 +1,39 @@
+package httpUtils
+
+import "net/http"
+
+// errorCodeUseDPoPNonce is the error when the server requires a nonce in the DPoP proof (RFC 9449, section 12.2).
+const errorCodeUseDPoPNonce = "use_dpop_nonce"
+
+// SetDPoPNonceHeader sets the "DPoP-Nonce" HTTP header (RFC 9449, section 8.1), which the client has to use
+// in the "nonce" of the next DPoP proof. It can be sent in any response, not only in the error ones.
+func SetDPoPNonceHeader(header http.Header, nonce string) {
+	header.Set(HeaderEnumDPoPNonce, nonce)
+}
+
+// GetDPoPNonceHeader returns the "DPoP-Nonce" HTTP header (empty if it does not exist).
+func GetDPoPNonceHeader(header http.Header) string {
+	return header.Get(HeaderEnumDPoPNonce)
+}
+
+// HttpResponseUseDPoPNonce responds with the nonce the client has to use in a new DPoP proof:
+// - authorization server (wwwAuthenticate is empty): 400 (Bad Request) with the "use_dpop_nonce" error in the JSON body.
+// - resource server: 401 (Unauthorized) with the "WWW-Authenticate" challenge, e.g.: DPoP error="use_dpop_nonce".
+// The errorDescription is only used in the JSON body: the resource server has to set it in the challenge
+// ("error_description", see openidUtils.DPoPChallenge.ErrorDescription) because the 401 response has no body.
+func HttpResponseUseDPoPNonce(w http.ResponseWriter, nonce, wwwAuthenticate, errorDescription string) {
+	SetDPoPNonceHeader(w.Header(), nonce)
+	w.Header().Set("Cache-Control", "no-store")
+
+	if wwwAuthenticate != "" {
+		w.Header().Set(HeaderEnumWWWAuthenticate, wwwAuthenticate)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	responseJSON := map[string]interface{}{"error": errorCodeUseDPoPNonce}
+	if errorDescription != "" {
+		responseJSON["error_description"] = errorDescription
+	}
+	HttpResponseJSON(w, http.StatusBadRequest, &responseJSON)
+}
@@ -0,0 +1,50 @@
+package httpUtils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDPoPNonce = "eyJ7S_zG.eyJH0-Z.HX4w-7v"
+
+func TestHttpResponseUseDPoPNonce_AuthorizationServer(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	HttpResponseUseDPoPNonce(recorder, testDPoPNonce, "", "Authorization server requires nonce in DPoP proof")
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, testDPoPNonce, GetDPoPNonceHeader(recorder.Header()))
+	assert.Equal(t, "no-store", recorder.Header().Get("Cache-Control"))
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	assert.Empty(t, recorder.Header().Get(HeaderEnumWWWAuthenticate))
+
+	responseJSON := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &responseJSON))
+	assert.Equal(t, map[string]interface{}{
+		"error":             "use_dpop_nonce",
+		"error_description": "Authorization server requires nonce in DPoP proof",
+	}, responseJSON)
+
+	// the "error_description" is optional
+	recorder = httptest.NewRecorder()
+	HttpResponseUseDPoPNonce(recorder, testDPoPNonce, "", "")
+	responseJSON = map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &responseJSON))
+	assert.Equal(t, map[string]interface{}{"error": "use_dpop_nonce"}, responseJSON)
+}
+
+func TestHttpResponseUseDPoPNonce_ResourceServer(t *testing.T) {
+	challenge := `DPoP error="use_dpop_nonce", error_description="Resource server requires nonce in DPoP proof"`
+	recorder := httptest.NewRecorder()
+	HttpResponseUseDPoPNonce(recorder, testDPoPNonce, challenge, "ignored description")
+
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	assert.Equal(t, challenge, recorder.Header().Get(HeaderEnumWWWAuthenticate))
+	assert.Equal(t, testDPoPNonce, GetDPoPNonceHeader(recorder.Header()))
+	assert.Equal(t, "no-store", recorder.Header().Get("Cache-Control"))
+	assert.Empty(t, recorder.Body.String())
+}
@@ -29,6 +29,15 @@ const (
 
 	// HeaderEnumReferer is a HeaderEnum enum value
 	HeaderEnumReferer = "Referer"
+
+	// HeaderEnumDPoP is a HeaderEnum enum value
+	HeaderEnumDPoP = "DPoP"
+
+	// HeaderEnumDPoPNonce is a HeaderEnum enum value
+	HeaderEnumDPoPNonce = "DPoP-Nonce"
+
+	// HeaderEnumWWWAuthenticate is a HeaderEnum enum value
+	HeaderEnumWWWAuthenticate = "WWW-Authenticate"
 )
 
 // HttpResponseHeaders contains the HttpRequestHeaders Response Header Fields, see:
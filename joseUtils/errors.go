@@ -1,5 +1,7 @@
 package joseUtils
 
+import "errors"
+
 var ErrMsgInvalidRequest = "request is empty or invalid"
 var ErrMsgInvalidBearerAccessToken = "invalid bearer access token"
 var ErrMsgInvalidAccessTokenJWT = "invalid access token format"
@@ -41,3 +43,29 @@ var ErrMsgIssuedInTheFuture = "validation field, token issued in the future (iat
 
 // ErrMsgInvalidContentType indicates that token requires JWT cty header.
 var ErrMsgInvalidContentType = "expected content type to be JWT (cty header)"
+
+// ErrMsgMissingClaim indicates that a required claim is not in the token.
+var ErrMsgMissingClaim = "validation failed, missing required claim"
+
+// ErrMsgTooOld indicates that the token was issued (iat) before the maximum age.
+var ErrMsgTooOld = "validation failed, token is too old (iat)"
+
+// ErrMsgInvalidType indicates that the typ header is not allowed.
+var ErrMsgInvalidType = "validation failed, invalid type (typ header)"
+
+// Errors returned by the Validator, which can be checked with errors.Is.
+var (
+	ErrInvalidClaims      = errors.New(ErrMsgInvalidClaims)
+	ErrInvalidNumericDate = errors.New(ErrMsgUnmarshalNumericDate)
+	ErrInvalidIssuer      = errors.New(ErrMsgInvalidIssuer)
+	ErrInvalidSubject     = errors.New(ErrMsgInvalidSubject)
+	ErrInvalidAudience    = errors.New(ErrMsgInvalidAudience)
+	ErrInvalidID          = errors.New(ErrMsgInvalidID)
+	ErrNotValidYet        = errors.New(ErrMsgNotValidYet)
+	ErrExpired            = errors.New(ErrMsgExpired)
+	ErrIssuedInTheFuture  = errors.New(ErrMsgIssuedInTheFuture)
+	ErrInvalidContentType = errors.New(ErrMsgInvalidContentType)
+	ErrMissingClaim       = errors.New(ErrMsgMissingClaim)
+	ErrTooOld             = errors.New(ErrMsgTooOld)
+	ErrInvalidType        = errors.New(ErrMsgInvalidType)
+)
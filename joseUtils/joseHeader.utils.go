@@ -1,5 +1,12 @@
 package joseUtils
 
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+)
+
 // KeyID gets Key ID from JOSE headers.
 func (h Headers) KeyID() (string, bool) {
 	return h.stringValue(HeaderKeyID)
@@ -30,6 +37,41 @@ func (h Headers) ContentType() (string, bool) {
 	return h.stringValue(HeaderContentType)
 }
 
+// JWKSetURL gets the JWK Set URL ("jku") from JOSE headers.
+func (h Headers) JWKSetURL() (string, bool) {
+	return h.stringValue(HeaderJWKSetURL)
+}
+
+// X509URL gets the X.509 certificate chain URL ("x5u") from JOSE headers.
+func (h Headers) X509URL() (string, bool) {
+	return h.stringValue(HeaderX509URL)
+}
+
+// X509CertificateDigestSha256 gets the X.509 certificate SHA-256 thumbprint ("x5t#S256") from JOSE headers.
+func (h Headers) X509CertificateDigestSha256() (string, bool) {
+	return h.stringValue(HeaderX509CertificateDigestSha256)
+}
+
+// X509CertificateChain gets the base64 DER certificates ("x5c") from JOSE headers.
+func (h Headers) X509CertificateChain() ([]string, bool) {
+	switch x5c := h[HeaderX509CertificateChain].(type) {
+	case []string:
+		return x5c, len(x5c) > 0
+	case []interface{}:
+		certificates := make([]string, 0, len(x5c))
+		for _, certificate := range x5c {
+			encodedCertificate, ok := certificate.(string)
+			if !ok {
+				return nil, false
+			}
+			certificates = append(certificates, encodedCertificate)
+		}
+		return certificates, len(certificates) > 0
+	default:
+		return nil, false
+	}
+}
+
 func (h Headers) stringValue(key string) (string, bool) {
 	raw, ok := h[key]
 	if !ok {
@@ -40,3 +82,58 @@ func (h Headers) stringValue(key string) (string, bool) {
 
 	return str, ok
 }
+
+// EphemeralPublicKey gets the ephemeral public key ("epk") from JWE headers.
+func (h Headers) EphemeralPublicKey() (*jwkUtils.JWK, bool) {
+	raw, ok := h[HeaderEPK]
+	if !ok {
+		return nil, false
+	}
+
+	var epkBytes []byte
+	switch epk := raw.(type) {
+	case json.RawMessage:
+		epkBytes = epk
+	default:
+		var err error
+		if epkBytes, err = json.Marshal(epk); err != nil {
+			return nil, false
+		}
+	}
+
+	epk := &jwkUtils.JWK{}
+	if err := json.Unmarshal(epkBytes, epk); err != nil {
+		return nil, false
+	}
+
+	return epk, true
+}
+
+// AgreementPartyUInfo gets the decoded agreement PartyUInfo ("apu") from JWE headers.
+func (h Headers) AgreementPartyUInfo() ([]byte, bool) {
+	return h.bytesValue(HeaderAPU)
+}
+
+// AgreementPartyVInfo gets the decoded agreement PartyVInfo ("apv") from JWE headers.
+func (h Headers) AgreementPartyVInfo() ([]byte, bool) {
+	return h.bytesValue(HeaderAPV)
+}
+
+// EncapsulatedKey gets the decoded KEM ciphertext ("ek") from JWE headers.
+func (h Headers) EncapsulatedKey() ([]byte, bool) {
+	return h.bytesValue(HeaderEncapsulatedKey)
+}
+
+func (h Headers) bytesValue(key string) ([]byte, bool) {
+	str, ok := h.stringValue(key)
+	if !ok {
+		return nil, false
+	}
+
+	value, err := base64.RawURLEncoding.DecodeString(str)
+	if err != nil {
+		return nil, false
+	}
+
+	return value, true
+}
@@ -102,6 +102,9 @@ const (
 
 	// HeaderEPK is used by JWE applications to wrap/unwrap the CEK for a recipient.
 	HeaderEPK = "epk" // JSON
+
+	// HeaderEncapsulatedKey is the KEM ciphertext used by JWE applications to decapsulate the shared secret of a recipient.
+	HeaderEncapsulatedKey = "ek" // string
 )
 
 // Header defined in https://tools.ietf.org/html/rfc7797
@@ -124,6 +127,39 @@ const (
 	A256CBCHS512ALG = "A256CBC-HS512"
 )
 
+// Key management algorithms for the JWE "alg" header as per https://tools.ietf.org/html/rfc7518#section-4.1
+const (
+	// ECDHESALG represents the Elliptic Curve Diffie-Hellman Ephemeral Static key agreement (direct, one recipient only).
+	ECDHESALG = "ECDH-ES"
+	// ECDHESA256KWALG represents ECDH-ES with the derived key used to wrap the CEK with "A256KW".
+	ECDHESA256KWALG = "ECDH-ES+A256KW"
+	// ECDH1PUA256KWALG represents the sender authenticated ECDH-1PU key agreement with the CEK wrapped by "A256KW"
+	// (draft-madden-jose-ecdh-1pu-04), used by the DIDComm v2 authcrypt envelopes.
+	ECDH1PUA256KWALG = "ECDH-1PU+A256KW"
+	// A256KWALG represents the CEK wrapped by "A256KW" with a shared symmetric Key Encryption Key (KEK).
+	A256KWALG = "A256KW"
+	// PBES2HS512A256KWALG represents the CEK wrapped by "A256KW" with a key derived from a passphrase by PBKDF2 with HMAC SHA-512.
+	PBES2HS512A256KWALG = "PBES2-HS512+A256KW"
+)
+
+// Post-quantum key management algorithms for the JWE "alg" header (the "alg" of the recipient's PQK key).
+// The KEM ciphertext is in the "ek" header and the shared secret is used as the Z value of the Concat KDF,
+// either to derive the CEK (direct, one recipient only) or to wrap the CEK with "A256KW".
+const (
+	// MLKEM768ALG represents the direct key encapsulation with ML-KEM-768 (FIPS 203).
+	MLKEM768ALG = "ML-KEM-768"
+	// MLKEM768A256KWALG represents ML-KEM-768 with the derived key used to wrap the CEK with "A256KW".
+	MLKEM768A256KWALG = "ML-KEM-768+A256KW"
+	// MLKEM1024ALG represents the direct key encapsulation with ML-KEM-1024 (FIPS 203).
+	MLKEM1024ALG = "ML-KEM-1024"
+	// MLKEM1024A256KWALG represents ML-KEM-1024 with the derived key used to wrap the CEK with "A256KW".
+	MLKEM1024A256KWALG = "ML-KEM-1024+A256KW"
+	// X25519MLKEM768ALG represents the direct key encapsulation with the hybrid X25519 + ML-KEM-768 KEM.
+	X25519MLKEM768ALG = "X25519MLKEM768"
+	// X25519MLKEM768A256KWALG represents the hybrid X25519 + ML-KEM-768 KEM with the derived key used to wrap the CEK with "A256KW".
+	X25519MLKEM768A256KWALG = "X25519MLKEM768+A256KW"
+)
+
 // Headers represents JOSE headers.
 type Headers map[string]interface{}
 
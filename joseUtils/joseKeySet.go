@@ -0,0 +1,25 @@
+package joseUtils
+
+import (
+	"errors"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+)
+
+var ErrJWKSetURLMismatch = errors.New("the JWK Set URL (jku) header is not the URL of the JWK Set")
+
+// SelectHeaderJWK returns the best key of the JWK Set for the "alg", "kid" and "x5t#S256" headers
+// and the operation (e.g.: jwkUtils.KeyOperationVerify), see jwkUtils.JWKeySet.SelectKey.
+// If the "jku" header exists and keySetURL is not empty (e.g.: the "jwks_uri" of the issuer), they must be the same.
+func SelectHeaderJWK(headers Headers, keys *jwkUtils.JWKeySet, keySetURL, operation string) (*jwkUtils.JWK, error) {
+	if jku, found := headers.JWKSetURL(); found && keySetURL != "" && jku != keySetURL {
+		return nil, ErrJWKSetURLMismatch
+	}
+
+	selector := jwkUtils.KeySelector{Operation: operation}
+	selector.Alg, _ = headers.Algorithm()
+	selector.Kid, _ = headers.KeyID()
+	selector.X5tS256, _ = headers.X509CertificateDigestSha256()
+
+	return keys.SelectKey(selector)
+}
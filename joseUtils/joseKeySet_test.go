@@ -0,0 +1,30 @@
+package joseUtils
+
+import (
+	"testing"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectHeaderJWK(t *testing.T) {
+	signKey, err := jwkUtils.GenerateJWK("ES256", jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+	encKey, err := jwkUtils.GenerateJWK("ES256", jwkUtils.JWKeyEncType)
+	require.NoError(t, err)
+	keys := jwkUtils.CreateJWKeySet(&[]jwkUtils.JWK{*encKey, *signKey})
+	jwksURL := "https://issuer.example.com/.well-known/jwks.json"
+
+	headers := Headers{HeaderAlgorithm: "ES256", HeaderKeyID: signKey.Kid, HeaderJWKSetURL: jwksURL}
+	key, err := SelectHeaderJWK(headers, keys, jwksURL, jwkUtils.KeyOperationVerify)
+	require.NoError(t, err)
+	assert.Equal(t, signKey.X, key.X)
+
+	_, err = SelectHeaderJWK(headers, keys, "https://other.example.com/jwks.json", jwkUtils.KeyOperationVerify)
+	assert.ErrorIs(t, err, ErrJWKSetURLMismatch)
+
+	headers = Headers{HeaderAlgorithm: "ES256", HeaderKeyID: encKey.Kid}
+	_, err = SelectHeaderJWK(headers, keys, jwksURL, jwkUtils.KeyOperationVerify)
+	assert.ErrorIs(t, err, jwkUtils.ErrKeyUseMismatch)
+}
@@ -0,0 +1,45 @@
+package joseUtils
+
+import (
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+)
+
+// GetX509HeaderJWK validates the "x5c" certificate chain of the JOSE headers with the verifier,
+// checks the "x5t#S256" header (if any) and returns the public JWK of the leaf certificate
+// (e.g.: to verify the JWS by using a JWK Set with this key).
+// The verifier with the trusted roots is required, else jwkUtils.ErrMissingX509Roots is returned.
+func GetX509HeaderJWK(headers Headers, verifier *jwkUtils.X509ChainVerifier) (*jwkUtils.JWK, error) {
+	if verifier == nil || verifier.Roots == nil {
+		return nil, jwkUtils.ErrMissingX509Roots
+	}
+
+	x5c, found := headers.X509CertificateChain()
+	if !found {
+		return nil, jwkUtils.ErrMissingX509Chain
+	}
+
+	certificates, err := jwkUtils.ParseX509CertificateChain(x5c)
+	if err != nil {
+		return nil, err
+	}
+
+	leafJWK, err := jwkUtils.FromCryptoKey(certificates[0].PublicKey)
+	if err != nil {
+		return nil, jwkUtils.ErrUnsupportedX509PublicKey
+	}
+
+	leafJWK.X5c = x5c
+	if x5t, found := headers.X509CertificateDigestSha256(); found {
+		leafJWK.X5tS256 = &x5t
+	}
+
+	if _, err = verifier.VerifyJWK(leafJWK); err != nil {
+		return nil, err
+	}
+
+	if kid, found := headers.KeyID(); found {
+		leafJWK.Kid = kid
+	}
+
+	return leafJWK, nil
+}
@@ -0,0 +1,81 @@
+package joseUtils
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetX509HeaderJWK(t *testing.T) {
+	signKey := newTestJWK(t, AlgorithmES256, "", "kid-seal")
+	leafKey, err := jwkUtils.GetECDSAPrivateKey(signKey)
+	require.NoError(t, err)
+
+	rootKey, err := ecdsa.GenerateKey(jwkUtils.GetEllipticCurve(jwkUtils.CurveP256), rand.Reader)
+	require.NoError(t, err)
+	rootTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Root CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	rootDER, err := x509.CreateCertificate(rand.Reader, rootTemplate, rootTemplate, &rootKey.PublicKey, rootKey)
+	require.NoError(t, err)
+	root, err := x509.ParseCertificate(rootDER)
+	require.NoError(t, err)
+
+	leafTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "Test Seal"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, root, &leafKey.PublicKey, rootKey)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(leafDER)
+	require.NoError(t, err)
+
+	headers := Headers{
+		HeaderX509CertificateChain:        jwkUtils.EncodeX509CertificateChain([]*x509.Certificate{leaf, root}),
+		HeaderX509CertificateDigestSha256: jwkUtils.CalculateX509ThumbprintSHA256(leaf),
+	}
+	compact, err := SignCompactJWT(headers, map[string]interface{}{"sub": "subjectID"}, signKey)
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+	verifier := jwkUtils.NewX509ChainVerifier(roots)
+
+	unverifiedJWT := GetDataJWT(&compact)
+	require.NotNil(t, unverifiedJWT)
+	leafJWK, err := GetX509HeaderJWK(unverifiedJWT.Header, verifier)
+	require.NoError(t, err)
+	assert.Equal(t, "kid-seal", leafJWK.Kid)
+
+	dataJWT, err := VerifyCompactJWT(compact, jwkUtils.CreateJWKeySet(&[]jwkUtils.JWK{*leafJWK}))
+	require.NoError(t, err)
+	assert.Equal(t, "subjectID", dataJWT.Payload["sub"])
+
+	_, err = GetX509HeaderJWK(unverifiedJWT.Header, jwkUtils.NewX509ChainVerifier(x509.NewCertPool()))
+	assert.ErrorIs(t, err, jwkUtils.ErrInvalidX509Chain)
+
+	_, err = GetX509HeaderJWK(Headers{}, verifier)
+	assert.ErrorIs(t, err, jwkUtils.ErrMissingX509Chain)
+
+	_, err = GetX509HeaderJWK(unverifiedJWT.Header, nil)
+	assert.ErrorIs(t, err, jwkUtils.ErrMissingX509Roots)
+
+	_, err = GetX509HeaderJWK(unverifiedJWT.Header, &jwkUtils.X509ChainVerifier{})
+	assert.ErrorIs(t, err, jwkUtils.ErrMissingX509Roots)
+}
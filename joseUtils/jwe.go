@@ -81,6 +81,7 @@ type RecipientHeaders struct {
 	Tag string          `json:"tag,omitempty"`
 	KID string          `json:"kid,omitempty"`
 	EPK json.RawMessage `json:"epk,omitempty"`
+	EK  string          `json:"ek,omitempty"`
 }
 
 const (
@@ -188,7 +189,10 @@ func (jweGo *JWEncryptionGo) SerializeMultiRecipientRawJSON() (json.RawMessage,
 func (jweGo *JWEncryptionGo) PrepareHeadersJSON(jsonMarshal JsonMarshalFunc) (string, json.RawMessage, error) {
 	var b64ProtectedHeaders string
 
-	if jweGo.ProtectedHeaders != nil {
+	if jweGo.OrigProtectedHders != "" {
+		// the original encoding is kept because it is part of the authenticated data
+		b64ProtectedHeaders = jweGo.OrigProtectedHders
+	} else if jweGo.ProtectedHeaders != nil {
 		protectedHeadersJSON, err := jsonMarshal(jweGo.ProtectedHeaders)
 		if err != nil {
 			return "", nil, err
@@ -283,12 +287,15 @@ func (jweGo *JWEncryptionGo) CompactSoleRecipientJWE(jsonMarshal JsonMarshalFunc
 		return "", errPerRecipientHeaderUnsupported
 	}
 
-	protectedHeadersJSON, err := jsonMarshal(jweGo.ProtectedHeaders)
-	if err != nil {
-		return "", err
-	}
+	b64ProtectedHeader := jweGo.OrigProtectedHders
+	if b64ProtectedHeader == "" {
+		protectedHeadersJSON, err := jsonMarshal(jweGo.ProtectedHeaders)
+		if err != nil {
+			return "", err
+		}
 
-	b64ProtectedHeader := base64.RawURLEncoding.EncodeToString(protectedHeadersJSON)
+		b64ProtectedHeader = base64.RawURLEncoding.EncodeToString(protectedHeadersJSON)
+	}
 	b64EncryptedKey := base64.RawURLEncoding.EncodeToString([]byte(jweGo.Recipients[0].EncryptedKey))
 	b64IV := base64.RawURLEncoding.EncodeToString([]byte(jweGo.IV))
 	b64Ciphertext := base64.RawURLEncoding.EncodeToString([]byte(jweGo.Ciphertext))
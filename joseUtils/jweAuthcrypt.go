@@ -0,0 +1,299 @@
+package joseUtils
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+)
+
+var (
+	ErrMissingSenderKeyID = errors.New("the sender key ID (skid) is required for an authcrypt JWE")
+	ErrMissingSenderKey   = errors.New("the sender's public key is required to decrypt an authcrypt JWE")
+)
+
+// SenderKeyResolver returns the sender's public key for the "skid" header of an authcrypt JWE
+// (e.g.: from the "keyAgreement" of the sender's DID Document).
+type SenderKeyResolver func(skid string) (*jwkUtils.JWK, error)
+
+// NewJWKeySetSenderKeyResolver returns a SenderKeyResolver for the keys of the set whose "kid" is the "skid".
+func NewJWKeySetSenderKeyResolver(keys *jwkUtils.JWKeySet) SenderKeyResolver {
+	return func(skid string) (*jwkUtils.JWK, error) {
+		if keys != nil {
+			for i := range keys.Keys {
+				if keys.Keys[i].Kid == skid {
+					return &keys.Keys[i], nil
+				}
+			}
+		}
+
+		return nil, fmt.Errorf("%w: %q", ErrKeyNotFound, skid)
+	}
+}
+
+// EncryptAuthcryptJWE encrypts the plaintext with ECDH-1PU+A256KW and A256CBC-HS512 (DIDComm v2 authcrypt),
+// so the recipients can authenticate the sender. The "skid" header is the opts.SenderKeyID or else the sender's "kid",
+// and the ephemeral key ("epk") is shared by all the recipients, which must use the sender's curve.
+// The key agreement is Z = Ze || Zs, where Ze = ECDH(epk, recipient) and Zs = ECDH(sender, recipient),
+// and the authentication tag of the content is included in the Concat KDF to bind the sender to the ciphertext.
+func EncryptAuthcryptJWE(plaintext []byte, senderKey *jwkUtils.JWK, recipients []*jwkUtils.JWK, opts *EncryptOptions) (*JWEncryptionGo, error) {
+	senderPrivateKey, err := jwkUtils.GetECDHPrivateKey(senderKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return encryptAuthcryptJWE(plaintext, senderKey, newECDHSharedSecretFunc(senderPrivateKey), recipients, opts)
+}
+
+// EncryptAuthcryptJWEWithKeyManager is the same as EncryptAuthcryptJWE but the sender's shared secrets (Zs)
+// are derived by the key manager.
+func EncryptAuthcryptJWEWithKeyManager(plaintext []byte, keyManager KeyManager, senderKid string, recipients []*jwkUtils.JWK, opts *EncryptOptions) (*JWEncryptionGo, error) {
+	senderPublicKey, err := keyManager.PublicJWK(senderKid)
+	if err != nil {
+		return nil, err
+	}
+
+	return encryptAuthcryptJWE(plaintext, senderPublicKey, func(publicKey *jwkUtils.JWK) ([]byte, error) {
+		return keyManager.DeriveSharedSecret(senderKid, publicKey)
+	}, recipients, opts)
+}
+
+// encryptAuthcryptJWE encrypts the plaintext for the recipients with the sender's public key and shared secret function.
+func encryptAuthcryptJWE(plaintext []byte, senderKey *jwkUtils.JWK, deriveSenderSecret ecdhSharedSecretFunc, recipients []*jwkUtils.JWK, opts *EncryptOptions) (*JWEncryptionGo, error) {
+	authcryptOpts := EncryptOptions{}
+	if opts != nil {
+		authcryptOpts = *opts
+	}
+
+	if len(recipients) == 0 {
+		return nil, ErrMissingRecipients
+	}
+
+	senderPublicKey, err := jwkUtils.GetECDHPublicKey(senderKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if authcryptOpts.SenderKeyID == "" {
+		authcryptOpts.SenderKeyID = senderKey.Kid
+	}
+	if authcryptOpts.SenderKeyID == "" {
+		return nil, ErrMissingSenderKeyID
+	}
+
+	if authcryptOpts.Encryption == "" {
+		authcryptOpts.Encryption = A256CBCHS512ALG
+	} else if authcryptOpts.Encryption != A256CBCHS512ALG {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedEncryption, authcryptOpts.Encryption)
+	}
+
+	encryption, err := getContentEncryption(authcryptOpts.Encryption)
+	if err != nil {
+		return nil, err
+	}
+
+	protectedHeaders := createProtectedHeadersJWE(ECDH1PUA256KWALG, authcryptOpts.Encryption, recipients, &authcryptOpts)
+	apu, _ := protectedHeaders.AgreementPartyUInfo()
+	apv, _ := protectedHeaders.AgreementPartyVInfo()
+
+	ephemeralKey, err := senderPublicKey.Curve().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	epk, err := jwkUtils.GetPublicJWKFromECDH(ephemeralKey.PublicKey())
+	if err != nil {
+		return nil, err
+	}
+
+	protectedHeaders[HeaderEPK] = epk
+	if len(recipients) == 1 && recipients[0].Kid != "" {
+		protectedHeaders[HeaderKeyID] = recipients[0].Kid
+	}
+
+	jwe := &JWEncryptionGo{AAD: string(authcryptOpts.AAD)}
+	if err = jwe.setProtectedHeaders(protectedHeaders); err != nil {
+		return nil, err
+	}
+
+	// the content is encrypted first because the tag is required to derive the key encryption keys
+	cek := make([]byte, encryption.keySize)
+	if _, err = rand.Read(cek); err != nil {
+		return nil, err
+	}
+
+	iv, ciphertext, tag, err := encryption.encrypt(cek, plaintext, jwe.computeAuthenticatedData())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, recipient := range recipients {
+		if recipient.Use != nil && *recipient.Use == jwkUtils.JWKeySignType {
+			return nil, fmt.Errorf("%w: the recipient key %q is for signatures", ErrUnsupportedKey, recipient.Kid)
+		}
+
+		recipientPublicKey, err := jwkUtils.GetECDHPublicKey(recipient)
+		if err != nil {
+			return nil, err
+		}
+
+		if recipientPublicKey.Curve() != senderPublicKey.Curve() {
+			return nil, fmt.Errorf("%w: the recipient key %q does not use the sender's curve", ErrUnsupportedKey, recipient.Kid)
+		}
+
+		ephemeralSecret, err := ephemeralKey.ECDH(recipientPublicKey)
+		if err != nil {
+			return nil, err
+		}
+
+		senderSecret, err := deriveSenderSecret(recipient)
+		if err != nil {
+			return nil, err
+		}
+
+		kek := concatKDF(append(ephemeralSecret, senderSecret...), ECDH1PUA256KWALG, apu, apv, 256, tag)
+		wrappedKey, err := aesKeyWrap(kek, cek)
+		if err != nil {
+			return nil, err
+		}
+
+		jweRecipient := &RecipientJWE{EncryptedKey: string(wrappedKey)}
+		if len(recipients) > 1 {
+			jweRecipient.Header = &RecipientHeaders{KID: recipient.Kid}
+		}
+		jwe.Recipients = append(jwe.Recipients, jweRecipient)
+	}
+
+	jwe.IV = string(iv)
+	jwe.Ciphertext = string(ciphertext)
+	jwe.Tag = string(tag)
+	return jwe, nil
+}
+
+// DecryptAuthcryptJWE decrypts an ECDH-1PU+A256KW JWE with the private key of a recipient,
+// authenticating the sender with the public key returned by the resolver for the "skid" header.
+// If the key has a "kid" only the recipients without "kid" or with the same "kid" are tried.
+func DecryptAuthcryptJWE(jwe *JWEncryptionGo, key *jwkUtils.JWK, resolveSenderKey SenderKeyResolver) ([]byte, error) {
+	privateKey, err := jwkUtils.GetECDHPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return decryptAuthcryptJWE(jwe, key, newECDHSharedSecretFunc(privateKey), resolveSenderKey)
+}
+
+// DecryptAuthcryptJWEWithKeyManager is the same as DecryptAuthcryptJWE but the shared secrets (Ze and Zs)
+// are derived by the key manager.
+func DecryptAuthcryptJWEWithKeyManager(jwe *JWEncryptionGo, keyManager KeyManager, kid string, resolveSenderKey SenderKeyResolver) ([]byte, error) {
+	publicKey, err := keyManager.PublicJWK(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	return decryptAuthcryptJWE(jwe, publicKey, func(otherPublicKey *jwkUtils.JWK) ([]byte, error) {
+		return keyManager.DeriveSharedSecret(kid, otherPublicKey)
+	}, resolveSenderKey)
+}
+
+// decryptAuthcryptJWE decrypts the JWE with the recipient's public key and shared secret function.
+func decryptAuthcryptJWE(jwe *JWEncryptionGo, key *jwkUtils.JWK, deriveSharedSecret ecdhSharedSecretFunc, resolveSenderKey SenderKeyResolver) ([]byte, error) {
+	if jwe == nil || len(jwe.Recipients) == 0 {
+		return nil, ErrMissingRecipients
+	}
+
+	if resolveSenderKey == nil {
+		return nil, ErrMissingSenderKey
+	}
+
+	publicKey, err := jwkUtils.GetECDHPublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aad, err := jwe.authenticatedData()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, recipient := range jwe.Recipients {
+		headers, err := jwe.recipientHeaders(recipient)
+		if err != nil {
+			return nil, err
+		}
+
+		if kid, _ := headers.KeyID(); kid != "" && key.Kid != "" && kid != key.Kid {
+			continue
+		}
+
+		if alg, _ := headers.Algorithm(); alg != ECDH1PUA256KWALG {
+			return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, alg)
+		}
+
+		if enc, _ := headers.Encryption(); enc != A256CBCHS512ALG {
+			return nil, fmt.Errorf("%w: %q", ErrUnsupportedEncryption, enc)
+		}
+
+		skid, _ := headers.SenderKeyID()
+		if skid == "" {
+			return nil, ErrMissingSenderKeyID
+		}
+
+		senderKey, err := resolveSenderKey(skid)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMissingSenderKey, err)
+		}
+
+		senderPublicKey, err := jwkUtils.GetECDHPublicKey(senderKey)
+		if err != nil || senderPublicKey.Curve() != publicKey.Curve() {
+			return nil, fmt.Errorf("%w: the sender key %q cannot be used", ErrUnsupportedKey, skid)
+		}
+
+		epk, found := headers.EphemeralPublicKey()
+		if !found {
+			return nil, ErrMissingKeyAgreementHeader
+		}
+
+		epkPublicKey, err := jwkUtils.GetECDHPublicKey(epk)
+		if err != nil || epkPublicKey.Curve() != publicKey.Curve() {
+			return nil, ErrUnsupportedKey
+		}
+
+		ephemeralSecret, err := deriveSharedSecret(epk)
+		if err != nil {
+			return nil, err
+		}
+
+		senderSecret, err := deriveSharedSecret(senderKey)
+		if err != nil {
+			return nil, err
+		}
+
+		apu, _ := headers.AgreementPartyUInfo()
+		apv, _ := headers.AgreementPartyVInfo()
+		kek := concatKDF(append(ephemeralSecret, senderSecret...), ECDH1PUA256KWALG, apu, apv, 256, []byte(jwe.Tag))
+		cek, err := aesKeyUnwrap(kek, []byte(recipient.EncryptedKey))
+		if err != nil {
+			continue
+		}
+
+		return decryptAESCBCHMACSHA512(cek, []byte(jwe.IV), []byte(jwe.Ciphertext), []byte(jwe.Tag), aad)
+	}
+
+	return nil, ErrRecipientNotFound
+}
+
+// ecdhSharedSecretFunc returns the ECDH shared secret of a private key with the public key of the other party.
+type ecdhSharedSecretFunc func(publicKey *jwkUtils.JWK) ([]byte, error)
+
+// newECDHSharedSecretFunc returns the ecdhSharedSecretFunc of the private key.
+func newECDHSharedSecretFunc(privateKey *ecdh.PrivateKey) ecdhSharedSecretFunc {
+	return func(publicKey *jwkUtils.JWK) ([]byte, error) {
+		ecdhPublicKey, err := jwkUtils.GetECDHPublicKey(publicKey)
+		if err != nil || ecdhPublicKey.Curve() != privateKey.Curve() {
+			return nil, ErrUnsupportedKey
+		}
+		return privateKey.ECDH(ecdhPublicKey)
+	}
+}
@@ -0,0 +1,83 @@
+package joseUtils
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptAndDecryptAuthcryptJWE(t *testing.T) {
+	senderKey := newTestJWK(t, jwkUtils.AlgorithmX25519, "", "did:example:alice#key-x25519-1")
+	bobKey := newTestJWK(t, jwkUtils.AlgorithmX25519, "", "did:example:bob#key-x25519-1")
+	charlieKey := newTestJWK(t, jwkUtils.AlgorithmX25519, "", "did:example:charlie#key-x25519-1")
+
+	senderPublicKey := jwkUtils.ExportPublicJWK(senderKey)
+	bobPublicKey := jwkUtils.ExportPublicJWK(bobKey)
+	charliePublicKey := jwkUtils.ExportPublicJWK(charlieKey)
+	resolveSenderKey := NewJWKeySetSenderKeyResolver(jwkUtils.CreateJWKeySet(&[]jwkUtils.JWK{senderPublicKey}))
+
+	t.Run("Sole recipient (compact serialization)", func(t *testing.T) {
+		jwe, err := EncryptAuthcryptJWE([]byte("secret message"), senderKey, []*jwkUtils.JWK{&bobPublicKey}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, ECDH1PUA256KWALG, jwe.ProtectedHeaders[HeaderAlgorithm])
+		assert.Equal(t, A256CBCHS512ALG, jwe.ProtectedHeaders[HeaderEncryption])
+		assert.Equal(t, senderKey.Kid, jwe.ProtectedHeaders[HeaderSenderKeyID])
+
+		compactJWE, err := jwe.CompactSoleRecipientJWE(json.Marshal)
+		require.NoError(t, err)
+
+		deserializedJWE, err := DeserializeJWE(compactJWE)
+		require.NoError(t, err)
+
+		plaintext, err := DecryptAuthcryptJWE(deserializedJWE, bobKey, resolveSenderKey)
+		require.NoError(t, err)
+		assert.Equal(t, "secret message", string(plaintext))
+
+		_, err = DecryptJWE(deserializedJWE, bobKey)
+		assert.ErrorIs(t, err, ErrMissingSenderKey)
+	})
+
+	t.Run("Multiple recipients", func(t *testing.T) {
+		recipients := []*jwkUtils.JWK{&bobPublicKey, &charliePublicKey}
+		jwe, err := EncryptAuthcryptJWE([]byte("secret message"), senderKey, recipients, nil)
+		require.NoError(t, err)
+
+		serializedJWE, err := jwe.SerializeMultiRecipientStringified()
+		require.NoError(t, err)
+
+		deserializedJWE, err := DeserializeJWE(serializedJWE)
+		require.NoError(t, err)
+
+		for _, recipientKey := range []*jwkUtils.JWK{bobKey, charlieKey} {
+			plaintext, err := DecryptAuthcryptJWE(deserializedJWE, recipientKey, resolveSenderKey)
+			require.NoError(t, err)
+			assert.Equal(t, "secret message", string(plaintext))
+		}
+	})
+
+	t.Run("Wrong sender key", func(t *testing.T) {
+		jwe, err := EncryptAuthcryptJWE([]byte("secret message"), senderKey, []*jwkUtils.JWK{&bobPublicKey}, nil)
+		require.NoError(t, err)
+
+		impostorKey := jwkUtils.ExportPublicJWK(newTestJWK(t, jwkUtils.AlgorithmX25519, "", senderKey.Kid))
+		resolveImpostorKey := NewJWKeySetSenderKeyResolver(jwkUtils.CreateJWKeySet(&[]jwkUtils.JWK{impostorKey}))
+		_, err = DecryptAuthcryptJWE(jwe, bobKey, resolveImpostorKey)
+		assert.ErrorIs(t, err, ErrRecipientNotFound)
+
+		_, err = DecryptAuthcryptJWE(jwe, bobKey, NewJWKeySetSenderKeyResolver(nil))
+		assert.ErrorIs(t, err, ErrMissingSenderKey)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		p256Key := jwkUtils.ExportPublicJWK(newTestJWK(t, AlgorithmES256, jwkUtils.JWKeyEncType, "kid-p256"))
+		_, err := EncryptAuthcryptJWE([]byte("secret message"), senderKey, []*jwkUtils.JWK{&p256Key}, nil)
+		assert.ErrorIs(t, err, ErrUnsupportedKey)
+
+		opts := &EncryptOptions{Encryption: A256GCMALG}
+		_, err = EncryptAuthcryptJWE([]byte("secret message"), senderKey, []*jwkUtils.JWK{&bobPublicKey}, opts)
+		assert.ErrorIs(t, err, ErrUnsupportedEncryption)
+	})
+}
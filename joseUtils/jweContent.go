@@ -0,0 +1,152 @@
+package joseUtils
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnsupportedEncryption = errors.New("unsupported content encryption algorithm")
+	ErrDecryption            = errors.New("JWE decryption error")
+)
+
+// contentEncryption has the functions to encrypt and decrypt the JWE plaintext for a given "enc" value.
+//   - keySize is the size of the Content Encryption Key (CEK) in bytes.
+//   - encrypt returns a new random IV, the ciphertext and the authentication tag.
+//   - decrypt returns the plaintext after checking the authentication tag.
+type contentEncryption struct {
+	keySize int
+	encrypt func(cek, plaintext, aad []byte) (iv, ciphertext, tag []byte, err error)
+	decrypt func(cek, iv, ciphertext, tag, aad []byte) ([]byte, error)
+}
+
+// contentEncryptions contains the supported JWE "enc" values.
+var contentEncryptions = map[string]contentEncryption{
+	A256GCMALG:      {keySize: 32, encrypt: encryptAESGCM, decrypt: decryptAESGCM},
+	A256CBCHS512ALG: {keySize: 64, encrypt: encryptAESCBCHMACSHA512, decrypt: decryptAESCBCHMACSHA512},
+}
+
+func getContentEncryption(enc string) (*contentEncryption, error) {
+	encryption, found := contentEncryptions[enc]
+	if !found {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedEncryption, enc)
+	}
+
+	return &encryption, nil
+}
+
+// encryptAESGCM uses AES GCM with a 96 bits IV and a 128 bits tag (RFC 7518, section 5.3).
+func encryptAESGCM(cek, plaintext, aad []byte) (iv, ciphertext, tag []byte, err error) {
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	aesGCM, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	iv = make([]byte, aesGCM.NonceSize())
+	if _, err = rand.Read(iv); err != nil {
+		return nil, nil, nil, err
+	}
+
+	sealed := aesGCM.Seal(nil, iv, plaintext, aad)
+	tagIndex := len(sealed) - aesGCM.Overhead()
+	return iv, sealed[:tagIndex], sealed[tagIndex:], nil
+}
+
+func decryptAESGCM(cek, iv, ciphertext, tag, aad []byte) ([]byte, error) {
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, ErrDecryption
+	}
+
+	aesGCM, err := cipher.NewGCM(block)
+	if err != nil || len(iv) != aesGCM.NonceSize() || len(tag) != aesGCM.Overhead() {
+		return nil, ErrDecryption
+	}
+
+	sealed := make([]byte, 0, len(ciphertext)+len(tag))
+	sealed = append(append(sealed, ciphertext...), tag...)
+	plaintext, err := aesGCM.Open(nil, iv, sealed, aad)
+	if err != nil {
+		return nil, ErrDecryption
+	}
+
+	return plaintext, nil
+}
+
+// encryptAESCBCHMACSHA512 uses AES_256_CBC_HMAC_SHA_512 (RFC 7518, section 5.2.5):
+// the first 32 bytes of the CEK are the MAC key and the last 32 bytes are the AES key.
+func encryptAESCBCHMACSHA512(cek, plaintext, aad []byte) (iv, ciphertext, tag []byte, err error) {
+	if len(cek) != 64 {
+		return nil, nil, nil, ErrUnsupportedKey
+	}
+
+	block, err := aes.NewCipher(cek[32:])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	iv = make([]byte, aes.BlockSize)
+	if _, err = rand.Read(iv); err != nil {
+		return nil, nil, nil, err
+	}
+
+	// PKCS #7 padding
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	ciphertext = append(append([]byte{}, plaintext...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, ciphertext)
+
+	return iv, ciphertext, computeCBCHMACTag(cek[:32], aad, iv, ciphertext), nil
+}
+
+func decryptAESCBCHMACSHA512(cek, iv, ciphertext, tag, aad []byte) ([]byte, error) {
+	if len(cek) != 64 || len(iv) != aes.BlockSize || len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, ErrDecryption
+	}
+
+	if !hmac.Equal(tag, computeCBCHMACTag(cek[:32], aad, iv, ciphertext)) {
+		return nil, ErrDecryption
+	}
+
+	block, err := aes.NewCipher(cek[32:])
+	if err != nil {
+		return nil, ErrDecryption
+	}
+
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+
+	padding := int(plaintext[len(plaintext)-1])
+	if padding < 1 || padding > aes.BlockSize ||
+		subtle.ConstantTimeCompare(plaintext[len(plaintext)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) != 1 {
+		return nil, ErrDecryption
+	}
+
+	return plaintext[:len(plaintext)-padding], nil
+}
+
+// computeCBCHMACTag returns the first 32 bytes of HMAC-SHA-512(AAD || IV || ciphertext || AL),
+// where AL is the number of bits of the AAD as a 64-bit big-endian integer.
+func computeCBCHMACTag(macKey, aad, iv, ciphertext []byte) []byte {
+	aadLength := make([]byte, 8)
+	binary.BigEndian.PutUint64(aadLength, uint64(len(aad))*8)
+
+	mac := hmac.New(sha512.New, macKey)
+	mac.Write(aad)
+	mac.Write(iv)
+	mac.Write(ciphertext)
+	mac.Write(aadLength)
+	return mac.Sum(nil)[:32]
+}
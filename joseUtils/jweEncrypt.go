@@ -0,0 +1,489 @@
+package joseUtils
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+)
+
+var (
+	ErrMissingRecipients          = errors.New("the JWE requires at least one recipient")
+	ErrDirectKeyAgreementMultiple = errors.New("direct key agreement (ECDH-ES, ML-KEM) only supports one recipient")
+	ErrRecipientNotFound          = errors.New("no JWE recipient can be decrypted with the key")
+	ErrMissingKeyAgreementHeader  = errors.New("the JWE does not contain the ephemeral public key (epk) or the encapsulated key (ek)")
+)
+
+// EncryptOptions are the optional settings to create a JWE.
+//   - Algorithm: the key management algorithm ("alg"), by default ECDH-ES+A256KW
+//     or "<alg>+A256KW" if the first recipient has a ML-KEM or hybrid key (e.g.: ML-KEM-768+A256KW).
+//   - Encryption: the content encryption algorithm ("enc"), A256GCM by default.
+//   - ContentType and Type: the "cty" and "typ" headers (if any).
+//   - SenderKeyID: the "skid" header (if any).
+//   - APU: the agreement PartyUInfo, by default the "skid" value (if any).
+//   - APV: the agreement PartyVInfo, by default the SHA-256 of the sorted recipients' "kid" joined with "." (as in DIDComm v2).
+//   - Headers: additional protected headers.
+//   - AAD: additional authenticated data (JSON serialization only).
+type EncryptOptions struct {
+	Algorithm   string
+	Encryption  string
+	ContentType string
+	Type        string
+	SenderKeyID string
+	APU         []byte
+	APV         []byte
+	Headers     Headers
+	AAD         []byte
+}
+
+// EncryptJWE encrypts the plaintext for the public keys of the recipients
+// (EC P-256, P-384, P-521 or OKP X25519 for ECDH-ES, PQK for ML-KEM and X25519MLKEM768).
+// With only one recipient all the headers are protected, so the JWE can be compact serialized;
+// with several recipients the "kid" and "epk" (or "ek") headers of each recipient are in its RecipientHeaders.
+func EncryptJWE(plaintext []byte, recipients []*jwkUtils.JWK, opts *EncryptOptions) (*JWEncryptionGo, error) {
+	if opts == nil {
+		opts = &EncryptOptions{}
+	}
+
+	if len(recipients) == 0 {
+		return nil, ErrMissingRecipients
+	}
+
+	alg := opts.Algorithm
+	if alg == "" {
+		alg = ECDHESA256KWALG
+		if _, isKEM := getKEMAlgorithm(recipients[0].Alg); isKEM {
+			alg = recipients[0].Alg + keyWrappingSuffix
+		}
+	}
+
+	if !isDirectKeyAgreement(alg) && !isKeyWrapping(alg) {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, alg)
+	} else if isDirectKeyAgreement(alg) && len(recipients) > 1 {
+		return nil, ErrDirectKeyAgreementMultiple
+	}
+
+	enc := opts.Encryption
+	if enc == "" {
+		enc = A256GCMALG
+	}
+
+	encryption, err := getContentEncryption(enc)
+	if err != nil {
+		return nil, err
+	}
+
+	protectedHeaders := createProtectedHeadersJWE(alg, enc, recipients, opts)
+	apu, _ := protectedHeaders.AgreementPartyUInfo()
+	apv, _ := protectedHeaders.AgreementPartyVInfo()
+
+	var cek []byte
+	if isKeyWrapping(alg) {
+		cek = make([]byte, encryption.keySize)
+		if _, err = rand.Read(cek); err != nil {
+			return nil, err
+		}
+	}
+
+	jweRecipients := make([]*RecipientJWE, 0, len(recipients))
+	for _, recipient := range recipients {
+		if recipient.Use != nil && *recipient.Use == jwkUtils.JWKeySignType {
+			return nil, fmt.Errorf("%w: the recipient key %q is for signatures", ErrUnsupportedKey, recipient.Kid)
+		}
+
+		sharedSecret, keyAgreementHeaders, err := encapsulateSharedSecret(alg, recipient)
+		if err != nil {
+			return nil, err
+		}
+
+		encryptedKey := ""
+		if isDirectKeyAgreement(alg) {
+			cek = concatKDF(sharedSecret, enc, apu, apv, encryption.keySize*8, nil)
+		} else {
+			wrappedKey, err := aesKeyWrap(concatKDF(sharedSecret, alg, apu, apv, 256, nil), cek)
+			if err != nil {
+				return nil, err
+			}
+			encryptedKey = string(wrappedKey)
+		}
+
+		jweRecipient := &RecipientJWE{EncryptedKey: encryptedKey}
+		if len(recipients) == 1 {
+			for name, value := range keyAgreementHeaders {
+				protectedHeaders[name] = value
+			}
+			if recipient.Kid != "" {
+				protectedHeaders[HeaderKeyID] = recipient.Kid
+			}
+		} else {
+			if jweRecipient.Header, err = createRecipientHeaders(recipient.Kid, keyAgreementHeaders); err != nil {
+				return nil, err
+			}
+		}
+		jweRecipients = append(jweRecipients, jweRecipient)
+	}
+
+	jwe := &JWEncryptionGo{Recipients: jweRecipients, AAD: string(opts.AAD)}
+	if err = jwe.setProtectedHeaders(protectedHeaders); err != nil {
+		return nil, err
+	}
+
+	iv, ciphertext, tag, err := encryption.encrypt(cek, plaintext, jwe.computeAuthenticatedData())
+	if err != nil {
+		return nil, err
+	}
+
+	jwe.IV = string(iv)
+	jwe.Ciphertext = string(ciphertext)
+	jwe.Tag = string(tag)
+	return jwe, nil
+}
+
+// DecryptJWE decrypts the JWE with the private key of a recipient
+// (EC P-256, P-384, P-521 or OKP X25519 for ECDH-ES, PQK for ML-KEM and X25519MLKEM768).
+// If the key has a "kid" only the recipients without "kid" or with the same "kid" are tried.
+func DecryptJWE(jwe *JWEncryptionGo, key *jwkUtils.JWK) ([]byte, error) {
+	if jwe == nil || len(jwe.Recipients) == 0 {
+		return nil, ErrMissingRecipients
+	}
+
+	if key == nil || key.D == nil {
+		return nil, jwkUtils.ErrMissingKeyMaterial
+	}
+
+	return decryptJWE(jwe, key.Kid, func(alg string, headers Headers) ([]byte, error) {
+		return decapsulateSharedSecret(alg, headers, key)
+	})
+}
+
+// decryptJWE decrypts the JWE for the first recipient whose shared secret (Z) can be derived by the function.
+// If the "kid" is not empty only the recipients without "kid" or with the same "kid" are tried.
+func decryptJWE(jwe *JWEncryptionGo, kid string, deriveSharedSecret func(alg string, headers Headers) ([]byte, error)) ([]byte, error) {
+	if jwe == nil || len(jwe.Recipients) == 0 {
+		return nil, ErrMissingRecipients
+	}
+
+	aad, err := jwe.authenticatedData()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, recipient := range jwe.Recipients {
+		headers, err := jwe.recipientHeaders(recipient)
+		if err != nil {
+			return nil, err
+		}
+
+		if recipientKid, _ := headers.KeyID(); recipientKid != "" && kid != "" && recipientKid != kid {
+			continue
+		}
+
+		if alg, _ := headers.Algorithm(); alg == ECDH1PUA256KWALG {
+			return nil, ErrMissingSenderKey
+		}
+
+		enc, _ := headers.Encryption()
+		encryption, err := getContentEncryption(enc)
+		if err != nil {
+			return nil, err
+		}
+
+		cek, err := deriveRecipientCEK(headers, recipient, deriveSharedSecret, encryption.keySize)
+		if err != nil {
+			continue
+		}
+
+		plaintext, err := encryption.decrypt(cek, []byte(jwe.IV), []byte(jwe.Ciphertext), []byte(jwe.Tag), aad)
+		if err != nil {
+			return nil, err
+		}
+
+		return plaintext, nil
+	}
+
+	return nil, ErrRecipientNotFound
+}
+
+// DecryptOpenidJWE decrypts a JWE containing a compact JWT (e.g.: a nested JWS) and returns its headers, recipients and the nested JWT.
+// The signature of the nested JWT is not verified.
+func DecryptOpenidJWE(jwe *JWEncryptionGo, key *jwkUtils.JWK) (*DecryptedOpenidJWE, error) {
+	plaintext, err := DecryptJWE(jwe, key)
+	if err != nil {
+		return nil, err
+	}
+
+	compactJWT := string(plaintext)
+	nestedJWT := GetDataJWT(&compactJWT)
+	if nestedJWT == nil {
+		return nil, ErrInvalidJWT
+	}
+
+	return &DecryptedOpenidJWE{
+		ProtectedHeaders:   jwe.ProtectedHeaders,
+		UnprotectedHeaders: jwe.UnprotectedHeaders,
+		Recipients:         jwe.Recipients,
+		NestedJWT:          *nestedJWT,
+	}, nil
+}
+
+// createProtectedHeadersJWE returns the protected headers shared by all the recipients.
+func createProtectedHeadersJWE(alg, enc string, recipients []*jwkUtils.JWK, opts *EncryptOptions) Headers {
+	protectedHeaders := Headers{}
+	for name, value := range opts.Headers {
+		protectedHeaders[name] = value
+	}
+
+	protectedHeaders[HeaderAlgorithm] = alg
+	protectedHeaders[HeaderEncryption] = enc
+	if opts.ContentType != "" {
+		protectedHeaders[HeaderContentType] = opts.ContentType
+	}
+	if opts.Type != "" {
+		protectedHeaders[HeaderType] = opts.Type
+	}
+	if opts.SenderKeyID != "" {
+		protectedHeaders[HeaderSenderKeyID] = opts.SenderKeyID
+	}
+
+	apu := opts.APU
+	if apu == nil && opts.SenderKeyID != "" {
+		apu = []byte(opts.SenderKeyID)
+	}
+	if apu != nil {
+		protectedHeaders[HeaderAPU] = base64.RawURLEncoding.EncodeToString(apu)
+	}
+
+	apv := opts.APV
+	if apv == nil {
+		apv = computeRecipientsAPV(recipients)
+	}
+	if apv != nil {
+		protectedHeaders[HeaderAPV] = base64.RawURLEncoding.EncodeToString(apv)
+	}
+
+	return protectedHeaders
+}
+
+// computeRecipientsAPV returns the SHA-256 of the sorted recipients' "kid" joined with "." or nil if no "kid".
+func computeRecipientsAPV(recipients []*jwkUtils.JWK) []byte {
+	var kids []string
+	for _, recipient := range recipients {
+		if recipient.Kid != "" {
+			kids = append(kids, recipient.Kid)
+		}
+	}
+
+	if len(kids) == 0 {
+		return nil
+	}
+
+	sort.Strings(kids)
+	digest := sha256.Sum256([]byte(strings.Join(kids, ".")))
+	return digest[:]
+}
+
+// agreeEphemeralSharedSecret generates an ephemeral key on the curve of the recipient's key
+// and returns the shared secret (Z) and the public ephemeral JWK ("epk").
+func agreeEphemeralSharedSecret(recipient *jwkUtils.JWK) ([]byte, *jwkUtils.JWK, error) {
+	recipientPublicKey, err := jwkUtils.GetECDHPublicKey(recipient)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ephemeralKey, err := recipientPublicKey.Curve().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sharedSecret, err := ephemeralKey.ECDH(recipientPublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	epk, err := jwkUtils.GetPublicJWKFromECDH(ephemeralKey.PublicKey())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return sharedSecret, epk, nil
+}
+
+// isDirectKeyAgreement returns true if the CEK is directly derived from the shared secret (one recipient only).
+func isDirectKeyAgreement(alg string) bool {
+	switch alg {
+	case ECDHESALG, MLKEM768ALG, MLKEM1024ALG, X25519MLKEM768ALG:
+		return true
+	default:
+		return false
+	}
+}
+
+// isKeyWrapping returns true if the CEK is wrapped with "A256KW" by using the key derived from the shared secret.
+func isKeyWrapping(alg string) bool {
+	switch alg {
+	case ECDHESA256KWALG, MLKEM768A256KWALG, MLKEM1024A256KWALG, X25519MLKEM768A256KWALG:
+		return true
+	default:
+		return false
+	}
+}
+
+// encapsulateSharedSecret returns a new shared secret (Z) for the recipient and the headers to recover it:
+// the ephemeral public key ("epk") for ECDH-ES or the KEM ciphertext ("ek") for ML-KEM.
+func encapsulateSharedSecret(alg string, recipient *jwkUtils.JWK) ([]byte, Headers, error) {
+	if kemAlg, isKEM := getKEMAlgorithm(alg); isKEM {
+		return encapsulateKEMSharedSecret(kemAlg, recipient)
+	}
+
+	sharedSecret, epk, err := agreeEphemeralSharedSecret(recipient)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return sharedSecret, Headers{HeaderEPK: epk}, nil
+}
+
+// createRecipientHeaders returns the per-recipient headers with the "kid" and the "epk" or "ek" headers.
+func createRecipientHeaders(kid string, keyAgreementHeaders Headers) (*RecipientHeaders, error) {
+	recipientHeaders := &RecipientHeaders{KID: kid}
+	if epk, found := keyAgreementHeaders[HeaderEPK]; found {
+		epkJSON, err := json.Marshal(epk)
+		if err != nil {
+			return nil, err
+		}
+		recipientHeaders.EPK = epkJSON
+	}
+
+	recipientHeaders.EK, _ = keyAgreementHeaders.stringValue(HeaderEncapsulatedKey)
+	return recipientHeaders, nil
+}
+
+// deriveRecipientCEK returns the CEK for the recipient by using the shared secret of the "epk" (or "ek") header,
+// and the "apu" and "apv" headers.
+func deriveRecipientCEK(headers Headers, recipient *RecipientJWE, deriveSharedSecret func(alg string, headers Headers) ([]byte, error), keySize int) ([]byte, error) {
+	alg, _ := headers.Algorithm()
+	enc, _ := headers.Encryption()
+	apu, _ := headers.AgreementPartyUInfo()
+	apv, _ := headers.AgreementPartyVInfo()
+
+	if !isDirectKeyAgreement(alg) && !isKeyWrapping(alg) {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, alg)
+	}
+
+	sharedSecret, err := deriveSharedSecret(alg, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	if isDirectKeyAgreement(alg) {
+		return concatKDF(sharedSecret, enc, apu, apv, keySize*8, nil), nil
+	}
+
+	return aesKeyUnwrap(concatKDF(sharedSecret, alg, apu, apv, 256, nil), []byte(recipient.EncryptedKey))
+}
+
+// decapsulateSharedSecret returns the shared secret (Z) of the recipient's private key
+// and the ephemeral public key ("epk") for ECDH-ES or the KEM ciphertext ("ek") for ML-KEM.
+func decapsulateSharedSecret(alg string, headers Headers, key *jwkUtils.JWK) ([]byte, error) {
+	if kemAlg, isKEM := getKEMAlgorithm(alg); isKEM {
+		return decapsulateKEMSharedSecret(kemAlg, headers, key)
+	}
+
+	privateKey, err := jwkUtils.GetECDHPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return agreeRecipientSharedSecret(headers, privateKey)
+}
+
+// agreeRecipientSharedSecret returns the shared secret (Z) of the recipient's private key and the ephemeral public key ("epk").
+func agreeRecipientSharedSecret(headers Headers, privateKey *ecdh.PrivateKey) ([]byte, error) {
+	epk, found := headers.EphemeralPublicKey()
+	if !found {
+		return nil, ErrMissingKeyAgreementHeader
+	}
+
+	epkPublicKey, err := jwkUtils.GetECDHPublicKey(epk)
+	if err != nil || epkPublicKey.Curve() != privateKey.Curve() {
+		return nil, ErrUnsupportedKey
+	}
+
+	return privateKey.ECDH(epkPublicKey)
+}
+
+// setProtectedHeaders sets both the protected headers and the original Base64Url encoded ones used for the AAD.
+func (jweGo *JWEncryptionGo) setProtectedHeaders(protectedHeaders Headers) error {
+	protectedHeadersJSON, err := json.Marshal(protectedHeaders)
+	if err != nil {
+		return err
+	}
+
+	jweGo.OrigProtectedHders = base64.RawURLEncoding.EncodeToString(protectedHeadersJSON)
+	jweGo.ProtectedHeaders = map[string]interface{}{}
+	return json.Unmarshal(protectedHeadersJSON, &jweGo.ProtectedHeaders)
+}
+
+// authenticatedData returns the JWE AAD, encoding the protected headers if the original ones are not available.
+func (jweGo *JWEncryptionGo) authenticatedData() ([]byte, error) {
+	if jweGo.OrigProtectedHders == "" {
+		if jweGo.ProtectedHeaders == nil {
+			return nil, errProtectedHeaderMissing
+		}
+
+		protectedHeadersJSON, err := json.Marshal(jweGo.ProtectedHeaders)
+		if err != nil {
+			return nil, err
+		}
+		jweGo.OrigProtectedHders = base64.RawURLEncoding.EncodeToString(protectedHeadersJSON)
+	}
+
+	return jweGo.computeAuthenticatedData(), nil
+}
+
+// computeAuthenticatedData returns ASCII(BASE64URL(UTF8(JWE Protected Header))) and,
+// if the JWE AAD exists, '.' || BASE64URL(JWE AAD) (RFC 7516, section 5.1).
+func (jweGo *JWEncryptionGo) computeAuthenticatedData() []byte {
+	if jweGo.AAD == "" {
+		return []byte(jweGo.OrigProtectedHders)
+	}
+
+	return []byte(jweGo.OrigProtectedHders + "." + base64.RawURLEncoding.EncodeToString([]byte(jweGo.AAD)))
+}
+
+// recipientHeaders returns the union of the protected, shared unprotected and per-recipient headers.
+func (jweGo *JWEncryptionGo) recipientHeaders(recipient *RecipientJWE) (Headers, error) {
+	headers := Headers{}
+	for name, value := range jweGo.UnprotectedHeaders {
+		headers[name] = value
+	}
+
+	if recipient.Header != nil {
+		recipientHeadersJSON, err := json.Marshal(recipient.Header)
+		if err != nil {
+			return nil, err
+		}
+
+		var recipientHeaders map[string]interface{}
+		if err = json.Unmarshal(recipientHeadersJSON, &recipientHeaders); err != nil {
+			return nil, err
+		}
+
+		for name, value := range recipientHeaders {
+			headers[name] = value
+		}
+	}
+
+	for name, value := range jweGo.ProtectedHeaders {
+		headers[name] = value
+	}
+
+	return headers, nil
+}
@@ -0,0 +1,156 @@
+package joseUtils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAESKeyWrap_RFC3394Vector(t *testing.T) {
+	// RFC 3394, section 4.6: wrap 256 bits of key data with a 256-bit KEK
+	kek := []byte{
+		0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0A, 0x0B, 0x0C, 0x0D, 0x0E, 0x0F,
+		0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1A, 0x1B, 0x1C, 0x1D, 0x1E, 0x1F,
+	}
+	keyData := []byte{
+		0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF,
+		0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0A, 0x0B, 0x0C, 0x0D, 0x0E, 0x0F,
+	}
+	expected := []byte{
+		0x28, 0xC9, 0xF4, 0x04, 0xC4, 0xB8, 0x10, 0xF4, 0xCB, 0xCC, 0xB3, 0x5C, 0xFB, 0x87, 0xF8, 0x26,
+		0x3F, 0x57, 0x86, 0xE2, 0xD8, 0x0E, 0xD3, 0x26, 0xCB, 0xC7, 0xF0, 0xE7, 0x1A, 0x99, 0xF4, 0x3B,
+		0xFB, 0x98, 0x8B, 0x9B, 0x7A, 0x02, 0xDD, 0x21,
+	}
+
+	wrapped, err := aesKeyWrap(kek, keyData)
+	require.NoError(t, err)
+	assert.Equal(t, expected, wrapped)
+
+	unwrapped, err := aesKeyUnwrap(kek, wrapped)
+	require.NoError(t, err)
+	assert.Equal(t, keyData, unwrapped)
+
+	wrapped[0] ^= 0x01
+	_, err = aesKeyUnwrap(kek, wrapped)
+	assert.ErrorIs(t, err, ErrKeyUnwrap)
+}
+
+func TestConcatKDF_RFC7518Vector(t *testing.T) {
+	// RFC 7518, appendix C: ECDH-ES with A128GCM, apu "Alice" and apv "Bob"
+	sharedSecret := []byte{
+		158, 86, 217, 29, 129, 113, 53, 211, 114, 131, 66, 131, 191, 132, 38, 156,
+		251, 49, 110, 163, 218, 128, 106, 72, 246, 218, 167, 121, 140, 254, 144, 196,
+	}
+
+	derivedKey := concatKDF(sharedSecret, "A128GCM", []byte("Alice"), []byte("Bob"), 128, nil)
+	assert.Equal(t, "VqqN6vgjbSBcIijNcacQGg", base64.RawURLEncoding.EncodeToString(derivedKey))
+}
+
+func TestEncryptAndDecryptJWE_SoleRecipient(t *testing.T) {
+	recipientKeys := []*jwkUtils.JWK{
+		newTestJWK(t, AlgorithmES256, jwkUtils.JWKeyEncType, "kid-p256"),
+		newTestJWK(t, AlgorithmES384, jwkUtils.JWKeyEncType, "kid-p384"),
+		newTestJWK(t, AlgorithmES512, jwkUtils.JWKeyEncType, "kid-p521"),
+		newTestJWK(t, jwkUtils.AlgorithmX25519, "", "kid-x25519"),
+	}
+
+	for _, recipientKey := range recipientKeys {
+		for _, alg := range []string{ECDHESALG, ECDHESA256KWALG} {
+			for _, enc := range []string{A256GCMALG, A256CBCHS512ALG} {
+				t.Run(recipientKey.Kid+" "+alg+" "+enc, func(t *testing.T) {
+					publicKey := jwkUtils.ExportPublicJWK(recipientKey)
+					opts := &EncryptOptions{Algorithm: alg, Encryption: enc, SenderKeyID: "did:example:alice#key-1"}
+					jwe, err := EncryptJWE([]byte("secret message"), []*jwkUtils.JWK{&publicKey}, opts)
+					require.NoError(t, err)
+					assert.Equal(t, recipientKey.Kid, jwe.ProtectedHeaders[HeaderKeyID])
+					assert.Equal(t, "did:example:alice#key-1", jwe.ProtectedHeaders[HeaderSenderKeyID])
+					assert.NotNil(t, jwe.ProtectedHeaders[HeaderEPK])
+					assert.NotNil(t, jwe.ProtectedHeaders[HeaderAPU])
+					assert.NotNil(t, jwe.ProtectedHeaders[HeaderAPV])
+
+					compactJWE, err := jwe.CompactSoleRecipientJWE(json.Marshal)
+					require.NoError(t, err)
+
+					deserializedJWE, err := DeserializeJWE(compactJWE)
+					require.NoError(t, err)
+
+					plaintext, err := DecryptJWE(deserializedJWE, recipientKey)
+					require.NoError(t, err)
+					assert.Equal(t, "secret message", string(plaintext))
+				})
+			}
+		}
+	}
+}
+
+func TestEncryptAndDecryptJWE_MultipleRecipients(t *testing.T) {
+	firstKey := newTestJWK(t, AlgorithmES256, jwkUtils.JWKeyEncType, "kid-first")
+	secondKey := newTestJWK(t, jwkUtils.AlgorithmX25519, "", "kid-second")
+	otherKey := newTestJWK(t, AlgorithmES256, jwkUtils.JWKeyEncType, "kid-other")
+
+	firstPublicKey := jwkUtils.ExportPublicJWK(firstKey)
+	secondPublicKey := jwkUtils.ExportPublicJWK(secondKey)
+	recipients := []*jwkUtils.JWK{&firstPublicKey, &secondPublicKey}
+
+	opts := &EncryptOptions{Encryption: A256CBCHS512ALG, AAD: []byte("additional data")}
+	jwe, err := EncryptJWE([]byte("secret message"), recipients, opts)
+	require.NoError(t, err)
+	require.Len(t, jwe.Recipients, 2)
+	assert.Equal(t, "kid-first", jwe.Recipients[0].Header.KID)
+	assert.NotEmpty(t, jwe.Recipients[1].Header.EPK)
+
+	serializedJWE, err := jwe.SerializeMultiRecipientStringified()
+	require.NoError(t, err)
+
+	deserializedJWE, err := DeserializeJWE(serializedJWE)
+	require.NoError(t, err)
+
+	for _, recipientKey := range []*jwkUtils.JWK{firstKey, secondKey} {
+		plaintext, err := DecryptJWE(deserializedJWE, recipientKey)
+		require.NoError(t, err)
+		assert.Equal(t, "secret message", string(plaintext))
+	}
+
+	_, err = DecryptJWE(deserializedJWE, otherKey)
+	assert.ErrorIs(t, err, ErrRecipientNotFound)
+
+	_, err = EncryptJWE([]byte("secret message"), recipients, &EncryptOptions{Algorithm: ECDHESALG})
+	assert.ErrorIs(t, err, ErrDirectKeyAgreementMultiple)
+}
+
+func TestDecryptJWE_TamperedData(t *testing.T) {
+	recipientKey := newTestJWK(t, AlgorithmES256, jwkUtils.JWKeyEncType, "kid-p256")
+	publicKey := jwkUtils.ExportPublicJWK(recipientKey)
+
+	jwe, err := EncryptJWE([]byte("secret message"), []*jwkUtils.JWK{&publicKey}, nil)
+	require.NoError(t, err)
+
+	tamperedFirstChar := "x"
+	if jwe.Ciphertext[0] == 'x' {
+		tamperedFirstChar = "y"
+	}
+	jwe.Ciphertext = tamperedFirstChar + jwe.Ciphertext[1:]
+	_, err = DecryptJWE(jwe, recipientKey)
+	assert.ErrorIs(t, err, ErrDecryption)
+}
+
+func TestDecryptOpenidJWE(t *testing.T) {
+	signingKey := newTestJWK(t, AlgorithmEdDSA, "", "kid-eddsa")
+	compactJWS, err := SignCompactJWT(Headers{}, map[string]interface{}{"sub": "subjectID"}, signingKey)
+	require.NoError(t, err)
+
+	recipientKey := newTestJWK(t, jwkUtils.AlgorithmX25519, "", "kid-x25519")
+	publicKey := jwkUtils.ExportPublicJWK(recipientKey)
+	jwe, err := EncryptJWE([]byte(compactJWS), []*jwkUtils.JWK{&publicKey}, &EncryptOptions{ContentType: "JWT"})
+	require.NoError(t, err)
+
+	decryptedJWE, err := DecryptOpenidJWE(jwe, recipientKey)
+	require.NoError(t, err)
+	assert.Equal(t, "JWT", decryptedJWE.ProtectedHeaders[HeaderContentType])
+	assert.Equal(t, "subjectID", decryptedJWE.NestedJWT.Payload["sub"])
+	assert.Equal(t, "kid-eddsa", decryptedJWE.NestedJWT.Header[HeaderKeyID])
+}
@@ -14,7 +14,8 @@ import "github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
 //	- the **"jwk"** (*conditional*) field value is the recipient's public JWK to which the CEK was encrypted (rather than using both "kid" and "jku").
 //	The main JWE "kid" header claim field is the recipient's public encryption keyID.
 //
-//	Note: *"x5u"* is not used in JAR because the certificate data can be included in the JWK (use the *"jwk"* or *"jku"* fields instead).
+//	Note: *"x5u"* is not used in JAR because the certificate data can be included in the JWK (use the *"jwk"* or *"jku"* fields instead),
+//	but the "x5c", "x5t#S256" and "x5u" fields are available for eIDAS / EBSI X.509 based trust.
 // todo:
 type HeaderRequestJWE struct {
 	// Algorithm ("alg", required) is the cryptographic algorithm used to encrypt (encapsulate) the value of the CEK (Crystals-Kyber).
@@ -41,4 +42,13 @@ type HeaderRequestJWE struct {
 
 	// Type ("typ", required) is used to declare the media type of the complete JWS. It is "jwt" as per the OpenID specification.
 	Type string `json:"typ,omitempty" bson:"typ,omitempty"`
+
+	// X509CertificateChain ("x5c", optional) contains the base64 DER certificate chain of the recipient's public key (the leaf first).
+	X509CertificateChain []string `json:"x5c,omitempty" bson:"x5c,omitempty"`
+
+	// X509CertificateDigestSha256 ("x5t#S256", optional) is the base64url SHA-256 thumbprint of the DER encoding of the recipient's certificate.
+	X509CertificateDigestSha256 *string `json:"x5t#S256,omitempty" bson:"x5t#S256,omitempty"`
+
+	// X509URL ("x5u", optional) is a URI that refers to the PEM certificate chain of the recipient's public key.
+	X509URL *string `json:"x5u,omitempty" bson:"x5u,omitempty"`
 }
@@ -0,0 +1,66 @@
+package joseUtils
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+)
+
+// keyWrappingSuffix is the suffix of the key management algorithms which wrap the CEK with "A256KW".
+const keyWrappingSuffix = "+A256KW"
+
+// getKEMAlgorithm returns the ML-KEM or hybrid algorithm of the recipient's key for a JWE "alg"
+// (e.g.: "ML-KEM-768" for both "ML-KEM-768" and "ML-KEM-768+A256KW") and false if it is not a KEM algorithm.
+func getKEMAlgorithm(alg string) (string, bool) {
+	kemAlg := strings.TrimSuffix(alg, keyWrappingSuffix)
+	if jwkUtils.GetPQKemScheme(kemAlg) == nil {
+		return "", false
+	}
+
+	return kemAlg, true
+}
+
+// encapsulateKEMSharedSecret encapsulates a new shared secret (Z) for the recipient's ML-KEM or hybrid key
+// and returns it and the "ek" header with the Base64Url encoded KEM ciphertext.
+func encapsulateKEMSharedSecret(kemAlg string, recipient *jwkUtils.JWK) ([]byte, Headers, error) {
+	if recipient.Alg != kemAlg {
+		return nil, nil, fmt.Errorf("%w: the recipient key %q is not for %q", ErrUnsupportedKey, recipient.Kid, kemAlg)
+	}
+
+	publicKey, err := jwkUtils.GetPQKemPublicKey(recipient)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ciphertext, sharedSecret, err := publicKey.Scheme().Encapsulate(publicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return sharedSecret, Headers{HeaderEncapsulatedKey: base64.RawURLEncoding.EncodeToString(ciphertext)}, nil
+}
+
+// decapsulateKEMSharedSecret returns the shared secret (Z) of the KEM ciphertext ("ek" header) by using the recipient's private key.
+func decapsulateKEMSharedSecret(kemAlg string, headers Headers, key *jwkUtils.JWK) ([]byte, error) {
+	if key.Alg != kemAlg {
+		return nil, ErrUnsupportedKey
+	}
+
+	ciphertext, found := headers.EncapsulatedKey()
+	if !found {
+		return nil, ErrMissingKeyAgreementHeader
+	}
+
+	privateKey, err := jwkUtils.GetPQKemPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) != privateKey.Scheme().CiphertextSize() {
+		return nil, ErrKeyUnwrap
+	}
+
+	return privateKey.Scheme().Decapsulate(privateKey, ciphertext)
+}
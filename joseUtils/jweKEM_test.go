@@ -0,0 +1,99 @@
+package joseUtils
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptAndDecryptJWE_KEMSoleRecipient(t *testing.T) {
+	testCases := []struct {
+		keyAlg string
+		alg    string
+	}{
+		{jwkUtils.AlgorithmMLKEM768, MLKEM768ALG},
+		{jwkUtils.AlgorithmMLKEM768, MLKEM768A256KWALG},
+		{jwkUtils.AlgorithmMLKEM1024, MLKEM1024ALG},
+		{jwkUtils.AlgorithmMLKEM1024, MLKEM1024A256KWALG},
+		{jwkUtils.AlgorithmX25519MLKEM768, X25519MLKEM768ALG},
+		{jwkUtils.AlgorithmX25519MLKEM768, X25519MLKEM768A256KWALG},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.alg, func(t *testing.T) {
+			recipientKey := newTestJWK(t, testCase.keyAlg, "", "kid-"+testCase.keyAlg)
+			publicKey := jwkUtils.ExportPublicJWK(recipientKey)
+
+			opts := &EncryptOptions{Algorithm: testCase.alg}
+			jwe, err := EncryptJWE([]byte("secret message"), []*jwkUtils.JWK{&publicKey}, opts)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.alg, jwe.ProtectedHeaders[HeaderAlgorithm])
+			assert.Equal(t, A256GCMALG, jwe.ProtectedHeaders[HeaderEncryption])
+			assert.NotEmpty(t, jwe.ProtectedHeaders[HeaderEncapsulatedKey])
+			assert.Nil(t, jwe.ProtectedHeaders[HeaderEPK])
+
+			compactJWE, err := jwe.CompactSoleRecipientJWE(json.Marshal)
+			require.NoError(t, err)
+
+			deserializedJWE, err := DeserializeJWE(compactJWE)
+			require.NoError(t, err)
+
+			plaintext, err := DecryptJWE(deserializedJWE, recipientKey)
+			require.NoError(t, err)
+			assert.Equal(t, "secret message", string(plaintext))
+
+			// a key of the same algorithm which is not the recipient's
+			otherKey := newTestJWK(t, testCase.keyAlg, "", "")
+			_, err = DecryptJWE(deserializedJWE, otherKey)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestEncryptJWE_KEMDefaultAlgorithm(t *testing.T) {
+	recipientKey := newTestJWK(t, jwkUtils.AlgorithmMLKEM768, "", "kid-mlkem")
+	publicKey := jwkUtils.ExportPublicJWK(recipientKey)
+
+	jwe, err := EncryptJWE([]byte("secret message"), []*jwkUtils.JWK{&publicKey}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, MLKEM768A256KWALG, jwe.ProtectedHeaders[HeaderAlgorithm])
+
+	// the "alg" must match the recipient's key
+	_, err = EncryptJWE([]byte("secret message"), []*jwkUtils.JWK{&publicKey}, &EncryptOptions{Algorithm: MLKEM1024ALG})
+	assert.ErrorIs(t, err, ErrUnsupportedKey)
+	_, err = EncryptJWE([]byte("secret message"), []*jwkUtils.JWK{&publicKey}, &EncryptOptions{Algorithm: ECDHESALG})
+	assert.ErrorIs(t, err, jwkUtils.ErrUnsupportedKey)
+}
+
+func TestEncryptAndDecryptJWE_KEMMultipleRecipients(t *testing.T) {
+	firstKey := newTestJWK(t, jwkUtils.AlgorithmX25519MLKEM768, "", "kid-first")
+	secondKey := newTestJWK(t, jwkUtils.AlgorithmX25519MLKEM768, "", "kid-second")
+	firstPublicKey := jwkUtils.ExportPublicJWK(firstKey)
+	secondPublicKey := jwkUtils.ExportPublicJWK(secondKey)
+	recipients := []*jwkUtils.JWK{&firstPublicKey, &secondPublicKey}
+
+	jwe, err := EncryptJWE([]byte("secret message"), recipients, &EncryptOptions{Algorithm: X25519MLKEM768A256KWALG})
+	require.NoError(t, err)
+	require.Len(t, jwe.Recipients, 2)
+	assert.Equal(t, "kid-second", jwe.Recipients[1].Header.KID)
+	assert.NotEmpty(t, jwe.Recipients[1].Header.EK)
+	assert.Empty(t, jwe.Recipients[1].Header.EPK)
+
+	serializedJWE, err := jwe.SerializeMultiRecipientStringified()
+	require.NoError(t, err)
+
+	deserializedJWE, err := DeserializeJWE(serializedJWE)
+	require.NoError(t, err)
+
+	for _, recipientKey := range []*jwkUtils.JWK{firstKey, secondKey} {
+		plaintext, err := DecryptJWE(deserializedJWE, recipientKey)
+		require.NoError(t, err)
+		assert.Equal(t, "secret message", string(plaintext))
+	}
+
+	_, err = EncryptJWE([]byte("secret message"), recipients, &EncryptOptions{Algorithm: X25519MLKEM768ALG})
+	assert.ErrorIs(t, err, ErrDirectKeyAgreementMultiple)
+}
@@ -0,0 +1,112 @@
+package joseUtils
+
+import (
+	"crypto/aes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+var ErrKeyUnwrap = errors.New("the encrypted key cannot be unwrapped")
+
+// aesKeyWrapDefaultIV is the default initial value of RFC 3394, section 2.2.3.1.
+var aesKeyWrapDefaultIV = []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+
+// concatKDF derives a key of keySizeBits with the Concat KDF of NIST SP 800-56A using SHA-256,
+// as defined for ECDH-ES in RFC 7518, section 4.6.2:
+// OtherInfo = AlgorithmID || PartyUInfo || PartyVInfo || SuppPubInfo (each one prefixed by its length, except SuppPubInfo).
+// The tag is only appended to SuppPubInfo for ECDH-1PU when the content is encrypted with AES-CBC-HMAC (nil otherwise).
+func concatKDF(sharedSecret []byte, algorithmID string, apu, apv []byte, keySizeBits int, tag []byte) []byte {
+	otherInfo := appendLengthPrefixed(nil, []byte(algorithmID))
+	otherInfo = appendLengthPrefixed(otherInfo, apu)
+	otherInfo = appendLengthPrefixed(otherInfo, apv)
+	otherInfo = binary.BigEndian.AppendUint32(otherInfo, uint32(keySizeBits))
+	if tag != nil {
+		otherInfo = appendLengthPrefixed(otherInfo, tag)
+	}
+
+	keySize := keySizeBits / 8
+	derivedKey := make([]byte, 0, keySize+sha256.Size)
+	for counter := uint32(1); len(derivedKey) < keySize; counter++ {
+		hasher := sha256.New()
+		hasher.Write(binary.BigEndian.AppendUint32(nil, counter))
+		hasher.Write(sharedSecret)
+		hasher.Write(otherInfo)
+		derivedKey = hasher.Sum(derivedKey)
+	}
+
+	return derivedKey[:keySize]
+}
+
+func appendLengthPrefixed(data, value []byte) []byte {
+	data = binary.BigEndian.AppendUint32(data, uint32(len(value)))
+	return append(data, value...)
+}
+
+// aesKeyWrap wraps the CEK with the Key Encryption Key (KEK) as defined in RFC 3394 (e.g.: "A256KW").
+func aesKeyWrap(kek, cek []byte) ([]byte, error) {
+	if len(cek)%8 != 0 || len(cek) < 16 {
+		return nil, ErrUnsupportedKey
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	blocksNumber := len(cek) / 8
+	wrapped := make([]byte, 8+len(cek))
+	copy(wrapped, aesKeyWrapDefaultIV)
+	copy(wrapped[8:], cek)
+
+	buffer := make([]byte, 16)
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= blocksNumber; i++ {
+			copy(buffer[:8], wrapped[:8])
+			copy(buffer[8:], wrapped[8*i:8*i+8])
+			block.Encrypt(buffer, buffer)
+
+			t := uint64(blocksNumber*j + i)
+			binary.BigEndian.PutUint64(wrapped[:8], binary.BigEndian.Uint64(buffer[:8])^t)
+			copy(wrapped[8*i:8*i+8], buffer[8:])
+		}
+	}
+
+	return wrapped, nil
+}
+
+// aesKeyUnwrap returns the CEK after checking the integrity of the wrapped key (RFC 3394).
+func aesKeyUnwrap(kek, wrapped []byte) ([]byte, error) {
+	if len(wrapped)%8 != 0 || len(wrapped) < 24 {
+		return nil, ErrKeyUnwrap
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, ErrKeyUnwrap
+	}
+
+	blocksNumber := len(wrapped)/8 - 1
+	unwrapped := make([]byte, len(wrapped))
+	copy(unwrapped, wrapped)
+
+	buffer := make([]byte, 16)
+	for j := 5; j >= 0; j-- {
+		for i := blocksNumber; i >= 1; i-- {
+			t := uint64(blocksNumber*j + i)
+			binary.BigEndian.PutUint64(buffer[:8], binary.BigEndian.Uint64(unwrapped[:8])^t)
+			copy(buffer[8:], unwrapped[8*i:8*i+8])
+			block.Decrypt(buffer, buffer)
+
+			copy(unwrapped[:8], buffer[:8])
+			copy(unwrapped[8*i:8*i+8], buffer[8:])
+		}
+	}
+
+	if subtle.ConstantTimeCompare(unwrapped[:8], aesKeyWrapDefaultIV) != 1 {
+		return nil, ErrKeyUnwrap
+	}
+
+	return unwrapped[8:], nil
+}
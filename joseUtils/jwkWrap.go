@@ -0,0 +1,211 @@
+package joseUtils
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	// ContentTypeJWK is the "cty" header of a JWE containing a JWK (RFC 7517, section 7).
+	ContentTypeJWK = "jwk+json"
+	// DefaultPBES2Count is the PBKDF2 iteration count ("p2c") to wrap a JWK with a passphrase.
+	DefaultPBES2Count = 210000
+	// MinPBES2Count is the minimum iteration count as per RFC 7518, section 4.8.1.2.
+	MinPBES2Count = 1000
+	// MaxPBES2Count is the maximum iteration count accepted to unwrap a JWK, to avoid a denial of service.
+	MaxPBES2Count = 10000000
+
+	pbes2SaltSize = 16
+	a256KWKeySize = 32
+)
+
+var (
+	ErrMissingWrappingKey = errors.New("a passphrase or a 256 bits key encryption key (KEK) is required to wrap the JWK")
+	ErrInvalidPBES2Count  = errors.New("invalid PBES2 iteration count (p2c)")
+	ErrInvalidWrappedJWK  = errors.New("invalid wrapped JWK")
+)
+
+// JWKWrapper encrypts a (private) JWK as a compact JWE with A256GCM to store it,
+// and wraps the CEK with PBES2-HS512+A256KW if it was created with a passphrase
+// or with A256KW if it was created with a Key Encryption Key (KEK).
+type JWKWrapper struct {
+	alg        string
+	passphrase []byte
+	kek        []byte
+	count      int
+}
+
+// NewPassphraseJWKWrapper returns a PBES2-HS512+A256KW wrapper.
+// The iteration count is DefaultPBES2Count if it is 0, else it cannot be less than MinPBES2Count.
+func NewPassphraseJWKWrapper(passphrase []byte, count int) (*JWKWrapper, error) {
+	if len(passphrase) == 0 {
+		return nil, ErrMissingWrappingKey
+	}
+
+	if count == 0 {
+		count = DefaultPBES2Count
+	} else if count < MinPBES2Count || count > MaxPBES2Count {
+		return nil, ErrInvalidPBES2Count
+	}
+
+	return &JWKWrapper{alg: PBES2HS512A256KWALG, passphrase: passphrase, count: count}, nil
+}
+
+// NewKEKJWKWrapper returns an A256KW wrapper for the 256 bits Key Encryption Key (e.g.: from a KMS).
+func NewKEKJWKWrapper(kek []byte) (*JWKWrapper, error) {
+	if len(kek) != a256KWKeySize {
+		return nil, ErrMissingWrappingKey
+	}
+
+	return &JWKWrapper{alg: A256KWALG, kek: kek}, nil
+}
+
+// Wrap returns the compact JWE of the JWK with the "jwk+json" content type and the "kid" of the JWK (if any).
+func (wrapper *JWKWrapper) Wrap(jwk *jwkUtils.JWK) (string, error) {
+	if jwk == nil {
+		return "", jwkUtils.ErrMissingKeyMaterial
+	}
+
+	plaintext, err := json.Marshal(jwk)
+	if err != nil {
+		return "", err
+	}
+
+	protectedHeaders := Headers{
+		HeaderAlgorithm:   wrapper.alg,
+		HeaderEncryption:  A256GCMALG,
+		HeaderContentType: ContentTypeJWK,
+	}
+	if jwk.Kid != "" {
+		protectedHeaders[HeaderKeyID] = jwk.Kid
+	}
+
+	var salt []byte
+	if wrapper.alg == PBES2HS512A256KWALG {
+		salt = make([]byte, pbes2SaltSize)
+		if _, err = rand.Read(salt); err != nil {
+			return "", err
+		}
+		protectedHeaders[HeaderP2S] = base64.RawURLEncoding.EncodeToString(salt)
+		protectedHeaders[HeaderP2C] = wrapper.count
+	}
+
+	cek := make([]byte, contentEncryptions[A256GCMALG].keySize)
+	if _, err = rand.Read(cek); err != nil {
+		return "", err
+	}
+
+	encryptedKey, err := aesKeyWrap(wrapper.getKEK(salt, wrapper.count), cek)
+	if err != nil {
+		return "", err
+	}
+
+	jwe := &JWEncryptionGo{Recipients: []*RecipientJWE{{EncryptedKey: string(encryptedKey)}}}
+	if err = jwe.setProtectedHeaders(protectedHeaders); err != nil {
+		return "", err
+	}
+
+	iv, ciphertext, tag, err := encryptAESGCM(cek, plaintext, jwe.computeAuthenticatedData())
+	if err != nil {
+		return "", err
+	}
+
+	jwe.IV = string(iv)
+	jwe.Ciphertext = string(ciphertext)
+	jwe.Tag = string(tag)
+	return jwe.CompactSoleRecipientJWE(json.Marshal)
+}
+
+// Unwrap decrypts the compact JWE created by Wrap and returns the JWK.
+func (wrapper *JWKWrapper) Unwrap(compactJWE string) (*jwkUtils.JWK, error) {
+	jwe, err := DeserializeCompactJWE(compactJWE)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWrappedJWK, err)
+	}
+
+	headers := Headers(jwe.ProtectedHeaders)
+	if alg, _ := headers.Algorithm(); alg != wrapper.alg {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, alg)
+	}
+
+	enc, _ := headers.Encryption()
+	encryption, err := getContentEncryption(enc)
+	if err != nil {
+		return nil, err
+	}
+
+	var salt []byte
+	count := 0
+	if wrapper.alg == PBES2HS512A256KWALG {
+		if salt, count, err = getPBES2Parameters(headers); err != nil {
+			return nil, err
+		}
+	}
+
+	cek, err := aesKeyUnwrap(wrapper.getKEK(salt, count), []byte(jwe.Recipients[0].EncryptedKey))
+	if err != nil {
+		return nil, err // e.g.: a wrong passphrase or KEK
+	}
+
+	aad, err := jwe.authenticatedData()
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := encryption.decrypt(cek, []byte(jwe.IV), []byte(jwe.Ciphertext), []byte(jwe.Tag), aad)
+	if err != nil {
+		return nil, err
+	}
+
+	jwk := &jwkUtils.JWK{}
+	if err = json.Unmarshal(plaintext, jwk); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWrappedJWK, err)
+	}
+
+	return jwk, nil
+}
+
+// UnwrapToKeyManager decrypts the compact JWE created by Wrap, imports the JWK in the key manager and returns its "kid".
+// The private members of the unwrapped JWK are cleared, so the key is only kept by the key manager.
+func (wrapper *JWKWrapper) UnwrapToKeyManager(compactJWE string, keyImporter KeyImporter) (string, error) {
+	jwk, err := wrapper.Unwrap(compactJWE)
+	if err != nil {
+		return "", err
+	}
+	defer func() { *jwk = jwkUtils.ExportPublicJWK(jwk) }()
+
+	return keyImporter.Import(jwk)
+}
+
+// getKEK returns the KEK or derives it from the passphrase as per RFC 7518, section 4.8.1.1:
+// PBKDF2 with HMAC SHA-512, the salt (UTF8(alg) || 0x00 || "p2s"), the "p2c" iterations and a 256 bits length.
+func (wrapper *JWKWrapper) getKEK(salt []byte, count int) []byte {
+	if wrapper.alg != PBES2HS512A256KWALG {
+		return wrapper.kek
+	}
+
+	saltInput := append(append([]byte(wrapper.alg), 0x00), salt...)
+	return pbkdf2.Key(wrapper.passphrase, saltInput, count, a256KWKeySize, sha512.New)
+}
+
+// getPBES2Parameters returns the "p2s" (at least 8 bytes) and "p2c" headers.
+func getPBES2Parameters(headers Headers) ([]byte, int, error) {
+	salt, found := headers.bytesValue(HeaderP2S)
+	if !found || len(salt) < 8 {
+		return nil, 0, fmt.Errorf("%w: invalid PBES2 salt (p2s)", ErrInvalidWrappedJWK)
+	}
+
+	count, isNumber := headers[HeaderP2C].(float64)
+	if !isNumber || count != float64(int(count)) || count < MinPBES2Count || count > MaxPBES2Count {
+		return nil, 0, ErrInvalidPBES2Count
+	}
+
+	return salt, int(count), nil
+}
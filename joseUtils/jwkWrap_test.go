@@ -0,0 +1,95 @@
+package joseUtils
+
+import (
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWKWrapper(t *testing.T) {
+	privateJWK, err := jwkUtils.GenerateJWK("ES256", jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+
+	passphraseWrapper, err := NewPassphraseJWKWrapper([]byte("correct horse battery staple"), MinPBES2Count)
+	require.NoError(t, err)
+
+	kek := make([]byte, 32)
+	_, err = rand.Read(kek)
+	require.NoError(t, err)
+	kekWrapper, err := NewKEKJWKWrapper(kek)
+	require.NoError(t, err)
+
+	for alg, wrapper := range map[string]*JWKWrapper{PBES2HS512A256KWALG: passphraseWrapper, A256KWALG: kekWrapper} {
+		t.Run(alg, func(t *testing.T) {
+			wrappedJWK, err := wrapper.Wrap(privateJWK)
+			require.NoError(t, err)
+			assert.NotContains(t, wrappedJWK, *privateJWK.D)
+
+			jwe, err := DeserializeCompactJWE(wrappedJWK)
+			require.NoError(t, err)
+			assert.Equal(t, alg, jwe.ProtectedHeaders[HeaderAlgorithm])
+			assert.Equal(t, ContentTypeJWK, jwe.ProtectedHeaders[HeaderContentType])
+			assert.Equal(t, privateJWK.Kid, jwe.ProtectedHeaders[HeaderKeyID])
+
+			unwrappedJWK, err := wrapper.Unwrap(wrappedJWK)
+			require.NoError(t, err)
+			assert.Equal(t, privateJWK, unwrappedJWK)
+
+			parts := strings.Split(wrappedJWK, ".")
+			parts[3] = strings.Repeat("A", len(parts[3]))
+			_, err = wrapper.Unwrap(strings.Join(parts, "."))
+			assert.ErrorIs(t, err, ErrDecryption)
+		})
+	}
+
+	t.Run("wrong wrapping key", func(t *testing.T) {
+		wrappedJWK, err := passphraseWrapper.Wrap(privateJWK)
+		require.NoError(t, err)
+
+		otherWrapper, err := NewPassphraseJWKWrapper([]byte("wrong passphrase"), MinPBES2Count)
+		require.NoError(t, err)
+		_, err = otherWrapper.Unwrap(wrappedJWK)
+		assert.ErrorIs(t, err, ErrKeyUnwrap)
+
+		_, err = kekWrapper.Unwrap(wrappedJWK)
+		assert.ErrorIs(t, err, ErrUnsupportedAlgorithm)
+	})
+
+	t.Run("invalid parameters", func(t *testing.T) {
+		_, err := NewPassphraseJWKWrapper(nil, 0)
+		assert.ErrorIs(t, err, ErrMissingWrappingKey)
+		_, err = NewPassphraseJWKWrapper([]byte("passphrase"), 10)
+		assert.ErrorIs(t, err, ErrInvalidPBES2Count)
+		_, err = NewKEKJWKWrapper(kek[:16])
+		assert.ErrorIs(t, err, ErrMissingWrappingKey)
+		_, err = kekWrapper.Unwrap("invalid")
+		assert.ErrorIs(t, err, ErrInvalidWrappedJWK)
+	})
+}
+
+func TestJWKWrapper_UnwrapToKeyManager(t *testing.T) {
+	privateJWK, err := jwkUtils.GenerateJWK("ES256", jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+
+	wrapper, err := NewPassphraseJWKWrapper([]byte("correct horse battery staple"), MinPBES2Count)
+	require.NoError(t, err)
+	wrappedJWK, err := wrapper.Wrap(privateJWK)
+	require.NoError(t, err)
+
+	keyManager := NewInMemoryKeyManager()
+	kid, err := wrapper.UnwrapToKeyManager(wrappedJWK, keyManager)
+	require.NoError(t, err)
+	assert.Equal(t, privateJWK.Kid, kid)
+
+	compactJWT, err := SignCompactJWTWithKeyManager(Headers{}, map[string]interface{}{"sub": "did:example:123"}, keyManager, kid)
+	require.NoError(t, err)
+	_, err = VerifyCompactJWT(compactJWT, &jwkUtils.JWKeySet{Keys: []jwkUtils.JWK{jwkUtils.ExportPublicJWK(privateJWK)}})
+	assert.NoError(t, err)
+
+	_, err = wrapper.UnwrapToKeyManager("invalid", keyManager)
+	assert.ErrorIs(t, err, ErrInvalidWrappedJWK)
+}
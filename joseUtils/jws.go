@@ -0,0 +1,419 @@
+package joseUtils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+	"github.com/lestrrat-go/jwx/jwk"
+)
+
+var (
+	ErrInvalidJWT           = errors.New("invalid compact JWT")
+	ErrUnsupportedAlgorithm = errors.New("unsupported algorithm")
+	ErrKeyNotFound          = errors.New("signature verification key not found")
+)
+
+const (
+	AlgorithmES256 = "ES256"
+	AlgorithmES384 = "ES384"
+	AlgorithmES512 = "ES512"
+	AlgorithmRS256 = "RS256"
+	AlgorithmRS384 = "RS384"
+	AlgorithmRS512 = "RS512"
+	AlgorithmPS256 = "PS256"
+	AlgorithmPS384 = "PS384"
+	AlgorithmPS512 = "PS512"
+	AlgorithmEdDSA = "EdDSA"
+	AlgorithmNone  = "none"
+)
+
+// jwsAlgorithm has the functions to create and check the signature of a JWS for a given "alg" value.
+//   - matches reports whether a JWK can be used with the algorithm.
+//   - sign returns the signature of the JWS Signing Input by using the private JWK.
+//   - verify returns nil if the signature of the JWS Signing Input is valid for the public JWK.
+type jwsAlgorithm struct {
+	matches func(key *jwkUtils.JWK) bool
+	sign    func(key *jwkUtils.JWK, signingInput []byte) ([]byte, error)
+	verify  func(key *jwkUtils.JWK, signingInput, signature []byte) error
+}
+
+// jwsAlgorithms contains the supported JWS "alg" values. "none" is never supported.
+var jwsAlgorithms = map[string]jwsAlgorithm{
+	AlgorithmES256: newECDSAAlgorithm(AlgorithmES256),
+	AlgorithmES384: newECDSAAlgorithm(AlgorithmES384),
+	AlgorithmES512: newECDSAAlgorithm(AlgorithmES512),
+	AlgorithmRS256: newRSAAlgorithm(AlgorithmRS256),
+	AlgorithmRS384: newRSAAlgorithm(AlgorithmRS384),
+	AlgorithmRS512: newRSAAlgorithm(AlgorithmRS512),
+	AlgorithmPS256: newRSAAlgorithm(AlgorithmPS256),
+	AlgorithmPS384: newRSAAlgorithm(AlgorithmPS384),
+	AlgorithmPS512: newRSAAlgorithm(AlgorithmPS512),
+	AlgorithmEdDSA: {
+		matches: func(key *jwkUtils.JWK) bool {
+			return key.Kty == jwkUtils.KeyTypeOKP && key.Crv != nil && *key.Crv == jwkUtils.CurveEd25519
+		},
+		sign:   signEd25519,
+		verify: verifyEd25519,
+	},
+	jwkUtils.AlgorithmCRYDI2:  newPQSignatureAlgorithm(jwkUtils.AlgorithmCRYDI2),
+	jwkUtils.AlgorithmCRYDI3:  newPQSignatureAlgorithm(jwkUtils.AlgorithmCRYDI3),
+	jwkUtils.AlgorithmCRYDI5:  newPQSignatureAlgorithm(jwkUtils.AlgorithmCRYDI5),
+	jwkUtils.AlgorithmMLDSA44: newPQSignatureAlgorithm(jwkUtils.AlgorithmMLDSA44),
+	jwkUtils.AlgorithmMLDSA65: newPQSignatureAlgorithm(jwkUtils.AlgorithmMLDSA65),
+	jwkUtils.AlgorithmMLDSA87: newPQSignatureAlgorithm(jwkUtils.AlgorithmMLDSA87),
+}
+
+// SignCompactJWT signs the payload claims with the given private JWK and returns the compact JWS.
+// The "alg" and "kid" headers are taken from the JWK when they are not in the given headers
+// (the given headers are not modified).
+func SignCompactJWT(headers Headers, payload interface{}, key *jwkUtils.JWK) (string, error) {
+	if key == nil {
+		return "", ErrUnsupportedKey
+	}
+
+	return signCompactJWT(headers, payload, key, func(alg string, signingInput []byte) ([]byte, error) {
+		return jwsAlgorithms[alg].sign(key, signingInput)
+	})
+}
+
+// signCompactJWT creates the compact JWS for the key (the public members are enough) and the sign function,
+// which is called once the "alg" of the headers and the key are checked.
+func signCompactJWT(headers Headers, payload interface{}, key *jwkUtils.JWK, sign func(alg string, signingInput []byte) ([]byte, error)) (string, error) {
+	protectedHeaders, alg, err := getSigningHeaders(headers, nil, key)
+	if err != nil {
+		return "", err
+	}
+
+	partsJWT, err := CreatePartsUnsignedJWT(protectedHeaders, payload)
+	if err != nil {
+		return "", err
+	}
+
+	signature, err := sign(alg, []byte(partsJWT.Header+"."+partsJWT.Payload))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrSignature, err)
+	}
+
+	signatureBase64Url := base64.RawURLEncoding.EncodeToString(signature)
+	partsJWT.Signature = &signatureBase64Url
+
+	return partsJWT.Compact(), nil
+}
+
+// VerifyCompactJWT checks the signature of a compact JWS by using the public keys in the JWK Set
+// and returns the decoded DataJWT. If the "kid" header exists only the key with the same "kid" is used,
+// else every key in the set that can be used with the "alg" header is tried.
+func VerifyCompactJWT(compact string, keys *jwkUtils.JWKeySet) (*DataJWT, error) {
+	partsJWT := GetPartsJWT(&compact)
+	if partsJWT == nil || partsJWT.Signature == nil || *partsJWT.Signature == "" {
+		return nil, ErrInvalidJWT
+	}
+
+	headerJSON, _ := GetInflatedDataByPartsJWT(partsJWT)
+	if headerJSON == nil {
+		return nil, ErrInvalidJWT
+	}
+	header := Headers(headerJSON)
+
+	if err := checkCriticalHeaders(header); err != nil {
+		return nil, err
+	}
+	if !isPayloadEncoded(header) {
+		return nil, fmt.Errorf("%w: the payload of a JWT must be encoded", ErrInvalidJWT)
+	}
+
+	alg, _ := header.Algorithm()
+	algorithm, err := getJWSAlgorithm(alg)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(*partsJWT.Signature)
+	if err != nil {
+		return nil, ErrInvalidJWT
+	}
+
+	candidateKeys := findVerificationKeys(header, algorithm, keys)
+	if len(candidateKeys) == 0 {
+		return nil, ErrKeyNotFound
+	}
+
+	signingInput := []byte(partsJWT.Header + "." + partsJWT.Payload)
+	for _, key := range candidateKeys {
+		if algorithm.verify(key, signingInput, signature) == nil {
+			dataJWT := GetDataByPartsJWT(partsJWT)
+			if dataJWT == nil {
+				return nil, ErrInvalidJWT
+			}
+
+			return dataJWT, nil
+		}
+	}
+
+	return nil, ErrSignature
+}
+
+// getSigningHeaders returns a copy of the protected headers with the "alg" and "kid" of the key when they are not
+// in the protected or unprotected headers, and checks that the key can be used with the "alg".
+func getSigningHeaders(protectedHeaders, unprotectedHeaders Headers, key *jwkUtils.JWK) (Headers, string, error) {
+	signingHeaders := Headers{}
+	for name, value := range protectedHeaders {
+		signingHeaders[name] = value
+	}
+
+	alg, _ := signingHeaders.Algorithm()
+	if alg == "" {
+		alg, _ = unprotectedHeaders.Algorithm()
+	}
+	if alg == "" {
+		alg = key.Alg
+		signingHeaders[HeaderAlgorithm] = alg
+	}
+
+	kid, _ := signingHeaders.KeyID()
+	if unprotectedKid, _ := unprotectedHeaders.KeyID(); kid == "" && unprotectedKid == "" && key.Kid != "" {
+		signingHeaders[HeaderKeyID] = key.Kid
+	}
+
+	algorithm, err := getJWSAlgorithm(alg)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !algorithm.matches(key) || (key.Alg != "" && key.Alg != alg) {
+		return nil, "", fmt.Errorf("%w: the key cannot be used with %s", ErrUnsupportedKey, alg)
+	}
+
+	return signingHeaders, alg, nil
+}
+
+func getJWSAlgorithm(alg string) (*jwsAlgorithm, error) {
+	if alg == "" || alg == AlgorithmNone {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, alg)
+	}
+
+	algorithm, found := jwsAlgorithms[alg]
+	if !found {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, alg)
+	}
+
+	return &algorithm, nil
+}
+
+// CheckSigningKey returns nil if the JWS "alg" is supported and the JWK can be used with it
+// (its key type and curve, and its "alg" if any), else ErrUnsupportedAlgorithm or ErrUnsupportedKey.
+func CheckSigningKey(alg string, key *jwkUtils.JWK) error {
+	algorithm, err := getJWSAlgorithm(alg)
+	if err != nil {
+		return err
+	}
+
+	if key == nil || !algorithm.matches(key) || (key.Alg != "" && key.Alg != alg) {
+		return fmt.Errorf("%w: the key cannot be used with %s", ErrUnsupportedKey, alg)
+	}
+	return nil
+}
+
+// findVerificationKeys returns the keys of the set which can verify the JWS:
+// the "kid" must match (if any), the key must allow to verify ("use" and "key_ops") and its "alg" (if any) must be the header's one.
+func findVerificationKeys(header Headers, algorithm *jwsAlgorithm, keys *jwkUtils.JWKeySet) []*jwkUtils.JWK {
+	if keys == nil {
+		return nil
+	}
+
+	alg, _ := header.Algorithm()
+	kid, _ := header.KeyID()
+
+	var candidateKeys []*jwkUtils.JWK
+	for i := range keys.Keys {
+		key := &keys.Keys[i]
+		if kid != "" && key.Kid != kid {
+			continue
+		}
+
+		if key.CheckKeyOperation(jwkUtils.KeyOperationVerify) != nil {
+			continue
+		}
+
+		if (key.Alg != "" && key.Alg != alg) || !algorithm.matches(key) {
+			continue
+		}
+
+		candidateKeys = append(candidateKeys, key)
+	}
+
+	return candidateKeys
+}
+
+// newECDSAAlgorithm uses the curve and hash defined in jwkUtils.JWAlgorithmToJWKCrvAndHashType for the given "alg".
+// The signature is the concatenation of R and S as big-endian integers of the curve size (RFC 7518, section 3.4).
+func newECDSAAlgorithm(alg string) jwsAlgorithm {
+	crv := jwkUtils.JWAlgorithmToJWKCrvAndHashType[alg][jwk.ECDSACrvKey]
+	hash := getHashByName(jwkUtils.JWAlgorithmToJWKCrvAndHashType[alg]["hash"])
+
+	return jwsAlgorithm{
+		matches: func(key *jwkUtils.JWK) bool {
+			return key.Kty == jwkUtils.KeyTypeEC && key.Crv != nil && *key.Crv == crv
+		},
+		sign: func(key *jwkUtils.JWK, signingInput []byte) ([]byte, error) {
+			privateKey, err := jwkUtils.GetECDSAPrivateKey(key)
+			if err != nil {
+				return nil, err
+			}
+
+			digest := hashBytes(hash, signingInput)
+			r, s, err := ecdsa.Sign(rand.Reader, privateKey, digest)
+			if err != nil {
+				return nil, err
+			}
+
+			keySize := (privateKey.Curve.Params().BitSize + 7) / 8
+			signature := make([]byte, 2*keySize)
+			r.FillBytes(signature[:keySize])
+			s.FillBytes(signature[keySize:])
+			return signature, nil
+		},
+		verify: func(key *jwkUtils.JWK, signingInput, signature []byte) error {
+			publicKey, err := jwkUtils.GetECDSAPublicKey(key)
+			if err != nil {
+				return err
+			}
+
+			keySize := (publicKey.Curve.Params().BitSize + 7) / 8
+			if len(signature) != 2*keySize {
+				return ErrSignature
+			}
+
+			r := new(big.Int).SetBytes(signature[:keySize])
+			s := new(big.Int).SetBytes(signature[keySize:])
+			if !ecdsa.Verify(publicKey, hashBytes(hash, signingInput), r, s) {
+				return ErrSignature
+			}
+
+			return nil
+		},
+	}
+}
+
+// newRSAAlgorithm uses the hash defined in jwkUtils.JWAlgorithmRSAToHashType for the given "alg":
+// RSASSA-PKCS1-v1_5 for "RS" algorithms and RSASSA-PSS with a salt of the hash size for "PS" ones (RFC 7518, section 3.5).
+func newRSAAlgorithm(alg string) jwsAlgorithm {
+	hash := getHashByName(jwkUtils.JWAlgorithmRSAToHashType[alg]["hash"])
+	pssOptions := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: hash}
+	isPSS := strings.HasPrefix(alg, "PS")
+
+	return jwsAlgorithm{
+		matches: func(key *jwkUtils.JWK) bool {
+			return key.Kty == jwkUtils.KeyTypeRSA
+		},
+		sign: func(key *jwkUtils.JWK, signingInput []byte) ([]byte, error) {
+			privateKey, err := jwkUtils.GetRSAPrivateKey(key)
+			if err != nil {
+				return nil, err
+			}
+
+			digest := hashBytes(hash, signingInput)
+			if isPSS {
+				return rsa.SignPSS(rand.Reader, privateKey, hash, digest, pssOptions)
+			}
+			return rsa.SignPKCS1v15(rand.Reader, privateKey, hash, digest)
+		},
+		verify: func(key *jwkUtils.JWK, signingInput, signature []byte) error {
+			publicKey, err := jwkUtils.GetRSAPublicKey(key)
+			if err != nil {
+				return err
+			}
+
+			digest := hashBytes(hash, signingInput)
+			if isPSS {
+				err = rsa.VerifyPSS(publicKey, hash, digest, signature, pssOptions)
+			} else {
+				err = rsa.VerifyPKCS1v15(publicKey, hash, digest, signature)
+			}
+			if err != nil {
+				return ErrSignature
+			}
+
+			return nil
+		},
+	}
+}
+
+func signEd25519(key *jwkUtils.JWK, signingInput []byte) ([]byte, error) {
+	privateKey, err := jwkUtils.GetEd25519PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return ed25519.Sign(privateKey, signingInput), nil
+}
+
+func verifyEd25519(key *jwkUtils.JWK, signingInput, signature []byte) error {
+	publicKey, err := jwkUtils.GetEd25519PublicKey(key)
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(publicKey, signingInput, signature) {
+		return ErrSignature
+	}
+
+	return nil
+}
+
+// newPQSignatureAlgorithm uses the Dilithium / ML-DSA scheme of a PQK JWK whose "alg" is the given one.
+// ML-DSA signatures use an empty context string.
+func newPQSignatureAlgorithm(alg string) jwsAlgorithm {
+	return jwsAlgorithm{
+		matches: func(key *jwkUtils.JWK) bool {
+			return key.Kty == jwkUtils.KeyTypePQK && key.Alg == alg
+		},
+		sign: func(key *jwkUtils.JWK, signingInput []byte) ([]byte, error) {
+			privateKey, err := jwkUtils.GetPQSignaturePrivateKey(key)
+			if err != nil {
+				return nil, err
+			}
+
+			return privateKey.Scheme().Sign(privateKey, signingInput, nil), nil
+		},
+		verify: func(key *jwkUtils.JWK, signingInput, signature []byte) error {
+			publicKey, err := jwkUtils.GetPQSignaturePublicKey(key)
+			if err != nil {
+				return err
+			}
+
+			if !publicKey.Scheme().Verify(publicKey, signingInput, signature, nil) {
+				return ErrSignature
+			}
+
+			return nil
+		},
+	}
+}
+
+// getHashByName returns the hash for the "SHA256", "SHA384" and "SHA512" names used in jwkUtils.
+func getHashByName(name string) crypto.Hash {
+	switch name {
+	case "SHA384":
+		return crypto.SHA384
+	case "SHA512":
+		return crypto.SHA512
+	default:
+		return crypto.SHA256
+	}
+}
+
+func hashBytes(hash crypto.Hash, data []byte) []byte {
+	hasher := hash.New()
+	hasher.Write(data)
+	return hasher.Sum(nil)
+}
@@ -31,6 +31,16 @@ type HeaderRequestJWS struct {
 	// Type ("typ") is used to declare the media type of the complete JWS. It is "jwt" as per the OpenID specification.
 	Type string `json:"typ,omitempty" bson:"typ,omitempty"`
 
+	// X509CertificateChain ("x5c", optional) contains the base64 DER certificate chain of the signing key (the leaf first),
+	// e.g.: for eIDAS qualified seals.
+	X509CertificateChain []string `json:"x5c,omitempty" bson:"x5c,omitempty"`
+
+	// X509CertificateDigestSha256 ("x5t#S256", optional) is the base64url SHA-256 thumbprint of the DER encoding of the signing certificate.
+	X509CertificateDigestSha256 *string `json:"x5t#S256,omitempty" bson:"x5t#S256,omitempty"`
+
+	// X509URL ("x5u", optional) is a URI that refers to the PEM certificate chain of the signing key.
+	X509URL *string `json:"x5u,omitempty" bson:"x5u,omitempty"`
+
 	// specifies if the payload bytes are compressed or not
 	ZipCompression *string `json:"zip,omitempty" bson:"zip,omitempty"` // CompressionAlgorithm
 }
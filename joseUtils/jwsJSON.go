@@ -0,0 +1,634 @@
+package joseUtils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+)
+
+const compactJWSRequiredNumOfParts = 3
+
+var (
+	ErrInvalidJWS                    = errors.New("invalid JWS")
+	ErrMissingJWSSignature           = errors.New("the JWS has no signatures")
+	ErrHeadersNotDisjoint            = errors.New("the protected and unprotected headers cannot have the same header parameter")
+	ErrUnsupportedVerificationPolicy = errors.New("unsupported signature verification policy")
+	ErrInvalidCriticalHeader         = errors.New("invalid critical header parameter (crit)")
+	ErrUnsupportedCriticalHeader     = errors.New("unsupported critical header parameter")
+	ErrInconsistentPayloadEncoding   = errors.New("every signature of the JWS must have the same b64 header value")
+)
+
+// supportedCriticalHeaders are the header parameters which can be in the "crit" header (they are understood and processed).
+var supportedCriticalHeaders = []string{HeaderB64Payload}
+
+var errNotOnlyOneSignature = errors.New("unable to serialize: the JWS must have exactly one signature")
+
+// SignatureVerificationPolicy defines the signatures of a JWS which must be valid.
+type SignatureVerificationPolicy string
+
+const (
+	// VerifyAllSignatures requires every signature to be valid (e.g.: a record co-signed by the practitioner and the organization).
+	VerifyAllSignatures SignatureVerificationPolicy = "all"
+	// VerifyAnySignature requires at least one valid signature.
+	VerifyAnySignature SignatureVerificationPolicy = "any"
+)
+
+// JWSignatureGo represents a JWS in Go (defined in https://tools.ietf.org/html/rfc7515).
+// In contrast to the JWS compact serialization, the JWS JSON serialization can produce multiple signatures
+// over the same JWS payload, each one with its own protected and unprotected headers:
+//   - the general syntax has a "signatures" array (https://tools.ietf.org/html/rfc7515#section-7.2.1).
+//   - the flattened syntax has the members of the sole signature at the top level (https://tools.ietf.org/html/rfc7515#section-7.2.2).
+//
+// A detached JWS (https://tools.ietf.org/html/rfc7515#appendix-F) is serialized without the payload,
+// which is conveyed by other means (e.g.: the data of a DIDComm attachment).
+// The payload is not base64url encoded in the serialization nor in the signing input
+// if the "b64" protected header is false (https://tools.ietf.org/html/rfc7797).
+type JWSignatureGo struct {
+	Payload    string          `json:"payload,omitempty"` // the payload bytes (not encoded)
+	Signatures []*SignatureJWS `json:"signatures,omitempty"`
+	Detached   bool            `json:"-"` // the payload is not serialized
+}
+
+// SignatureJWS is a signature of a JWS with its protected and unprotected headers.
+type SignatureJWS struct {
+	ProtectedHeaders   map[string]interface{}
+	OrigProtectedHders string                 `json:"protected,omitempty"` // the original protected headers Base64Url encoded
+	UnprotectedHeaders map[string]interface{} `json:"header,omitempty"`
+	Signature          string                 `json:"signature,omitempty"` // the signature bytes (not encoded)
+}
+
+// JWSignatureRawJSON represents a RAW JSON JWS that is used for serialization/deserialization (JWSignatureGo)
+// with the general syntax ("signatures") or the flattened syntax ("protected", "header" and "signature").
+// The payload is nil for a detached JWS.
+type JWSignatureRawJSON struct {
+	Payload             *string         `json:"payload,omitempty"` // BASE64URL(payload) or the payload itself if "b64" is false
+	Signatures          json.RawMessage `json:"signatures,omitempty"`
+	B64ProtectedHeaders string          `json:"protected,omitempty"`
+	UnprotectedHeaders  json.RawMessage `json:"header,omitempty"`
+	B64Signature        string          `json:"signature,omitempty"`
+}
+
+// SignatureRawJSON represents a RAW JSON signature in the "signatures" array of a JWS.
+type SignatureRawJSON struct {
+	B64ProtectedHeaders string          `json:"protected,omitempty"`
+	UnprotectedHeaders  json.RawMessage `json:"header,omitempty"`
+	B64Signature        string          `json:"signature"`
+}
+
+// NewJWSignature returns a JWS without signatures for the payload.
+func NewJWSignature(payload []byte) *JWSignatureGo {
+	return &JWSignatureGo{Payload: string(payload)}
+}
+
+// NewDetachedJWSignature returns a JWS without signatures for the payload, which is not serialized.
+func NewDetachedJWSignature(payload []byte) *JWSignatureGo {
+	return &JWSignatureGo{Payload: string(payload), Detached: true}
+}
+
+// AddSignature signs the payload with the private JWK and adds the signature.
+// The "alg" and "kid" protected headers are taken from the JWK when they are not in the given headers
+// (the given headers are not modified). If the "b64" protected header is false, it is added to the "crit" header.
+func (jwsGo *JWSignatureGo) AddSignature(protectedHeaders, unprotectedHeaders Headers, key *jwkUtils.JWK) error {
+	if key == nil {
+		return ErrUnsupportedKey
+	}
+
+	return jwsGo.addSignature(protectedHeaders, unprotectedHeaders, key, func(alg string, signingInput []byte) ([]byte, error) {
+		return jwsAlgorithms[alg].sign(key, signingInput)
+	})
+}
+
+// AddSignatureWithKeyManager is the same as AddSignature but the signature is created by the key manager.
+func (jwsGo *JWSignatureGo) AddSignatureWithKeyManager(protectedHeaders, unprotectedHeaders Headers, keyManager KeyManager, kid string) error {
+	publicKey, err := keyManager.PublicJWK(kid)
+	if err != nil {
+		return err
+	}
+
+	return jwsGo.addSignature(protectedHeaders, unprotectedHeaders, publicKey, func(alg string, signingInput []byte) ([]byte, error) {
+		return keyManager.Sign(kid, alg, signingInput)
+	})
+}
+
+func (jwsGo *JWSignatureGo) addSignature(protectedHeaders, unprotectedHeaders Headers, key *jwkUtils.JWK, sign func(alg string, signingInput []byte) ([]byte, error)) error {
+	signingHeaders, alg, err := getSigningHeaders(protectedHeaders, unprotectedHeaders, key)
+	if err != nil {
+		return err
+	}
+
+	if !isPayloadEncoded(signingHeaders) {
+		signingHeaders[HeaderCritical] = addCriticalHeader(signingHeaders[HeaderCritical], HeaderB64Payload)
+	}
+
+	signature := &SignatureJWS{ProtectedHeaders: signingHeaders}
+	if len(unprotectedHeaders) > 0 {
+		signature.UnprotectedHeaders = map[string]interface{}{}
+		for name, value := range unprotectedHeaders {
+			signature.UnprotectedHeaders[name] = value
+		}
+	}
+
+	if err = signature.checkHeaders(); err != nil {
+		return err
+	}
+
+	if len(jwsGo.Signatures) > 0 && signature.isPayloadEncoded() != jwsGo.Signatures[0].isPayloadEncoded() {
+		return ErrInconsistentPayloadEncoding
+	}
+
+	if signature.OrigProtectedHders, err = signature.encodeProtectedHeaders(json.Marshal); err != nil {
+		return err
+	}
+
+	signatureBytes, err := sign(alg, jwsGo.signingInput(signature.OrigProtectedHders, signature.isPayloadEncoded()))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSignature, err)
+	}
+
+	signature.Signature = string(signatureBytes)
+	jwsGo.Signatures = append(jwsGo.Signatures, signature)
+	return nil
+}
+
+// Verify checks the signatures by using the public keys in the JWK Set (see VerifyCompactJWT to know how the keys are selected)
+// and returns the valid ones. Every signature must be valid for VerifyAllSignatures and at least one for VerifyAnySignature,
+// else the error of the first invalid signature is returned.
+func (jwsGo *JWSignatureGo) Verify(keys *jwkUtils.JWKeySet, policy SignatureVerificationPolicy) ([]*SignatureJWS, error) {
+	if policy != VerifyAllSignatures && policy != VerifyAnySignature {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedVerificationPolicy, policy)
+	}
+
+	if len(jwsGo.Signatures) == 0 {
+		return nil, ErrMissingJWSSignature
+	}
+
+	if _, err := jwsGo.isPayloadEncoded(); err != nil {
+		return nil, err
+	}
+
+	var verifiedSignatures []*SignatureJWS
+	var firstErr error
+	for i, signature := range jwsGo.Signatures {
+		if err := jwsGo.verifySignature(signature, keys); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%w (signature %d)", err, i)
+			}
+			continue
+		}
+
+		verifiedSignatures = append(verifiedSignatures, signature)
+	}
+
+	if firstErr != nil && (policy == VerifyAllSignatures || len(verifiedSignatures) == 0) {
+		return nil, firstErr
+	}
+
+	return verifiedSignatures, nil
+}
+
+func (jwsGo *JWSignatureGo) verifySignature(signature *SignatureJWS, keys *jwkUtils.JWKeySet) error {
+	if err := signature.checkHeaders(); err != nil {
+		return err
+	}
+
+	headers := signature.Headers()
+	alg, _ := headers.Algorithm()
+	algorithm, err := getJWSAlgorithm(alg)
+	if err != nil {
+		return err
+	}
+
+	candidateKeys := findVerificationKeys(headers, algorithm, keys)
+	if len(candidateKeys) == 0 {
+		return ErrKeyNotFound
+	}
+
+	b64ProtectedHeaders, err := signature.encodeProtectedHeaders(json.Marshal)
+	if err != nil {
+		return err
+	}
+
+	signingInput := jwsGo.signingInput(b64ProtectedHeaders, signature.isPayloadEncoded())
+	for _, key := range candidateKeys {
+		if algorithm.verify(key, signingInput, []byte(signature.Signature)) == nil {
+			return nil
+		}
+	}
+
+	return ErrSignature
+}
+
+// signingInput returns ASCII(BASE64URL(UTF8(JWS Protected Header)) || '.' || BASE64URL(JWS Payload)),
+// or the payload itself instead of BASE64URL(JWS Payload) if it is not encoded ("b64" is false).
+func (jwsGo *JWSignatureGo) signingInput(b64ProtectedHeaders string, isPayloadEncoded bool) []byte {
+	return []byte(b64ProtectedHeaders + "." + encodePayload(jwsGo.Payload, isPayloadEncoded))
+}
+
+// isPayloadEncoded returns false if the "b64" header of the signatures is false,
+// or ErrInconsistentPayloadEncoding if the signatures have different "b64" values.
+func (jwsGo *JWSignatureGo) isPayloadEncoded() (bool, error) {
+	isEncoded := true
+	for i, signature := range jwsGo.Signatures {
+		if i == 0 {
+			isEncoded = signature.isPayloadEncoded()
+		} else if signature.isPayloadEncoded() != isEncoded {
+			return false, ErrInconsistentPayloadEncoding
+		}
+	}
+	return isEncoded, nil
+}
+
+// serializedPayload returns the payload member of the JSON serialization or nil if the JWS is detached.
+func (jwsGo *JWSignatureGo) serializedPayload() (*string, error) {
+	isEncoded, err := jwsGo.isPayloadEncoded()
+	if err != nil || jwsGo.Detached {
+		return nil, err
+	}
+
+	payload := encodePayload(jwsGo.Payload, isEncoded)
+	return &payload, nil
+}
+
+func encodePayload(payload string, isEncoded bool) string {
+	if !isEncoded {
+		return payload
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(payload))
+}
+
+// Headers returns the JOSE Header of the signature: the union of the protected and unprotected headers.
+func (signature *SignatureJWS) Headers() Headers {
+	headers := Headers{}
+	for name, value := range signature.UnprotectedHeaders {
+		headers[name] = value
+	}
+	for name, value := range signature.ProtectedHeaders {
+		headers[name] = value
+	}
+	return headers
+}
+
+// isPayloadEncoded returns false only if the "b64" protected header is false.
+func (signature *SignatureJWS) isPayloadEncoded() bool {
+	return isPayloadEncoded(signature.ProtectedHeaders)
+}
+
+// checkHeaders checks that the protected and unprotected headers are disjoint and the critical headers (see checkCriticalHeaders).
+func (signature *SignatureJWS) checkHeaders() error {
+	for name := range signature.UnprotectedHeaders {
+		if _, found := signature.ProtectedHeaders[name]; found {
+			return fmt.Errorf("%w: %q", ErrHeadersNotDisjoint, name)
+		}
+	}
+
+	if _, found := signature.UnprotectedHeaders[HeaderCritical]; found {
+		return fmt.Errorf("%w: it must be protected", ErrInvalidCriticalHeader)
+	}
+	if _, found := signature.UnprotectedHeaders[HeaderB64Payload]; found {
+		return fmt.Errorf("%w: the b64 header must be protected", ErrInvalidCriticalHeader)
+	}
+
+	return checkCriticalHeaders(signature.ProtectedHeaders)
+}
+
+// encodeProtectedHeaders returns the original encoding of the protected headers (if any)
+// because it is part of the signing input.
+func (signature *SignatureJWS) encodeProtectedHeaders(jsonMarshal JsonMarshalFunc) (string, error) {
+	if signature.OrigProtectedHders != "" || len(signature.ProtectedHeaders) == 0 {
+		return signature.OrigProtectedHders, nil
+	}
+
+	protectedHeadersJSON, err := jsonMarshal(signature.ProtectedHeaders)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(protectedHeadersJSON), nil
+}
+
+func (signature *SignatureJWS) prepareRawJSON(jsonMarshal JsonMarshalFunc) (*SignatureRawJSON, error) {
+	b64ProtectedHeaders, err := signature.encodeProtectedHeaders(jsonMarshal)
+	if err != nil {
+		return nil, err
+	}
+
+	var unprotectedHeaders json.RawMessage
+	if signature.UnprotectedHeaders != nil {
+		if unprotectedHeaders, err = jsonMarshal(signature.UnprotectedHeaders); err != nil {
+			return nil, err
+		}
+	}
+
+	return &SignatureRawJSON{
+		B64ProtectedHeaders: b64ProtectedHeaders,
+		UnprotectedHeaders:  unprotectedHeaders,
+		B64Signature:        base64.RawURLEncoding.EncodeToString([]byte(signature.Signature)),
+	}, nil
+}
+
+// SerializeGeneral serializes the JWS with the general JSON syntax, as defined in https://tools.ietf.org/html/rfc7515#section-7.2.1.
+func (jwsGo *JWSignatureGo) SerializeGeneral(jsonMarshal JsonMarshalFunc) ([]byte, error) {
+	if len(jwsGo.Signatures) == 0 {
+		return nil, ErrMissingJWSSignature
+	}
+
+	signatures := make([]*SignatureRawJSON, len(jwsGo.Signatures))
+	for i, signature := range jwsGo.Signatures {
+		rawSignature, err := signature.prepareRawJSON(jsonMarshal)
+		if err != nil {
+			return nil, err
+		}
+		signatures[i] = rawSignature
+	}
+
+	signaturesJSON, err := jsonMarshal(signatures)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := jwsGo.serializedPayload()
+	if err != nil {
+		return nil, err
+	}
+
+	return jsonMarshal(JWSignatureRawJSON{
+		Payload:    payload,
+		Signatures: signaturesJSON,
+	})
+}
+
+// SerializeFlattened serializes the JWS with the flattened JSON syntax, as defined in https://tools.ietf.org/html/rfc7515#section-7.2.2.
+// The JWS must have exactly one signature.
+func (jwsGo *JWSignatureGo) SerializeFlattened(jsonMarshal JsonMarshalFunc) ([]byte, error) {
+	if len(jwsGo.Signatures) != 1 {
+		return nil, errNotOnlyOneSignature
+	}
+
+	rawSignature, err := jwsGo.Signatures[0].prepareRawJSON(jsonMarshal)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := jwsGo.serializedPayload()
+	if err != nil {
+		return nil, err
+	}
+
+	return jsonMarshal(JWSignatureRawJSON{
+		Payload:             payload,
+		B64ProtectedHeaders: rawSignature.B64ProtectedHeaders,
+		UnprotectedHeaders:  rawSignature.UnprotectedHeaders,
+		B64Signature:        rawSignature.B64Signature,
+	})
+}
+
+// SerializeCompact serializes the JWS into a compact, URL-safe string as defined in https://tools.ietf.org/html/rfc7515#section-7.1.
+// The JWS must have exactly one signature without unprotected headers. The payload part is empty if the JWS is detached,
+// and an unencoded payload ("b64" is false) cannot contain a period.
+func (jwsGo *JWSignatureGo) SerializeCompact() (string, error) {
+	if len(jwsGo.Signatures) != 1 {
+		return "", errNotOnlyOneSignature
+	}
+
+	signature := jwsGo.Signatures[0]
+	if len(signature.UnprotectedHeaders) > 0 {
+		return "", fmt.Errorf("%w: the compact serialization has no unprotected headers", ErrInvalidJWS)
+	}
+
+	rawSignature, err := signature.prepareRawJSON(json.Marshal)
+	if err != nil {
+		return "", err
+	}
+
+	payload := ""
+	if !jwsGo.Detached {
+		payload = encodePayload(jwsGo.Payload, signature.isPayloadEncoded())
+		if strings.Contains(payload, ".") {
+			return "", fmt.Errorf("%w: the unencoded payload contains a period", ErrInvalidJWS)
+		}
+	}
+
+	return rawSignature.B64ProtectedHeaders + "." + payload + "." + rawSignature.B64Signature, nil
+}
+
+// DeserializeJWS deserializes a compact JWS or a JSON JWS (general or flattened syntax) into a JWSignatureGo object.
+// The signatures are not verified (see JWSignatureGo.Verify). The JWS is detached if it has no payload
+// (see DeserializeDetachedJWS).
+func DeserializeJWS(serializedJWS string) (*JWSignatureGo, error) {
+	if strings.HasPrefix(serializedJWS, "{") {
+		rawJWS := JWSignatureRawJSON{}
+		if err := json.Unmarshal([]byte(serializedJWS), &rawJWS); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidJWS, err)
+		}
+
+		return DeserializeFromRawJWS(&rawJWS)
+	}
+
+	parts := strings.Split(serializedJWS, ".")
+	if len(parts) != compactJWSRequiredNumOfParts {
+		return nil, fmt.Errorf("%w: a compact JWS must have three parts", ErrInvalidJWS)
+	}
+
+	rawJWS := &JWSignatureRawJSON{B64ProtectedHeaders: parts[0], B64Signature: parts[2]}
+	if parts[1] != "" {
+		rawJWS.Payload = &parts[1]
+	}
+
+	return DeserializeFromRawJWS(rawJWS)
+}
+
+// DeserializeDetachedJWS deserializes a detached JWS (compact or JSON) and sets the payload conveyed by other means.
+// It fails if the serialized JWS has a payload.
+func DeserializeDetachedJWS(serializedJWS string, payload []byte) (*JWSignatureGo, error) {
+	jwsGo, err := DeserializeJWS(serializedJWS)
+	if err != nil {
+		return nil, err
+	}
+
+	if !jwsGo.Detached {
+		return nil, fmt.Errorf("%w: the payload is not detached", ErrInvalidJWS)
+	}
+
+	jwsGo.Payload = string(payload)
+	return jwsGo, nil
+}
+
+// DeserializeFromRawJWS decodes the payload (if not detached) and the signatures of the general or flattened syntax.
+func DeserializeFromRawJWS(rawJWS *JWSignatureRawJSON) (*JWSignatureGo, error) {
+	var rawSignatures []*SignatureRawJSON
+	if len(rawJWS.Signatures) > 0 {
+		if rawJWS.B64Signature != "" || rawJWS.B64ProtectedHeaders != "" || len(rawJWS.UnprotectedHeaders) > 0 {
+			return nil, fmt.Errorf("%w: the general and flattened syntaxes cannot be mixed", ErrInvalidJWS)
+		}
+		if err := json.Unmarshal(rawJWS.Signatures, &rawSignatures); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidJWS, err)
+		}
+	} else {
+		rawSignatures = []*SignatureRawJSON{{
+			B64ProtectedHeaders: rawJWS.B64ProtectedHeaders,
+			UnprotectedHeaders:  rawJWS.UnprotectedHeaders,
+			B64Signature:        rawJWS.B64Signature,
+		}}
+	}
+
+	jwsGo := &JWSignatureGo{Detached: rawJWS.Payload == nil}
+	for _, rawSignature := range rawSignatures {
+		signature, err := deserializeSignatureJWS(rawSignature)
+		if err != nil {
+			return nil, err
+		}
+		jwsGo.Signatures = append(jwsGo.Signatures, signature)
+	}
+
+	isEncoded, err := jwsGo.isPayloadEncoded()
+	if err != nil || jwsGo.Detached {
+		return jwsGo, err
+	}
+
+	if !isEncoded {
+		jwsGo.Payload = *rawJWS.Payload
+		return jwsGo, nil
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(*rawJWS.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJWS, err)
+	}
+
+	jwsGo.Payload = string(payload)
+	return jwsGo, nil
+}
+
+func deserializeSignatureJWS(rawSignature *SignatureRawJSON) (*SignatureJWS, error) {
+	if rawSignature == nil || rawSignature.B64Signature == "" {
+		return nil, ErrMissingJWSSignature
+	}
+
+	signature := &SignatureJWS{OrigProtectedHders: rawSignature.B64ProtectedHeaders}
+
+	if rawSignature.B64ProtectedHeaders != "" {
+		protectedHeadersJSON, err := base64.RawURLEncoding.DecodeString(rawSignature.B64ProtectedHeaders)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidJWS, err)
+		}
+		if err = json.Unmarshal(protectedHeadersJSON, &signature.ProtectedHeaders); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidJWS, err)
+		}
+	}
+
+	if len(rawSignature.UnprotectedHeaders) > 0 {
+		if err := json.Unmarshal(rawSignature.UnprotectedHeaders, &signature.UnprotectedHeaders); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidJWS, err)
+		}
+	}
+
+	signatureBytes, err := base64.RawURLEncoding.DecodeString(rawSignature.B64Signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJWS, err)
+	}
+	signature.Signature = string(signatureBytes)
+
+	if err = signature.checkHeaders(); err != nil {
+		return nil, err
+	}
+
+	return signature, nil
+}
+
+// isPayloadEncoded returns false only if the "b64" header is false (https://tools.ietf.org/html/rfc7797#section-3).
+func isPayloadEncoded(headers Headers) bool {
+	b64, isBool := headers[HeaderB64Payload].(bool)
+	return !isBool || b64
+}
+
+// checkCriticalHeaders checks the "crit" protected header as per https://tools.ietf.org/html/rfc7515#section-4.1.11:
+// it must be a non-empty array of unique names which are understood (supportedCriticalHeaders) and in the protected headers.
+// The "b64" header must be a boolean in the "crit" header (https://tools.ietf.org/html/rfc7797#section-6).
+func checkCriticalHeaders(protectedHeaders Headers) error {
+	if b64, found := protectedHeaders[HeaderB64Payload]; found {
+		if _, isBool := b64.(bool); !isBool {
+			return fmt.Errorf("%w: the b64 header must be a boolean", ErrInvalidCriticalHeader)
+		}
+	}
+
+	criticalValue, found := protectedHeaders[HeaderCritical]
+	if !found {
+		if _, found = protectedHeaders[HeaderB64Payload]; found {
+			return fmt.Errorf("%w: the b64 header must be critical", ErrInvalidCriticalHeader)
+		}
+		return nil
+	}
+
+	criticalNames, err := getCriticalHeaderNames(criticalValue)
+	if err != nil {
+		return err
+	}
+
+	isCritical := map[string]bool{}
+	for _, name := range criticalNames {
+		if isCritical[name] {
+			return fmt.Errorf("%w: duplicated %q", ErrInvalidCriticalHeader, name)
+		}
+		isCritical[name] = true
+
+		if !containsHeaderName(supportedCriticalHeaders, name) {
+			return fmt.Errorf("%w: %q", ErrUnsupportedCriticalHeader, name)
+		}
+		if _, found = protectedHeaders[name]; !found {
+			return fmt.Errorf("%w: %q is not in the protected headers", ErrInvalidCriticalHeader, name)
+		}
+	}
+
+	if _, found = protectedHeaders[HeaderB64Payload]; found && !isCritical[HeaderB64Payload] {
+		return fmt.Errorf("%w: the b64 header must be critical", ErrInvalidCriticalHeader)
+	}
+
+	return nil
+}
+
+// getCriticalHeaderNames returns the names of the "crit" header, which can be a []string (before serializing it)
+// or a []interface{} of strings (after deserializing it).
+func getCriticalHeaderNames(criticalValue interface{}) ([]string, error) {
+	var names []string
+	switch values := criticalValue.(type) {
+	case []string:
+		names = values
+	case []interface{}:
+		for _, value := range values {
+			name, isString := value.(string)
+			if !isString || name == "" {
+				return nil, ErrInvalidCriticalHeader
+			}
+			names = append(names, name)
+		}
+	default:
+		return nil, ErrInvalidCriticalHeader
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%w: it cannot be empty", ErrInvalidCriticalHeader)
+	}
+	return names, nil
+}
+
+// addCriticalHeader returns the "crit" header value with the name (if not already there).
+func addCriticalHeader(criticalValue interface{}, name string) []string {
+	names, _ := getCriticalHeaderNames(criticalValue)
+	if containsHeaderName(names, name) {
+		return names
+	}
+	return append(append([]string{}, names...), name)
+}
+
+func containsHeaderName(names []string, name string) bool {
+	for _, value := range names {
+		if value == name {
+			return true
+		}
+	}
+	return false
+}
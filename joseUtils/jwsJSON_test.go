@@ -0,0 +1,228 @@
+package joseUtils
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCoSignedJWS(t *testing.T) (*JWSignatureGo, *jwkUtils.JWKeySet) {
+	practitionerKey, err := jwkUtils.GenerateJWK(AlgorithmES256, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+	organizationKey, err := jwkUtils.GenerateJWK(AlgorithmEdDSA, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+
+	jwsGo := NewJWSignature([]byte(`{"resourceType":"Composition"}`))
+	require.NoError(t, jwsGo.AddSignature(Headers{HeaderType: "JOSE+JSON"}, Headers{"role": "practitioner"}, practitionerKey))
+	require.NoError(t, jwsGo.AddSignature(nil, nil, organizationKey))
+
+	practitionerPublicKey := jwkUtils.ExportPublicJWK(practitionerKey)
+	organizationPublicKey := jwkUtils.ExportPublicJWK(organizationKey)
+	return jwsGo, jwkUtils.CreateJWKeySet(&[]jwkUtils.JWK{practitionerPublicKey, organizationPublicKey})
+}
+
+func TestJWSignature_GeneralSerialization(t *testing.T) {
+	jwsGo, keys := newTestCoSignedJWS(t)
+
+	serializedJWS, err := jwsGo.SerializeGeneral(json.Marshal)
+	require.NoError(t, err)
+
+	rawJWS := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(serializedJWS, &rawJWS))
+	assert.Len(t, rawJWS["signatures"], 2)
+	assert.Nil(t, rawJWS["signature"])
+
+	deserializedJWS, err := DeserializeJWS(string(serializedJWS))
+	require.NoError(t, err)
+	assert.Equal(t, `{"resourceType":"Composition"}`, deserializedJWS.Payload)
+	require.Len(t, deserializedJWS.Signatures, 2)
+	assert.Equal(t, "practitioner", deserializedJWS.Signatures[0].Headers()["role"])
+	assert.Equal(t, AlgorithmEdDSA, deserializedJWS.Signatures[1].ProtectedHeaders[HeaderAlgorithm])
+
+	verifiedSignatures, err := deserializedJWS.Verify(keys, VerifyAllSignatures)
+	require.NoError(t, err)
+	assert.Len(t, verifiedSignatures, 2)
+
+	_, err = deserializedJWS.Verify(keys, "most")
+	assert.ErrorIs(t, err, ErrUnsupportedVerificationPolicy)
+}
+
+func TestJWSignature_VerificationPolicy(t *testing.T) {
+	jwsGo, keys := newTestCoSignedJWS(t)
+
+	// only the practitioner key is known
+	practitionerKeys := jwkUtils.CreateJWKeySet(&[]jwkUtils.JWK{keys.Keys[0]})
+	_, err := jwsGo.Verify(practitionerKeys, VerifyAllSignatures)
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	verifiedSignatures, err := jwsGo.Verify(practitionerKeys, VerifyAnySignature)
+	require.NoError(t, err)
+	require.Len(t, verifiedSignatures, 1)
+	assert.Equal(t, keys.Keys[0].Kid, verifiedSignatures[0].ProtectedHeaders[HeaderKeyID])
+
+	// a tampered payload invalidates every signature
+	jwsGo.Payload = `{"resourceType":"Patient"}`
+	_, err = jwsGo.Verify(keys, VerifyAnySignature)
+	assert.ErrorIs(t, err, ErrSignature)
+}
+
+func TestJWSignature_FlattenedAndCompactSerialization(t *testing.T) {
+	keyManager := NewInMemoryKeyManager()
+	kid, err := keyManager.Create(AlgorithmES256, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+	publicKey, err := keyManager.PublicJWK(kid)
+	require.NoError(t, err)
+	keys := jwkUtils.CreateJWKeySet(&[]jwkUtils.JWK{*publicKey})
+
+	jwsGo := NewJWSignature([]byte("payload"))
+	require.NoError(t, jwsGo.AddSignatureWithKeyManager(nil, nil, keyManager, kid))
+
+	flattenedJWS, err := jwsGo.SerializeFlattened(json.Marshal)
+	require.NoError(t, err)
+	assert.NotContains(t, string(flattenedJWS), `"signatures"`)
+
+	deserializedJWS, err := DeserializeJWS(string(flattenedJWS))
+	require.NoError(t, err)
+	_, err = deserializedJWS.Verify(keys, VerifyAllSignatures)
+	require.NoError(t, err)
+
+	compactJWS, err := deserializedJWS.SerializeCompact()
+	require.NoError(t, err)
+	deserializedJWS, err = DeserializeJWS(compactJWS)
+	require.NoError(t, err)
+	assert.Equal(t, "payload", deserializedJWS.Payload)
+	_, err = deserializedJWS.Verify(keys, VerifyAllSignatures)
+	require.NoError(t, err)
+
+	// the compact serialization of a JWT can be verified as a JWS
+	compactJWT, err := SignCompactJWTWithKeyManager(Headers{}, map[string]interface{}{"sub": "alice"}, keyManager, kid)
+	require.NoError(t, err)
+	deserializedJWS, err = DeserializeJWS(compactJWT)
+	require.NoError(t, err)
+	_, err = deserializedJWS.Verify(keys, VerifyAllSignatures)
+	require.NoError(t, err)
+
+	require.NoError(t, jwsGo.AddSignatureWithKeyManager(nil, nil, keyManager, kid))
+	_, err = jwsGo.SerializeFlattened(json.Marshal)
+	assert.Error(t, err)
+	_, err = jwsGo.SerializeCompact()
+	assert.Error(t, err)
+}
+
+func TestJWSignature_InvalidHeaders(t *testing.T) {
+	key, err := jwkUtils.GenerateJWK(AlgorithmES256, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+
+	jwsGo := NewJWSignature([]byte("payload"))
+	err = jwsGo.AddSignature(Headers{HeaderType: "JOSE"}, Headers{HeaderType: "JWT"}, key)
+	assert.ErrorIs(t, err, ErrHeadersNotDisjoint)
+
+	_, err = DeserializeJWS(`{"payload":"cGF5bG9hZA","signatures":[{"protected":"eyJhbGciOiJFUzI1NiJ9","header":{"alg":"ES256"},"signature":"c2ln"}]}`)
+	assert.ErrorIs(t, err, ErrHeadersNotDisjoint)
+
+	_, err = DeserializeJWS(`{"payload":"cGF5bG9hZA","signatures":[{"protected":"eyJhbGciOiJFUzI1NiJ9"}]}`)
+	assert.ErrorIs(t, err, ErrMissingJWSSignature)
+
+	_, err = DeserializeJWS("a.b")
+	assert.ErrorIs(t, err, ErrInvalidJWS)
+
+	_, err = jwsGo.SerializeGeneral(json.Marshal)
+	assert.ErrorIs(t, err, ErrMissingJWSSignature)
+}
+
+func TestJWSignature_Detached(t *testing.T) {
+	key, err := jwkUtils.GenerateJWK(AlgorithmES256, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+	keys := jwkUtils.CreateJWKeySet(&[]jwkUtils.JWK{jwkUtils.ExportPublicJWK(key)})
+
+	jwsGo := NewDetachedJWSignature([]byte("detached content"))
+	require.NoError(t, jwsGo.AddSignature(nil, nil, key))
+
+	flattenedJWS, err := jwsGo.SerializeFlattened(json.Marshal)
+	require.NoError(t, err)
+	assert.NotContains(t, string(flattenedJWS), `"payload"`)
+
+	compactJWS, err := jwsGo.SerializeCompact()
+	require.NoError(t, err)
+	assert.Contains(t, compactJWS, "..")
+
+	for _, serializedJWS := range []string{string(flattenedJWS), compactJWS} {
+		deserializedJWS, err := DeserializeDetachedJWS(serializedJWS, []byte("detached content"))
+		require.NoError(t, err)
+		_, err = deserializedJWS.Verify(keys, VerifyAllSignatures)
+		require.NoError(t, err)
+
+		deserializedJWS, err = DeserializeDetachedJWS(serializedJWS, []byte("other content"))
+		require.NoError(t, err)
+		_, err = deserializedJWS.Verify(keys, VerifyAllSignatures)
+		assert.ErrorIs(t, err, ErrSignature)
+	}
+
+	// the payload of a non-detached JWS cannot be replaced
+	jwsGo.Detached = false
+	compactJWS, err = jwsGo.SerializeCompact()
+	require.NoError(t, err)
+	_, err = DeserializeDetachedJWS(compactJWS, []byte("detached content"))
+	assert.ErrorIs(t, err, ErrInvalidJWS)
+}
+
+func TestJWSignature_UnencodedPayload(t *testing.T) {
+	key, err := jwkUtils.GenerateJWK(AlgorithmEdDSA, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+	keys := jwkUtils.CreateJWKeySet(&[]jwkUtils.JWK{jwkUtils.ExportPublicJWK(key)})
+
+	jwsGo := NewJWSignature([]byte("$.02"))
+	require.NoError(t, jwsGo.AddSignature(Headers{HeaderB64Payload: false}, nil, key))
+	assert.Equal(t, []string{HeaderB64Payload}, jwsGo.Signatures[0].ProtectedHeaders[HeaderCritical])
+
+	serializedJWS, err := jwsGo.SerializeGeneral(json.Marshal)
+	require.NoError(t, err)
+	assert.Contains(t, string(serializedJWS), `"payload":"$.02"`)
+
+	deserializedJWS, err := DeserializeJWS(string(serializedJWS))
+	require.NoError(t, err)
+	assert.Equal(t, "$.02", deserializedJWS.Payload)
+	_, err = deserializedJWS.Verify(keys, VerifyAllSignatures)
+	require.NoError(t, err)
+
+	// the unencoded payload contains a period, so it must be detached in the compact serialization
+	_, err = jwsGo.SerializeCompact()
+	assert.ErrorIs(t, err, ErrInvalidJWS)
+	jwsGo.Detached = true
+	compactJWS, err := jwsGo.SerializeCompact()
+	require.NoError(t, err)
+	deserializedJWS, err = DeserializeDetachedJWS(compactJWS, []byte("$.02"))
+	require.NoError(t, err)
+	_, err = deserializedJWS.Verify(keys, VerifyAllSignatures)
+	require.NoError(t, err)
+
+	// every signature must have the same "b64" value
+	err = jwsGo.AddSignature(nil, nil, key)
+	assert.ErrorIs(t, err, ErrInconsistentPayloadEncoding)
+
+	// a JWT cannot have an unencoded payload
+	partsJWT, err := CreatePartsUnsignedJWT(Headers{HeaderAlgorithm: AlgorithmEdDSA, HeaderB64Payload: false, HeaderCritical: []string{HeaderB64Payload}}, map[string]interface{}{})
+	require.NoError(t, err)
+	_, err = VerifyCompactJWT(partsJWT.Header+"."+partsJWT.Payload+".c2ln", keys)
+	assert.ErrorIs(t, err, ErrInvalidJWT)
+}
+
+func TestCheckCriticalHeaders(t *testing.T) {
+	assert.NoError(t, checkCriticalHeaders(Headers{HeaderAlgorithm: AlgorithmES256}))
+	assert.NoError(t, checkCriticalHeaders(Headers{HeaderB64Payload: true, HeaderCritical: []interface{}{HeaderB64Payload}}))
+
+	assert.ErrorIs(t, checkCriticalHeaders(Headers{HeaderCritical: []interface{}{"exp"}, "exp": 1}), ErrUnsupportedCriticalHeader)
+	assert.ErrorIs(t, checkCriticalHeaders(Headers{HeaderCritical: []interface{}{}}), ErrInvalidCriticalHeader)
+	assert.ErrorIs(t, checkCriticalHeaders(Headers{HeaderCritical: "b64", HeaderB64Payload: false}), ErrInvalidCriticalHeader)
+	assert.ErrorIs(t, checkCriticalHeaders(Headers{HeaderCritical: []interface{}{HeaderB64Payload}}), ErrInvalidCriticalHeader)
+	assert.ErrorIs(t, checkCriticalHeaders(Headers{HeaderB64Payload: false}), ErrInvalidCriticalHeader)
+	assert.ErrorIs(t, checkCriticalHeaders(Headers{HeaderB64Payload: "false", HeaderCritical: []interface{}{HeaderB64Payload}}), ErrInvalidCriticalHeader)
+	assert.ErrorIs(t, checkCriticalHeaders(Headers{HeaderB64Payload: false, HeaderCritical: []interface{}{HeaderB64Payload, HeaderB64Payload}}), ErrInvalidCriticalHeader)
+
+	// "crit" must be protected
+	signature := &SignatureJWS{ProtectedHeaders: Headers{HeaderAlgorithm: AlgorithmES256}, UnprotectedHeaders: Headers{HeaderCritical: []interface{}{"exp"}}}
+	assert.ErrorIs(t, signature.checkHeaders(), ErrInvalidCriticalHeader)
+}
@@ -0,0 +1,165 @@
+package joseUtils
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestJWK returns a new private JWK for the algorithm and use (see jwkUtils.GenerateJWK) with the "kid".
+func newTestJWK(t *testing.T, alg, use, kid string) *jwkUtils.JWK {
+	privateJWK, err := jwkUtils.GenerateJWK(alg, use)
+	require.NoError(t, err)
+	privateJWK.Kid = kid
+	return privateJWK
+}
+
+func TestSignAndVerifyCompactJWT(t *testing.T) {
+	testKeys := []*jwkUtils.JWK{
+		newTestJWK(t, AlgorithmES256, "", "kid-es256"),
+		newTestJWK(t, AlgorithmES384, "", "kid-es384"),
+		newTestJWK(t, AlgorithmES512, "", "kid-es512"),
+		newTestJWK(t, AlgorithmEdDSA, "", "kid-eddsa"),
+	}
+
+	for _, privateJWK := range testKeys {
+		t.Run(privateJWK.Alg, func(t *testing.T) {
+			payload := map[string]interface{}{"sub": "subjectID"}
+			compact, err := SignCompactJWT(Headers{HeaderType: "JWT"}, payload, privateJWK)
+			require.NoError(t, err)
+
+			keySet := jwkUtils.CreateJWKeySet(&[]jwkUtils.JWK{*privateJWK})
+			dataJWT, err := VerifyCompactJWT(compact, keySet)
+			require.NoError(t, err)
+			assert.Equal(t, privateJWK.Alg, dataJWT.Header[HeaderAlgorithm])
+			assert.Equal(t, privateJWK.Kid, dataJWT.Header[HeaderKeyID])
+			assert.Equal(t, "subjectID", dataJWT.Payload["sub"])
+		})
+	}
+}
+
+func TestVerifyCompactJWT_SelectsKeyByKid(t *testing.T) {
+	firstJWK := newTestJWK(t, AlgorithmES256, "", "first")
+	secondJWK := newTestJWK(t, AlgorithmES256, "", "second")
+	keySet := jwkUtils.CreateJWKeySet(&[]jwkUtils.JWK{*firstJWK, *secondJWK})
+
+	compact, err := SignCompactJWT(Headers{}, map[string]interface{}{"sub": "subjectID"}, secondJWK)
+	require.NoError(t, err)
+
+	_, err = VerifyCompactJWT(compact, keySet)
+	assert.NoError(t, err)
+
+	// the "kid" of the header is not in the set
+	compact, err = SignCompactJWT(Headers{HeaderKeyID: "unknown"}, map[string]interface{}{"sub": "subjectID"}, secondJWK)
+	require.NoError(t, err)
+	_, err = VerifyCompactJWT(compact, keySet)
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	// the key with the same "kid" did not sign
+	compact, err = SignCompactJWT(Headers{HeaderKeyID: "first"}, map[string]interface{}{"sub": "subjectID"}, secondJWK)
+	require.NoError(t, err)
+	_, err = VerifyCompactJWT(compact, keySet)
+	assert.ErrorIs(t, err, ErrSignature)
+}
+
+func TestVerifyCompactJWT_Errors(t *testing.T) {
+	privateJWK := newTestJWK(t, AlgorithmEdDSA, "", "kid-eddsa")
+	keySet := jwkUtils.CreateJWKeySet(&[]jwkUtils.JWK{*privateJWK})
+
+	compact, err := SignCompactJWT(Headers{}, map[string]interface{}{"sub": "subjectID"}, privateJWK)
+	require.NoError(t, err)
+
+	parts := strings.Split(compact, ".")
+	tamperedPayload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"other"}`))
+	_, err = VerifyCompactJWT(parts[0]+"."+tamperedPayload+"."+parts[2], keySet)
+	assert.ErrorIs(t, err, ErrSignature)
+
+	noneHeader := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	_, err = VerifyCompactJWT(noneHeader+"."+parts[1]+".", keySet)
+	assert.ErrorIs(t, err, ErrInvalidJWT)
+	_, err = VerifyCompactJWT(noneHeader+"."+parts[1]+"."+parts[2], keySet)
+	assert.ErrorIs(t, err, ErrUnsupportedAlgorithm)
+
+	_, err = VerifyCompactJWT("not a JWT", keySet)
+	assert.ErrorIs(t, err, ErrInvalidJWT)
+
+	_, err = SignCompactJWT(Headers{HeaderAlgorithm: AlgorithmES256}, map[string]interface{}{}, privateJWK)
+	assert.ErrorIs(t, err, ErrUnsupportedKey)
+}
+
+func TestSignCompactJWT_Compression(t *testing.T) {
+	privateJWK := newTestJWK(t, AlgorithmES256, "", "kid-es256")
+	payload := map[string]interface{}{"sub": strings.Repeat("subjectID", 20)}
+
+	compact, err := SignCompactJWT(Headers{HeaderCompression: "DEF"}, payload, privateJWK)
+	require.NoError(t, err)
+
+	dataJWT, err := VerifyCompactJWT(compact, jwkUtils.CreateJWKeySet(&[]jwkUtils.JWK{*privateJWK}))
+	require.NoError(t, err)
+	assert.Equal(t, payload["sub"], dataJWT.Payload["sub"])
+}
+
+func TestSignAndVerifyCompactJWT_PostQuantum(t *testing.T) {
+	for _, alg := range []string{jwkUtils.AlgorithmCRYDI3, jwkUtils.AlgorithmMLDSA65} {
+		t.Run(alg, func(t *testing.T) {
+			privateJWK, err := jwkUtils.GeneratePQSignatureJWK(alg)
+			require.NoError(t, err)
+			privateJWK.Kid = "kid-" + alg
+
+			compact, err := SignCompactJWT(Headers{}, map[string]interface{}{"sub": "subjectID"}, privateJWK)
+			require.NoError(t, err)
+
+			keySet := jwkUtils.CreateJWKeySet(&[]jwkUtils.JWK{*privateJWK})
+			dataJWT, err := VerifyCompactJWT(compact, keySet)
+			require.NoError(t, err)
+			assert.Equal(t, alg, dataJWT.Header[HeaderAlgorithm])
+
+			parts := strings.Split(compact, ".")
+			tamperedPayload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"other"}`))
+			_, err = VerifyCompactJWT(parts[0]+"."+tamperedPayload+"."+parts[2], keySet)
+			assert.ErrorIs(t, err, ErrSignature)
+		})
+	}
+}
+
+func TestSignAndVerifyCompactJWT_RSA(t *testing.T) {
+	for _, alg := range []string{AlgorithmRS256, AlgorithmPS256, AlgorithmPS384} {
+		t.Run(alg, func(t *testing.T) {
+			privateJWK, err := jwkUtils.GenerateJWK(alg, jwkUtils.JWKeySignType)
+			require.NoError(t, err)
+
+			compact, err := SignCompactJWT(Headers{}, map[string]interface{}{"sub": "subjectID"}, privateJWK)
+			require.NoError(t, err)
+
+			publicJWK := jwkUtils.ExportPublicJWK(privateJWK)
+			keySet := jwkUtils.CreateJWKeySet(&[]jwkUtils.JWK{publicJWK})
+			dataJWT, err := VerifyCompactJWT(compact, keySet)
+			require.NoError(t, err)
+			assert.Equal(t, alg, dataJWT.Header[HeaderAlgorithm])
+
+			// a key without "alg" can be used with any RSA algorithm, but the signature scheme must be the same
+			publicJWK.Alg = ""
+			parts := strings.Split(compact, ".")
+			otherHeader := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS512","kid":"` + privateJWK.Kid + `"}`))
+			_, err = VerifyCompactJWT(otherHeader+"."+parts[1]+"."+parts[2], jwkUtils.CreateJWKeySet(&[]jwkUtils.JWK{publicJWK}))
+			assert.ErrorIs(t, err, ErrSignature)
+		})
+	}
+}
+
+func TestCheckSigningKey(t *testing.T) {
+	privateJWK, err := jwkUtils.GenerateJWK(AlgorithmES256, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+	publicJWK := jwkUtils.ExportPublicJWK(privateJWK)
+
+	assert.NoError(t, CheckSigningKey(AlgorithmES256, &publicJWK))
+	assert.ErrorIs(t, CheckSigningKey(AlgorithmES384, &publicJWK), ErrUnsupportedKey)
+	assert.ErrorIs(t, CheckSigningKey(AlgorithmEdDSA, &publicJWK), ErrUnsupportedKey)
+	assert.ErrorIs(t, CheckSigningKey(AlgorithmES256, nil), ErrUnsupportedKey)
+	assert.ErrorIs(t, CheckSigningKey("HS256", &publicJWK), ErrUnsupportedAlgorithm)
+	assert.ErrorIs(t, CheckSigningKey(AlgorithmNone, &publicJWK), ErrUnsupportedAlgorithm)
+}
@@ -235,6 +235,7 @@ func CreatePartsUnsignedJWT(headerClaims Headers, payloadClaims interface{}) (*P
 			if err = compressionWriter.Close(); err != nil {
 				return nil, ErrCannotCreateData
 			}
+			payloadBytes = payloadBuffer.Bytes()
 		}
 	}
 	payloadBase64Url := base64.RawURLEncoding.EncodeToString(payloadBytes)
@@ -0,0 +1,256 @@
+package joseUtils
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Registered claim names (RFC 7519, section 4.1).
+const (
+	ClaimIssuer         = "iss"
+	ClaimSubject        = "sub"
+	ClaimAudience       = "aud"
+	ClaimExpirationTime = "exp"
+	ClaimNotBefore      = "nbf"
+	ClaimIssuedAt       = "iat"
+	ClaimJWTID          = "jti"
+)
+
+// Validator checks the registered claims and the "typ" and "cty" headers of a decoded JWT (e.g.: after VerifyCompactJWT).
+// The zero value only checks the "exp", "nbf" and "iat" claims (if any) and the type of the other registered claims.
+//   - Issuers: the "iss" claim must be one of them (if any).
+//   - Audiences: the "aud" claim must contain at least one of them (if any).
+//   - Subject: the "sub" claim must be the same (if not empty).
+//   - RequiredClaims: the claims which must exist (e.g.: "exp" and "jti").
+//   - MaxAge: the "iat" claim is required and the token cannot be older (if not zero).
+//   - AllowedTypes: the "typ" header must be one of them (if any), compared as per RFC 7515, section 4.1.9.
+//   - ContentType: the "cty" header must be the same (if not empty), e.g.: "JWT" for a nested JWT.
+//   - Leeway: the clock skew allowed for the "exp", "nbf" and "iat" claims.
+//   - Now: returns the current time (time.Now if nil).
+type Validator struct {
+	Issuers        []string
+	Audiences      []string
+	Subject        string
+	RequiredClaims []string
+	MaxAge         time.Duration
+	AllowedTypes   []string
+	ContentType    string
+	Leeway         time.Duration
+	Now            func() time.Time
+}
+
+// Validate checks the header and the payload claims of the decoded JWT.
+func (validator *Validator) Validate(dataJWT *DataJWT) error {
+	if dataJWT == nil {
+		return ErrInvalidJWT
+	}
+
+	return validator.ValidateClaims(dataJWT.Header, dataJWT.Payload)
+}
+
+// ValidateClaims checks the header and the payload claims. The error wraps one of the validation errors
+// (e.g.: ErrExpired, ErrInvalidAudience), so it can be checked with errors.Is.
+func (validator *Validator) ValidateClaims(header Headers, claims map[string]interface{}) error {
+	if claims == nil {
+		return ErrInvalidClaims
+	}
+
+	if err := validator.validateHeader(header); err != nil {
+		return err
+	}
+
+	for _, name := range validator.RequiredClaims {
+		if _, found := claims[name]; !found {
+			return fmt.Errorf("%w: %q", ErrMissingClaim, name)
+		}
+	}
+
+	if err := validator.validateIdentifiers(claims); err != nil {
+		return err
+	}
+
+	return validator.validateTimes(claims)
+}
+
+func (validator *Validator) validateHeader(header Headers) error {
+	if len(validator.AllowedTypes) > 0 {
+		typ, _ := header.Type()
+		if !containsMediaType(validator.AllowedTypes, typ) {
+			return fmt.Errorf("%w: %q", ErrInvalidType, typ)
+		}
+	}
+
+	if validator.ContentType != "" {
+		if cty, _ := header.ContentType(); !strings.EqualFold(cty, validator.ContentType) {
+			return fmt.Errorf("%w: %q", ErrInvalidContentType, cty)
+		}
+	}
+
+	return nil
+}
+
+func (validator *Validator) validateIdentifiers(claims map[string]interface{}) error {
+	issuer, err := getStringClaim(claims, ClaimIssuer, ErrInvalidIssuer)
+	if err != nil {
+		return err
+	}
+	if len(validator.Issuers) > 0 && !containsHeaderName(validator.Issuers, issuer) {
+		return fmt.Errorf("%w: %q", ErrInvalidIssuer, issuer)
+	}
+
+	subject, err := getStringClaim(claims, ClaimSubject, ErrInvalidSubject)
+	if err != nil {
+		return err
+	}
+	if validator.Subject != "" && subject != validator.Subject {
+		return fmt.Errorf("%w: %q", ErrInvalidSubject, subject)
+	}
+
+	if _, err = getStringClaim(claims, ClaimJWTID, ErrInvalidID); err != nil {
+		return err
+	}
+
+	audiences, err := getAudienceClaim(claims)
+	if err != nil {
+		return err
+	}
+	if len(validator.Audiences) > 0 && !containsAnyAudience(audiences, validator.Audiences) {
+		return fmt.Errorf("%w: %q", ErrInvalidAudience, audiences)
+	}
+
+	return nil
+}
+
+func (validator *Validator) validateTimes(claims map[string]interface{}) error {
+	now := time.Now()
+	if validator.Now != nil {
+		now = validator.Now()
+	}
+
+	expirationTime, err := getNumericDateClaim(claims, ClaimExpirationTime)
+	if err != nil {
+		return err
+	}
+	if expirationTime != nil && !now.Before(expirationTime.Time().Add(validator.Leeway)) {
+		return fmt.Errorf("%w: %d", ErrExpired, *expirationTime)
+	}
+
+	notBefore, err := getNumericDateClaim(claims, ClaimNotBefore)
+	if err != nil {
+		return err
+	}
+	if notBefore != nil && now.Add(validator.Leeway).Before(notBefore.Time()) {
+		return fmt.Errorf("%w: %d", ErrNotValidYet, *notBefore)
+	}
+
+	issuedAt, err := getNumericDateClaim(claims, ClaimIssuedAt)
+	if err != nil {
+		return err
+	}
+	if issuedAt != nil && now.Add(validator.Leeway).Before(issuedAt.Time()) {
+		return fmt.Errorf("%w: %d", ErrIssuedInTheFuture, *issuedAt)
+	}
+
+	if validator.MaxAge > 0 {
+		if issuedAt == nil {
+			return fmt.Errorf("%w: %q", ErrMissingClaim, ClaimIssuedAt)
+		}
+		if now.Sub(issuedAt.Time()) > validator.MaxAge+validator.Leeway {
+			return fmt.Errorf("%w: %d", ErrTooOld, *issuedAt)
+		}
+	}
+
+	return nil
+}
+
+// getStringClaim returns the claim value ("" if it does not exist) or the given error if it is not a string.
+func getStringClaim(claims map[string]interface{}, name string, invalidErr error) (string, error) {
+	value, found := claims[name]
+	if !found {
+		return "", nil
+	}
+
+	stringValue, isString := value.(string)
+	if !isString {
+		return "", fmt.Errorf("%w: it must be a string", invalidErr)
+	}
+	return stringValue, nil
+}
+
+// getAudienceClaim returns the "aud" claim, which can be a string or an array of strings.
+func getAudienceClaim(claims map[string]interface{}) (AudienceSlice, error) {
+	switch audience := claims[ClaimAudience].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return AudienceSlice{audience}, nil
+	case []string:
+		return audience, nil
+	case []interface{}:
+		audiences := AudienceSlice{}
+		for _, value := range audience {
+			stringValue, isString := value.(string)
+			if !isString {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidAudience, ErrMsgUnmarshalAudience)
+			}
+			audiences = append(audiences, stringValue)
+		}
+		return audiences, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAudience, ErrMsgUnmarshalAudience)
+	}
+}
+
+// getNumericDateClaim returns the NumericDate of the claim (nil if it does not exist).
+// The value can be a float64 (decoded JSON), a json.Number or an integer.
+func getNumericDateClaim(claims map[string]interface{}, name string) (*NumericDate, error) {
+	var seconds float64
+	switch value := claims[name].(type) {
+	case nil:
+		return nil, nil
+	case float64:
+		seconds = value
+	case json.Number:
+		floatValue, err := value.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("%w (%s)", ErrInvalidNumericDate, name)
+		}
+		seconds = floatValue
+	case int64:
+		seconds = float64(value)
+	case int:
+		seconds = float64(value)
+	default:
+		return nil, fmt.Errorf("%w (%s)", ErrInvalidNumericDate, name)
+	}
+
+	if math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+		return nil, fmt.Errorf("%w (%s)", ErrInvalidNumericDate, name)
+	}
+
+	numericDate := NumericDate(seconds)
+	return &numericDate, nil
+}
+
+func containsAnyAudience(audiences AudienceSlice, expectedAudiences []string) bool {
+	for _, expectedAudience := range expectedAudiences {
+		if audiences.Contains(expectedAudience) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsMediaType compares the media types ignoring the case and the "application/" prefix (RFC 7515, section 4.1.9).
+func containsMediaType(mediaTypes []string, mediaType string) bool {
+	normalizedType := strings.TrimPrefix(strings.ToLower(mediaType), "application/")
+	for _, allowedType := range mediaTypes {
+		if strings.TrimPrefix(strings.ToLower(allowedType), "application/") == normalizedType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package joseUtils
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestValidator(now time.Time) *Validator {
+	return &Validator{
+		Issuers:        []string{"did:example:issuer"},
+		Audiences:      []string{"https://api.example.com", "did:example:api"},
+		Subject:        "did:example:alice",
+		RequiredClaims: []string{ClaimExpirationTime, ClaimJWTID},
+		MaxAge:         10 * time.Minute,
+		AllowedTypes:   []string{"at+jwt"},
+		Leeway:         30 * time.Second,
+		Now:            func() time.Time { return now },
+	}
+}
+
+func newTestClaims(now time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		ClaimIssuer:         "did:example:issuer",
+		ClaimSubject:        "did:example:alice",
+		ClaimAudience:       []interface{}{"did:example:other", "did:example:api"},
+		ClaimExpirationTime: float64(now.Add(5 * time.Minute).Unix()),
+		ClaimIssuedAt:       float64(now.Add(-time.Minute).Unix()),
+		ClaimJWTID:          "jti-1",
+	}
+}
+
+func TestValidator_Validate(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	validator := newTestValidator(now)
+	header := Headers{HeaderType: "application/AT+JWT"}
+
+	require.NoError(t, validator.Validate(&DataJWT{Header: header, Payload: newTestClaims(now)}))
+
+	// the claims of a decoded JSON payload
+	payloadJSON, err := json.Marshal(newTestClaims(now))
+	require.NoError(t, err)
+	claims := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(payloadJSON, &claims))
+	require.NoError(t, validator.ValidateClaims(header, claims))
+
+	// the zero value only checks the times
+	assert.NoError(t, (&Validator{Now: validator.Now}).ValidateClaims(nil, newTestClaims(now)))
+	assert.ErrorIs(t, validator.Validate(nil), ErrInvalidJWT)
+}
+
+func TestValidator_ValidateErrors(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	testCases := []struct {
+		name     string
+		header   Headers
+		modify   func(claims map[string]interface{})
+		expected error
+	}{
+		{"issuer", nil, func(claims map[string]interface{}) { claims[ClaimIssuer] = "did:example:other" }, ErrInvalidIssuer},
+		{"issuer type", nil, func(claims map[string]interface{}) { claims[ClaimIssuer] = 1.0 }, ErrInvalidIssuer},
+		{"subject", nil, func(claims map[string]interface{}) { claims[ClaimSubject] = "did:example:bob" }, ErrInvalidSubject},
+		{"audience", nil, func(claims map[string]interface{}) { claims[ClaimAudience] = "did:example:other" }, ErrInvalidAudience},
+		{"audience type", nil, func(claims map[string]interface{}) { claims[ClaimAudience] = []interface{}{1.0} }, ErrInvalidAudience},
+		{"jti type", nil, func(claims map[string]interface{}) { claims[ClaimJWTID] = 1.0 }, ErrInvalidID},
+		{"required", nil, func(claims map[string]interface{}) { delete(claims, ClaimJWTID) }, ErrMissingClaim},
+		{"expired", nil, func(claims map[string]interface{}) {
+			claims[ClaimExpirationTime] = float64(now.Add(-31 * time.Second).Unix())
+		}, ErrExpired},
+		{"not valid yet", nil, func(claims map[string]interface{}) {
+			claims[ClaimNotBefore] = float64(now.Add(time.Minute).Unix())
+		}, ErrNotValidYet},
+		{"issued in the future", nil, func(claims map[string]interface{}) {
+			claims[ClaimIssuedAt] = float64(now.Add(time.Minute).Unix())
+		}, ErrIssuedInTheFuture},
+		{"too old", nil, func(claims map[string]interface{}) {
+			claims[ClaimIssuedAt] = float64(now.Add(-11 * time.Minute).Unix())
+		}, ErrTooOld},
+		{"max age without iat", nil, func(claims map[string]interface{}) { delete(claims, ClaimIssuedAt) }, ErrMissingClaim},
+		{"numeric date", nil, func(claims map[string]interface{}) { claims[ClaimExpirationTime] = "tomorrow" }, ErrInvalidNumericDate},
+		{"type", Headers{HeaderType: "JWT"}, func(claims map[string]interface{}) {}, ErrInvalidType},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			header := testCase.header
+			if header == nil {
+				header = Headers{HeaderType: "at+jwt"}
+			}
+
+			claims := newTestClaims(now)
+			testCase.modify(claims)
+			assert.ErrorIs(t, newTestValidator(now).ValidateClaims(header, claims), testCase.expected)
+		})
+	}
+}
+
+func TestValidator_Leeway(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	claims := newTestClaims(now)
+	claims[ClaimExpirationTime] = float64(now.Add(-10 * time.Second).Unix())
+	claims[ClaimNotBefore] = float64(now.Add(10 * time.Second).Unix())
+
+	validator := newTestValidator(now)
+	assert.NoError(t, validator.ValidateClaims(Headers{HeaderType: "at+jwt"}, claims))
+
+	validator.Leeway = 0
+	assert.ErrorIs(t, validator.ValidateClaims(Headers{HeaderType: "at+jwt"}, claims), ErrExpired)
+
+	validator = &Validator{ContentType: "JWT"}
+	assert.ErrorIs(t, validator.ValidateClaims(Headers{HeaderContentType: "json"}, map[string]interface{}{}), ErrInvalidContentType)
+	assert.NoError(t, validator.ValidateClaims(Headers{HeaderContentType: "jwt"}, map[string]interface{}{}))
+}
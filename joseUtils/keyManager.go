@@ -0,0 +1,90 @@
+package joseUtils
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+)
+
+var (
+	ErrKeyManagerKeyNotFound   = errors.New("the key does not exist in the key manager")
+	ErrUnsupportedKeyOperation = errors.New("the key manager does not support the operation for the key")
+)
+
+// KeyManager creates and uses private keys without exposing them (e.g.: in memory, Tink keysets, a KMS or a HSM).
+// The keys are identified by their "kid" and only the public JWK can be exported.
+// The JOSE functions with a KeyManager (SignCompactJWTWithKeyManager, DecryptJWEWithKeyManager,
+// EncryptAuthcryptJWEWithKeyManager, DecryptAuthcryptJWEWithKeyManager, NewDPoPProofWithKeyManager,
+// JWSignatureGo.AddSignatureWithKeyManager and NewAccessTokenIssuerWithKeyManager) are the same as the ones
+// with a private JWK, so the key storage can be changed without changing the code.
+// A key manager can support only some operations (e.g.: TinkKeyManager only signs), the others return ErrUnsupportedKeyOperation.
+type KeyManager interface {
+	// Create generates a new key for the algorithm and use (see jwkUtils.GenerateJWK) and returns its "kid".
+	Create(alg, use string) (string, error)
+	// PublicJWK returns the public JWK of the key.
+	PublicJWK(kid string) (*jwkUtils.JWK, error)
+	// Sign returns the JWS signature of the signing input for the JWS "alg" (e.g.: R || S for ES256).
+	Sign(kid, alg string, signingInput []byte) ([]byte, error)
+	// DeriveSharedSecret returns the ECDH shared secret (Z) with the public key of the other party (e.g.: the JWE "epk").
+	DeriveSharedSecret(kid string, publicJWK *jwkUtils.JWK) ([]byte, error)
+	// Decapsulate returns the KEM shared secret (Z) of the ciphertext (e.g.: the JWE "ek").
+	Decapsulate(kid string, ciphertext []byte) ([]byte, error)
+	// Rotate creates a new key with the same algorithm and use and returns its "kid" (the previous key is not destroyed).
+	Rotate(kid string) (string, error)
+	// Destroy removes the key, so it cannot be used anymore.
+	Destroy(kid string) error
+}
+
+// KeyImporter is a key manager which can import a private JWK (e.g.: InMemoryKeyManager),
+// so the unwrapped private keys are only kept by the key manager (see JWKWrapper.UnwrapToKeyManager).
+type KeyImporter interface {
+	// Import adds the private JWK and returns its "kid".
+	Import(privateJWK *jwkUtils.JWK) (string, error)
+}
+
+// SignCompactJWTWithKeyManager is the same as SignCompactJWT but the signature is created by the key manager.
+func SignCompactJWTWithKeyManager(headers Headers, payload interface{}, keyManager KeyManager, kid string) (string, error) {
+	publicKey, err := keyManager.PublicJWK(kid)
+	if err != nil {
+		return "", err
+	}
+
+	return signCompactJWT(headers, payload, publicKey, func(alg string, signingInput []byte) ([]byte, error) {
+		return keyManager.Sign(kid, alg, signingInput)
+	})
+}
+
+// DecryptJWEWithKeyManager is the same as DecryptJWE but the shared secret is derived (ECDH-ES)
+// or decapsulated (ML-KEM) by the key manager.
+func DecryptJWEWithKeyManager(jwe *JWEncryptionGo, keyManager KeyManager, kid string) ([]byte, error) {
+	publicKey, err := keyManager.PublicJWK(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	return decryptJWE(jwe, publicKey.Kid, func(alg string, headers Headers) ([]byte, error) {
+		if kemAlg, isKEM := getKEMAlgorithm(alg); isKEM {
+			if publicKey.Alg != kemAlg {
+				return nil, ErrUnsupportedKey
+			}
+
+			ciphertext, found := headers.EncapsulatedKey()
+			if !found {
+				return nil, ErrMissingKeyAgreementHeader
+			}
+			return keyManager.Decapsulate(kid, ciphertext)
+		}
+
+		epk, found := headers.EphemeralPublicKey()
+		if !found {
+			return nil, ErrMissingKeyAgreementHeader
+		}
+		return keyManager.DeriveSharedSecret(kid, epk)
+	})
+}
+
+// keyNotFoundInKeyManager returns ErrKeyManagerKeyNotFound with the "kid".
+func keyNotFoundInKeyManager(kid string) error {
+	return fmt.Errorf("%w: %q", ErrKeyManagerKeyNotFound, kid)
+}
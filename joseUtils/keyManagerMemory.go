@@ -0,0 +1,209 @@
+package joseUtils
+
+import (
+	"sync"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+)
+
+// InMemoryKeyManager is a KeyManager which keeps the private JWKs in memory (e.g.: for tests or short-lived keys).
+// It is safe for concurrent use.
+type InMemoryKeyManager struct {
+	mutex sync.RWMutex
+	keys  map[string]*inMemoryKey
+}
+
+// inMemoryKey has the private JWK and the algorithm and use to generate it again on rotation.
+type inMemoryKey struct {
+	jwk *jwkUtils.JWK
+	alg string
+	use string
+}
+
+// NewInMemoryKeyManager returns an empty key manager.
+func NewInMemoryKeyManager() *InMemoryKeyManager {
+	return &InMemoryKeyManager{keys: map[string]*inMemoryKey{}}
+}
+
+// Create generates a new private JWK (see jwkUtils.GenerateJWK) and returns its "kid" (the JWK thumbprint).
+func (keyManager *InMemoryKeyManager) Create(alg, use string) (string, error) {
+	privateJWK, err := jwkUtils.GenerateJWK(alg, use)
+	if err != nil {
+		return "", err
+	}
+
+	keyManager.mutex.Lock()
+	defer keyManager.mutex.Unlock()
+	keyManager.keys[privateJWK.Kid] = &inMemoryKey{jwk: privateJWK, alg: alg, use: use}
+	return privateJWK.Kid, nil
+}
+
+// Import adds an existing private JWK (e.g.: unwrapped by a joseUtils.JWKWrapper) and returns its "kid",
+// which is the JWK thumbprint if the JWK has no "kid". The JWK is copied, so it can be cleared by the caller.
+func (keyManager *InMemoryKeyManager) Import(privateJWK *jwkUtils.JWK) (string, error) {
+	if privateJWK == nil || !privateJWK.HasPrivateMembers() {
+		return "", jwkUtils.ErrMissingKeyMaterial
+	}
+
+	key := &inMemoryKey{jwk: &jwkUtils.JWK{}, alg: getGenerationAlgorithm(privateJWK)}
+	*key.jwk = *privateJWK
+	if key.jwk.Kid == "" {
+		if key.jwk.Kid = jwkUtils.CalculateThumbprintJWK(key.jwk); key.jwk.Kid == "" {
+			return "", jwkUtils.ErrUnsupportedKey
+		}
+	}
+	if key.jwk.Use != nil {
+		key.use = *key.jwk.Use
+	}
+
+	keyManager.mutex.Lock()
+	defer keyManager.mutex.Unlock()
+	keyManager.keys[key.jwk.Kid] = key
+	return key.jwk.Kid, nil
+}
+
+// PublicJWK returns a copy of the JWK without the private members.
+func (keyManager *InMemoryKeyManager) PublicJWK(kid string) (*jwkUtils.JWK, error) {
+	key, err := keyManager.getKey(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	publicJWK := jwkUtils.ExportPublicJWK(key.jwk)
+	return &publicJWK, nil
+}
+
+// Sign returns the JWS signature by using the algorithm of the "alg", which must be compatible with the key.
+func (keyManager *InMemoryKeyManager) Sign(kid, alg string, signingInput []byte) ([]byte, error) {
+	key, err := keyManager.getKey(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = key.jwk.CheckKeyOperation(jwkUtils.KeyOperationSign); err != nil {
+		return nil, err
+	}
+
+	algorithm, err := getJWSAlgorithm(alg)
+	if err != nil {
+		return nil, err
+	}
+
+	if !algorithm.matches(key.jwk) || (key.jwk.Alg != "" && key.jwk.Alg != alg) {
+		return nil, ErrUnsupportedKey
+	}
+
+	return algorithm.sign(key.jwk, signingInput)
+}
+
+// DeriveSharedSecret returns the ECDH shared secret of an EC or X25519 key with a public key on the same curve.
+func (keyManager *InMemoryKeyManager) DeriveSharedSecret(kid string, publicJWK *jwkUtils.JWK) ([]byte, error) {
+	key, err := keyManager.getKey(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = key.jwk.CheckKeyOperation(jwkUtils.KeyOperationDeriveBits); err != nil {
+		return nil, err
+	}
+
+	privateKey, err := jwkUtils.GetECDHPrivateKey(key.jwk)
+	if err != nil {
+		return nil, ErrUnsupportedKeyOperation
+	}
+
+	publicKey, err := jwkUtils.GetECDHPublicKey(publicJWK)
+	if err != nil || publicKey.Curve() != privateKey.Curve() {
+		return nil, ErrUnsupportedKey
+	}
+
+	return privateKey.ECDH(publicKey)
+}
+
+// Decapsulate returns the shared secret of a ML-KEM or hybrid key for the KEM ciphertext.
+func (keyManager *InMemoryKeyManager) Decapsulate(kid string, ciphertext []byte) ([]byte, error) {
+	key, err := keyManager.getKey(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = key.jwk.CheckKeyOperation(jwkUtils.KeyOperationDeriveBits); err != nil {
+		return nil, err
+	}
+
+	privateKey, err := jwkUtils.GetPQKemPrivateKey(key.jwk)
+	if err != nil {
+		return nil, ErrUnsupportedKeyOperation
+	}
+
+	if len(ciphertext) != privateKey.Scheme().CiphertextSize() {
+		return nil, ErrKeyUnwrap
+	}
+
+	return privateKey.Scheme().Decapsulate(privateKey, ciphertext)
+}
+
+// Rotate creates a new key with the algorithm and use of the given one, which is kept until it is destroyed.
+func (keyManager *InMemoryKeyManager) Rotate(kid string) (string, error) {
+	key, err := keyManager.getKey(kid)
+	if err != nil {
+		return "", err
+	}
+
+	if key.alg == "" {
+		return "", ErrUnsupportedKeyOperation
+	}
+
+	return keyManager.Create(key.alg, key.use)
+}
+
+// Destroy removes the key from memory.
+func (keyManager *InMemoryKeyManager) Destroy(kid string) error {
+	keyManager.mutex.Lock()
+	defer keyManager.mutex.Unlock()
+
+	if _, found := keyManager.keys[kid]; !found {
+		return keyNotFoundInKeyManager(kid)
+	}
+
+	delete(keyManager.keys, kid)
+	return nil
+}
+
+func (keyManager *InMemoryKeyManager) getKey(kid string) (*inMemoryKey, error) {
+	keyManager.mutex.RLock()
+	defer keyManager.mutex.RUnlock()
+
+	key, found := keyManager.keys[kid]
+	if !found {
+		return nil, keyNotFoundInKeyManager(kid)
+	}
+	return key, nil
+}
+
+// getGenerationAlgorithm returns the algorithm to generate a key like the given one (see jwkUtils.GenerateJWK) or "".
+func getGenerationAlgorithm(key *jwkUtils.JWK) string {
+	if key.Alg != "" {
+		return key.Alg
+	}
+
+	crv := ""
+	if key.Crv != nil {
+		crv = *key.Crv
+	}
+
+	switch {
+	case key.Kty == jwkUtils.KeyTypeEC && crv == jwkUtils.CurveP256:
+		return AlgorithmES256
+	case key.Kty == jwkUtils.KeyTypeEC && crv == jwkUtils.CurveP384:
+		return AlgorithmES384
+	case key.Kty == jwkUtils.KeyTypeEC && crv == jwkUtils.CurveP521:
+		return AlgorithmES512
+	case key.Kty == jwkUtils.KeyTypeOKP && crv == jwkUtils.CurveEd25519:
+		return AlgorithmEdDSA
+	case key.Kty == jwkUtils.KeyTypeOKP && crv == jwkUtils.CurveX25519:
+		return jwkUtils.AlgorithmX25519
+	default:
+		return "" // e.g.: a RSA key without "alg"
+	}
+}
@@ -0,0 +1,239 @@
+package joseUtils
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"math/big"
+	"sync"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+	"github.com/google/tink/go/keyset"
+	commonpb "github.com/google/tink/go/proto/common_go_proto"
+	ecdsapb "github.com/google/tink/go/proto/ecdsa_go_proto"
+	ed25519pb "github.com/google/tink/go/proto/ed25519_go_proto"
+	tinkpb "github.com/google/tink/go/proto/tink_go_proto"
+	"github.com/google/tink/go/signature"
+	"github.com/google/tink/go/tink"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	tinkECDSAPublicKeyTypeURL   = "type.googleapis.com/google.crypto.tink.EcdsaPublicKey"
+	tinkEd25519PublicKeyTypeURL = "type.googleapis.com/google.crypto.tink.Ed25519PublicKey"
+)
+
+// TinkKeyManager is a KeyManager which keeps the keys in Tink keysets, so they can be stored encrypted
+// by a KMS master key (see Keyset and ImportKeyset). It only supports the ES256 and EdDSA signing keys:
+// DeriveSharedSecret and Decapsulate always return ErrUnsupportedKeyOperation, so the JWE keys
+// (ECDH-ES, ECDH-1PU and ML-KEM) have to be in another KeyManager (e.g.: InMemoryKeyManager or a KMS / HSM one).
+// It is safe for concurrent use.
+type TinkKeyManager struct {
+	mutex sync.RWMutex
+	keys  map[string]*tinkKey
+}
+
+// tinkKey has the keyset handle, the signer primitive and the public JWK.
+type tinkKey struct {
+	handle    *keyset.Handle
+	signer    tink.Signer
+	publicJWK *jwkUtils.JWK
+}
+
+// NewTinkKeyManager returns an empty key manager.
+func NewTinkKeyManager() *TinkKeyManager {
+	return &TinkKeyManager{keys: map[string]*tinkKey{}}
+}
+
+// Create generates a new Tink keyset for ES256 (IEEE P1363 signatures) or EdDSA (Ed25519)
+// and returns its "kid" (the JWK thumbprint). The use can only be "sig".
+func (keyManager *TinkKeyManager) Create(alg, use string) (string, error) {
+	if use != "" && use != jwkUtils.JWKeySignType {
+		return "", ErrUnsupportedKeyOperation
+	}
+
+	var template *tinkpb.KeyTemplate
+	switch alg {
+	case AlgorithmES256:
+		template = signature.ECDSAP256RawKeyTemplate()
+	case AlgorithmEdDSA:
+		template = signature.ED25519KeyWithoutPrefixTemplate()
+	default:
+		return "", ErrUnsupportedAlgorithm
+	}
+
+	handle, err := keyset.NewHandle(template)
+	if err != nil {
+		return "", err
+	}
+
+	return keyManager.ImportKeyset(handle)
+}
+
+// ImportKeyset adds a Tink keyset with a RAW ECDSA P-256 (IEEE P1363) or Ed25519 primary key
+// (e.g.: read with keyset.Read and a KMS master key) and returns its "kid".
+func (keyManager *TinkKeyManager) ImportKeyset(handle *keyset.Handle) (string, error) {
+	publicJWK, err := getTinkPublicJWK(handle)
+	if err != nil {
+		return "", err
+	}
+
+	signer, err := signature.NewSigner(handle)
+	if err != nil {
+		return "", err
+	}
+
+	keyManager.mutex.Lock()
+	defer keyManager.mutex.Unlock()
+	keyManager.keys[publicJWK.Kid] = &tinkKey{handle: handle, signer: signer, publicJWK: publicJWK}
+	return publicJWK.Kid, nil
+}
+
+// Keyset returns the keyset handle of the key, to write it encrypted with a KMS master key (see keyset.Handle.Write).
+func (keyManager *TinkKeyManager) Keyset(kid string) (*keyset.Handle, error) {
+	key, err := keyManager.getKey(kid)
+	if err != nil {
+		return nil, err
+	}
+	return key.handle, nil
+}
+
+// PublicJWK returns a copy of the public JWK of the keyset.
+func (keyManager *TinkKeyManager) PublicJWK(kid string) (*jwkUtils.JWK, error) {
+	key, err := keyManager.getKey(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	publicJWK := *key.publicJWK
+	return &publicJWK, nil
+}
+
+// Sign returns the JWS signature created by the Tink signer. The "alg" must be the one of the key.
+func (keyManager *TinkKeyManager) Sign(kid, alg string, signingInput []byte) ([]byte, error) {
+	key, err := keyManager.getKey(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	if alg != key.publicJWK.Alg {
+		return nil, ErrUnsupportedKey
+	}
+
+	return key.signer.Sign(signingInput)
+}
+
+// DeriveSharedSecret is not supported by the Tink signing keys.
+func (keyManager *TinkKeyManager) DeriveSharedSecret(kid string, publicJWK *jwkUtils.JWK) ([]byte, error) {
+	if _, err := keyManager.getKey(kid); err != nil {
+		return nil, err
+	}
+	return nil, ErrUnsupportedKeyOperation
+}
+
+// Decapsulate is not supported by the Tink signing keys.
+func (keyManager *TinkKeyManager) Decapsulate(kid string, ciphertext []byte) ([]byte, error) {
+	if _, err := keyManager.getKey(kid); err != nil {
+		return nil, err
+	}
+	return nil, ErrUnsupportedKeyOperation
+}
+
+// Rotate creates a new keyset with the algorithm of the given one, which is kept until it is destroyed.
+func (keyManager *TinkKeyManager) Rotate(kid string) (string, error) {
+	key, err := keyManager.getKey(kid)
+	if err != nil {
+		return "", err
+	}
+
+	return keyManager.Create(key.publicJWK.Alg, jwkUtils.JWKeySignType)
+}
+
+// Destroy removes the keyset from memory.
+func (keyManager *TinkKeyManager) Destroy(kid string) error {
+	keyManager.mutex.Lock()
+	defer keyManager.mutex.Unlock()
+
+	if _, found := keyManager.keys[kid]; !found {
+		return keyNotFoundInKeyManager(kid)
+	}
+
+	delete(keyManager.keys, kid)
+	return nil
+}
+
+func (keyManager *TinkKeyManager) getKey(kid string) (*tinkKey, error) {
+	keyManager.mutex.RLock()
+	defer keyManager.mutex.RUnlock()
+
+	key, found := keyManager.keys[kid]
+	if !found {
+		return nil, keyNotFoundInKeyManager(kid)
+	}
+	return key, nil
+}
+
+// getTinkPublicJWK returns the public JWK of the primary key of the keyset with the "alg", "use" and "kid" members.
+func getTinkPublicJWK(handle *keyset.Handle) (*jwkUtils.JWK, error) {
+	publicHandle, err := handle.Public()
+	if err != nil {
+		return nil, err
+	}
+
+	publicKeyset := &keyset.MemReaderWriter{}
+	if err = publicHandle.WriteWithNoSecrets(publicKeyset); err != nil {
+		return nil, err
+	}
+
+	var keyData *tinkpb.KeyData
+	for _, key := range publicKeyset.Keyset.Key {
+		if key.KeyId == publicKeyset.Keyset.PrimaryKeyId {
+			keyData = key.KeyData
+			if key.OutputPrefixType != tinkpb.OutputPrefixType_RAW {
+				return nil, ErrUnsupportedKey // the JWS signatures cannot have the Tink prefix
+			}
+		}
+	}
+	if keyData == nil {
+		return nil, ErrUnsupportedKey
+	}
+
+	var publicJWK *jwkUtils.JWK
+	var alg string
+	switch keyData.TypeUrl {
+	case tinkECDSAPublicKeyTypeURL:
+		publicKey := &ecdsapb.EcdsaPublicKey{}
+		if err = proto.Unmarshal(keyData.Value, publicKey); err != nil {
+			return nil, err
+		}
+		if publicKey.Params.GetCurve() != commonpb.EllipticCurveType_NIST_P256 || publicKey.Params.GetHashType() != commonpb.HashType_SHA256 ||
+			publicKey.Params.GetEncoding() != ecdsapb.EcdsaSignatureEncoding_IEEE_P1363 {
+			return nil, ErrUnsupportedKey
+		}
+		alg = AlgorithmES256
+		publicJWK, err = jwkUtils.FromCryptoKey(&ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(publicKey.X),
+			Y:     new(big.Int).SetBytes(publicKey.Y),
+		})
+	case tinkEd25519PublicKeyTypeURL:
+		publicKey := &ed25519pb.Ed25519PublicKey{}
+		if err = proto.Unmarshal(keyData.Value, publicKey); err != nil {
+			return nil, err
+		}
+		alg = AlgorithmEdDSA
+		publicJWK, err = jwkUtils.FromCryptoKey(ed25519.PublicKey(publicKey.KeyValue))
+	default:
+		return nil, ErrUnsupportedKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	use := jwkUtils.JWKeySignType
+	publicJWK.Alg, publicJWK.Use = alg, &use
+	if publicJWK.Kid == "" {
+		publicJWK.Kid = jwkUtils.CalculateThumbprintJWK(publicJWK)
+	}
+	return publicJWK, nil
+}
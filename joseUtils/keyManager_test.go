@@ -0,0 +1,191 @@
+package joseUtils
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+	"github.com/google/tink/go/keyset"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignCompactJWTWithKeyManager(t *testing.T) {
+	keyManagers := map[string]KeyManager{
+		"memory": NewInMemoryKeyManager(),
+		"tink":   NewTinkKeyManager(),
+	}
+
+	for name, keyManager := range keyManagers {
+		for _, alg := range []string{AlgorithmES256, AlgorithmEdDSA} {
+			t.Run(name+" "+alg, func(t *testing.T) {
+				kid, err := keyManager.Create(alg, jwkUtils.JWKeySignType)
+				require.NoError(t, err)
+
+				publicKey, err := keyManager.PublicJWK(kid)
+				require.NoError(t, err)
+				assert.Equal(t, kid, publicKey.Kid)
+				assert.False(t, publicKey.HasPrivateMembers())
+
+				compact, err := SignCompactJWTWithKeyManager(Headers{}, map[string]interface{}{"sub": "alice"}, keyManager, kid)
+				require.NoError(t, err)
+
+				dataJWT, err := VerifyCompactJWT(compact, jwkUtils.CreateJWKeySet(&[]jwkUtils.JWK{*publicKey}))
+				require.NoError(t, err)
+				assert.Equal(t, "alice", dataJWT.Payload["sub"])
+				assert.Equal(t, kid, dataJWT.Header[HeaderKeyID])
+
+				_, err = SignCompactJWTWithKeyManager(Headers{HeaderAlgorithm: AlgorithmES384}, map[string]interface{}{}, keyManager, kid)
+				assert.Error(t, err)
+			})
+		}
+	}
+}
+
+func TestDecryptJWEWithKeyManager(t *testing.T) {
+	testCases := []struct {
+		keyAlg string
+		alg    string
+	}{
+		{AlgorithmES256, ECDHESA256KWALG},
+		{jwkUtils.AlgorithmX25519, ECDHESALG},
+		{jwkUtils.AlgorithmMLKEM768, MLKEM768A256KWALG},
+		{jwkUtils.AlgorithmX25519MLKEM768, X25519MLKEM768ALG},
+	}
+
+	keyManager := NewInMemoryKeyManager()
+	for _, testCase := range testCases {
+		t.Run(testCase.alg, func(t *testing.T) {
+			kid, err := keyManager.Create(testCase.keyAlg, jwkUtils.JWKeyEncType)
+			require.NoError(t, err)
+			publicKey, err := keyManager.PublicJWK(kid)
+			require.NoError(t, err)
+
+			jwe, err := EncryptJWE([]byte("secret message"), []*jwkUtils.JWK{publicKey}, &EncryptOptions{Algorithm: testCase.alg})
+			require.NoError(t, err)
+			compactJWE, err := jwe.CompactSoleRecipientJWE(json.Marshal)
+			require.NoError(t, err)
+			deserializedJWE, err := DeserializeJWE(compactJWE)
+			require.NoError(t, err)
+
+			plaintext, err := DecryptJWEWithKeyManager(deserializedJWE, keyManager, kid)
+			require.NoError(t, err)
+			assert.Equal(t, "secret message", string(plaintext))
+		})
+	}
+}
+
+func TestAuthcryptJWEWithKeyManager(t *testing.T) {
+	keyManager := NewInMemoryKeyManager()
+	senderKid, err := keyManager.Create(jwkUtils.AlgorithmX25519, jwkUtils.JWKeyEncType)
+	require.NoError(t, err)
+	recipientKid, err := keyManager.Create(jwkUtils.AlgorithmX25519, jwkUtils.JWKeyEncType)
+	require.NoError(t, err)
+
+	senderPublicKey, err := keyManager.PublicJWK(senderKid)
+	require.NoError(t, err)
+	recipientPublicKey, err := keyManager.PublicJWK(recipientKid)
+	require.NoError(t, err)
+	resolveSenderKey := NewJWKeySetSenderKeyResolver(jwkUtils.CreateJWKeySet(&[]jwkUtils.JWK{*senderPublicKey}))
+
+	jwe, err := EncryptAuthcryptJWEWithKeyManager([]byte("secret message"), keyManager, senderKid, []*jwkUtils.JWK{recipientPublicKey}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, ECDH1PUA256KWALG, jwe.ProtectedHeaders[HeaderAlgorithm])
+	assert.Equal(t, senderKid, jwe.ProtectedHeaders[HeaderSenderKeyID])
+
+	compactJWE, err := jwe.CompactSoleRecipientJWE(json.Marshal)
+	require.NoError(t, err)
+	deserializedJWE, err := DeserializeJWE(compactJWE)
+	require.NoError(t, err)
+
+	plaintext, err := DecryptAuthcryptJWEWithKeyManager(deserializedJWE, keyManager, recipientKid, resolveSenderKey)
+	require.NoError(t, err)
+	assert.Equal(t, "secret message", string(plaintext))
+
+	// the Tink key manager cannot derive the ECDH shared secrets
+	tinkKeyManager := NewTinkKeyManager()
+	tinkKid, err := tinkKeyManager.Create(AlgorithmES256, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+	p256Kid, err := keyManager.Create(AlgorithmES256, jwkUtils.JWKeyEncType)
+	require.NoError(t, err)
+	p256PublicKey, err := keyManager.PublicJWK(p256Kid)
+	require.NoError(t, err)
+	_, err = EncryptAuthcryptJWEWithKeyManager([]byte("secret message"), tinkKeyManager, tinkKid, []*jwkUtils.JWK{p256PublicKey}, nil)
+	assert.ErrorIs(t, err, ErrUnsupportedKeyOperation)
+}
+
+func TestInMemoryKeyManager_ImportRotateAndDestroy(t *testing.T) {
+	keyManager := NewInMemoryKeyManager()
+
+	privateJWK, err := jwkUtils.GenerateJWK(AlgorithmES256, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+	kid, err := keyManager.Import(privateJWK)
+	require.NoError(t, err)
+	assert.Equal(t, privateJWK.Kid, kid)
+
+	publicKey := jwkUtils.ExportPublicJWK(privateJWK)
+	_, err = keyManager.Import(&publicKey)
+	assert.ErrorIs(t, err, jwkUtils.ErrMissingKeyMaterial)
+
+	newKid, err := keyManager.Rotate(kid)
+	require.NoError(t, err)
+	assert.NotEqual(t, kid, newKid)
+	newPublicKey, err := keyManager.PublicJWK(newKid)
+	require.NoError(t, err)
+	assert.Equal(t, jwkUtils.CurveP256, *newPublicKey.Crv)
+
+	// an encryption key cannot sign
+	encKid, err := keyManager.Create(AlgorithmES256, jwkUtils.JWKeyEncType)
+	require.NoError(t, err)
+	_, err = keyManager.Sign(encKid, AlgorithmES256, []byte("input"))
+	assert.ErrorIs(t, err, jwkUtils.ErrKeyUseMismatch)
+
+	require.NoError(t, keyManager.Destroy(kid))
+	_, err = keyManager.PublicJWK(kid)
+	assert.ErrorIs(t, err, ErrKeyManagerKeyNotFound)
+	assert.ErrorIs(t, keyManager.Destroy(kid), ErrKeyManagerKeyNotFound)
+	_, err = keyManager.Rotate(kid)
+	assert.ErrorIs(t, err, ErrKeyManagerKeyNotFound)
+}
+
+func TestTinkKeyManager_Keyset(t *testing.T) {
+	keyManager := NewTinkKeyManager()
+
+	kid, err := keyManager.Create(AlgorithmES256, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+
+	// the keyset can be stored (encrypted with a KMS master key in production) and imported again
+	handle, err := keyManager.Keyset(kid)
+	require.NoError(t, err)
+	storedKeyset := &keyset.MemReaderWriter{}
+	require.NoError(t, handle.Write(storedKeyset, &testAEAD{}))
+	readHandle, err := keyset.Read(storedKeyset, &testAEAD{})
+	require.NoError(t, err)
+
+	otherKeyManager := NewTinkKeyManager()
+	importedKid, err := otherKeyManager.ImportKeyset(readHandle)
+	require.NoError(t, err)
+	assert.Equal(t, kid, importedKid)
+
+	_, err = keyManager.Create(AlgorithmES256, jwkUtils.JWKeyEncType)
+	assert.ErrorIs(t, err, ErrUnsupportedKeyOperation)
+	_, err = keyManager.Create(AlgorithmPS256, jwkUtils.JWKeySignType)
+	assert.ErrorIs(t, err, ErrUnsupportedAlgorithm)
+	_, err = keyManager.DeriveSharedSecret(kid, nil)
+	assert.ErrorIs(t, err, ErrUnsupportedKeyOperation)
+
+	newKid, err := keyManager.Rotate(kid)
+	require.NoError(t, err)
+	assert.NotEqual(t, kid, newKid)
+
+	require.NoError(t, keyManager.Destroy(kid))
+	_, err = keyManager.Sign(kid, AlgorithmES256, []byte("input"))
+	assert.ErrorIs(t, err, ErrKeyManagerKeyNotFound)
+}
+
+// testAEAD is a master key which does not encrypt, only for the tests.
+type testAEAD struct{}
+
+func (testAEAD) Encrypt(plaintext, _ []byte) ([]byte, error) { return plaintext, nil }
+
+func (testAEAD) Decrypt(ciphertext, _ []byte) ([]byte, error) { return ciphertext, nil }
@@ -0,0 +1,112 @@
+package joseUtils
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrReplayDetected = errors.New("the token has already been used")
+
+// ReplayCache remembers the identifiers of the tokens already received (e.g.: the "jti" of a DPoP proof
+// or of a request object) until they expire, so a token cannot be used twice.
+type ReplayCache interface {
+	// CheckAndStore returns ErrReplayDetected if the ID was already stored and has not expired,
+	// else it stores the ID until expiresAt. An ID which is already expired is not stored.
+	CheckAndStore(id string, expiresAt time.Time) error
+}
+
+// GetReplayExpiration returns until when the ID of a token has to be remembered:
+// the "exp" claim (if not zero) or else the "iat" claim plus the given window (e.g.: the max age of a DPoP proof).
+func GetReplayExpiration(expiration, issuedAt int64, window time.Duration) time.Time {
+	if expiration > 0 {
+		return time.Unix(expiration, 0)
+	}
+	return time.Unix(issuedAt, 0).Add(window)
+}
+
+// InMemoryReplayCache is a ReplayCache for a single instance of a service.
+// The expired entries are removed in background every eviction interval until Close is called.
+type InMemoryReplayCache struct {
+	mutex   sync.Mutex
+	entries map[string]time.Time
+	now     func() time.Time
+	stop    chan struct{}
+	closed  sync.Once
+}
+
+// NewInMemoryReplayCache creates the cache and starts the background eviction (if the interval is greater than zero).
+func NewInMemoryReplayCache(evictionInterval time.Duration) *InMemoryReplayCache {
+	cache := &InMemoryReplayCache{
+		entries: map[string]time.Time{},
+		now:     time.Now,
+		stop:    make(chan struct{}),
+	}
+
+	if evictionInterval > 0 {
+		go cache.evictPeriodically(evictionInterval)
+	}
+
+	return cache
+}
+
+// CheckAndStore returns ErrReplayDetected if the ID was already stored and has not expired,
+// else it stores the ID until expiresAt.
+func (cache *InMemoryReplayCache) CheckAndStore(id string, expiresAt time.Time) error {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	now := cache.now()
+	if storedExpiration, found := cache.entries[id]; found && now.Before(storedExpiration) {
+		return ErrReplayDetected
+	}
+
+	if now.Before(expiresAt) {
+		cache.entries[id] = expiresAt
+	} else {
+		delete(cache.entries, id)
+	}
+	return nil
+}
+
+// Len returns the number of entries (including the expired ones not evicted yet).
+func (cache *InMemoryReplayCache) Len() int {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	return len(cache.entries)
+}
+
+// EvictExpired removes the expired entries.
+func (cache *InMemoryReplayCache) EvictExpired() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	now := cache.now()
+	for id, expiresAt := range cache.entries {
+		if !now.Before(expiresAt) {
+			delete(cache.entries, id)
+		}
+	}
+}
+
+// Close stops the background eviction.
+func (cache *InMemoryReplayCache) Close() {
+	cache.closed.Do(func() {
+		close(cache.stop)
+	})
+}
+
+func (cache *InMemoryReplayCache) evictPeriodically(evictionInterval time.Duration) {
+	ticker := time.NewTicker(evictionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			cache.EvictExpired()
+		case <-cache.stop:
+			return
+		}
+	}
+}
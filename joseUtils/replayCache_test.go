@@ -0,0 +1,51 @@
+package joseUtils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryReplayCache_CheckAndStore(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	cache := NewInMemoryReplayCache(0)
+	cache.now = func() time.Time { return now }
+
+	require.NoError(t, cache.CheckAndStore("jti-1", now.Add(time.Minute)))
+	assert.ErrorIs(t, cache.CheckAndStore("jti-1", now.Add(time.Minute)), ErrReplayDetected)
+	assert.NoError(t, cache.CheckAndStore("jti-2", now.Add(time.Minute)))
+
+	// an expired ID is not stored
+	assert.NoError(t, cache.CheckAndStore("jti-3", now.Add(-time.Second)))
+	assert.NoError(t, cache.CheckAndStore("jti-3", now.Add(-time.Second)))
+
+	// the entry can be reused after its expiration
+	now = now.Add(time.Minute)
+	assert.NoError(t, cache.CheckAndStore("jti-1", now.Add(time.Minute)))
+	assert.ErrorIs(t, cache.CheckAndStore("jti-1", now.Add(time.Minute)), ErrReplayDetected)
+
+	assert.Equal(t, 2, cache.Len())
+	cache.EvictExpired()
+	assert.Equal(t, 1, cache.Len())
+}
+
+func TestInMemoryReplayCache_BackgroundEviction(t *testing.T) {
+	cache := NewInMemoryReplayCache(5 * time.Millisecond)
+	defer cache.Close()
+
+	require.NoError(t, cache.CheckAndStore("jti-1", time.Now().Add(20*time.Millisecond)))
+	require.NoError(t, cache.CheckAndStore("jti-2", time.Now().Add(time.Hour)))
+
+	assert.Eventually(t, func() bool { return cache.Len() == 1 }, time.Second, 5*time.Millisecond)
+	assert.ErrorIs(t, cache.CheckAndStore("jti-2", time.Now().Add(time.Hour)), ErrReplayDetected)
+
+	cache.Close()
+	cache.Close() // it can be called twice
+}
+
+func TestGetReplayExpiration(t *testing.T) {
+	assert.Equal(t, time.Unix(1700000600, 0), GetReplayExpiration(1700000600, 1700000000, time.Minute))
+	assert.Equal(t, time.Unix(1700000060, 0), GetReplayExpiration(0, 1700000000, time.Minute))
+}
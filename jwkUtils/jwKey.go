@@ -1,11 +1,10 @@
 package jwkUtils
 
 import (
+	"crypto"
 	"encoding/base64"
-	"encoding/json"
 	"errors"
 
-	"github.com/Universal-Health-Chain/common-utils-golang/contentUtils"
 	"github.com/lestrrat-go/jwx/jwk"
 )
 
@@ -20,7 +19,19 @@ var JWAlgorithmToJWKCrvAndHashType = map[string]map[string]string{
 	"ES512": {jwk.ECDSACrvKey: "P-521", "hash": "SHA512", jwk.KeyTypeKey: "EC", jwk.KeyUsageKey: JWKeySignType}, // P-521 is not a typo (not P-512)
 }
 
+// JWAlgorithmRSAToHashType has the RSASSA-PKCS1-v1_5 ("RS") and RSASSA-PSS ("PS") algorithms (RFC 7518, sections 3.3 and 3.5).
+var JWAlgorithmRSAToHashType = map[string]map[string]string{
+	"RS256": {"hash": "SHA256", jwk.KeyTypeKey: "RSA", jwk.KeyUsageKey: JWKeySignType},
+	"RS384": {"hash": "SHA384", jwk.KeyTypeKey: "RSA", jwk.KeyUsageKey: JWKeySignType},
+	"RS512": {"hash": "SHA512", jwk.KeyTypeKey: "RSA", jwk.KeyUsageKey: JWKeySignType},
+	"PS256": {"hash": "SHA256", jwk.KeyTypeKey: "RSA", jwk.KeyUsageKey: JWKeySignType},
+	"PS384": {"hash": "SHA384", jwk.KeyTypeKey: "RSA", jwk.KeyUsageKey: JWKeySignType},
+	"PS512": {"hash": "SHA512", jwk.KeyTypeKey: "RSA", jwk.KeyUsageKey: JWKeySignType},
+}
+
 // BaseThumbprintJWK is to calculate the Thumbprint of a public key.
+//
+// Deprecated: the thumbprint only uses the required members of the key type, see GetThumbprintMembers.
 type BaseThumbprintJWK struct {
 	Alg  string  `json:"alg,omitempty"` // for Crystals-Dilithium and Crystals-Kyber
 	Crv  *string `json:"crv,omitempty"` // for non-PQC Elliptic Curve keys
@@ -41,28 +52,50 @@ type PublicJWK struct {
 	X    string  `json:"x,omitempty" bson:"x,omitempty"`       // for public Dilithium, Kyber and Elliptic Curve keys
 	Xs   *string `json:"xs,omitempty" bson:"xs,omitempty"`     // for Dilithium: shake256 of the public key (not the JWK) encoded in raw base64url [RFC4648]
 	Y    *string `json:"y,omitempty" bson:"y,omitempty"`       // for public Elliptic Curve keys
+	N    *string `json:"n,omitempty" bson:"n,omitempty"`       // for RSA keys: the modulus
+	E    *string `json:"e,omitempty" bson:"e,omitempty"`       // for RSA keys: the public exponent
 	Use  *string `json:"use,omitempty" bson:"use,omitempty"`   // 'enc' or 'sig'
+
+	// Key operations (RFC 7517, section 4.3): should not be used together with "use", else they must be consistent.
+	KeyOps []string `json:"key_ops,omitempty" bson:"key_ops,omitempty"` // e.g.: "sign", "verify", "encrypt", "decrypt", "wrapKey", "unwrapKey", "deriveKey", "deriveBits"
+
+	// X.509 members (RFC 7517, sections 4.6 to 4.9): when used, the bare key members must match the first certificate.
+	X5c     []string `json:"x5c,omitempty" bson:"x5c,omitempty"`           // certificate chain: base64 (not base64url) DER certificates, the first one contains the key
+	X5tS256 *string  `json:"x5t#S256,omitempty" bson:"x5t#S256,omitempty"` // base64url SHA-256 thumbprint of the DER encoding of the first certificate
+	X5u     *string  `json:"x5u,omitempty" bson:"x5u,omitempty"`           // URI of the certificate chain (PEM encoded)
 }
 
-// All possible properties including the private key "d" (RSA not supported for now)
+// All possible properties including the private key "d" of EC, OKP (Ed25519 and X25519), RSA and PQK keys
 type JWK struct {
 	Alg  string  `json:"alg,omitempty" bson:"alg,omitempty"`   // for Crystals-Dilithium and Crystals-Kyber
 	Crv  *string `json:"crv,omitempty" bson:"crv,omitempty"`   // for non-PQC Elliptic Curve keys
 	H    *string `json:"h,omitempty" bson:"h,omitempty"`       // Crystals-Kyber SHA3-256 of public key bytes: H(pk)
 	Kid  string  `json:"kid,omitempty" bson:"kid,omitempty"`   // the JWK Thumbprint id the keyID (kid) as per RFC
-	Kty  string  `json:"kty,omitempty" bson:"kty,omitempty"`   // "EC", "PQK"
+	Kty  string  `json:"kty,omitempty" bson:"kty,omitempty"`   // "EC", "OKP", "RSA", "PQK"
 	Pset *string `json:"pset,omitempty" bson:"pset,omitempty"` // for Crystals-Dilithium
-	X    string  `json:"x,omitempty" bson:"x,omitempty"`       // for public Dilithium, Kyber and Elliptic Curve keys
+	X    string  `json:"x,omitempty" bson:"x,omitempty"`       // for public Dilithium, Kyber, Elliptic Curve and OKP (Ed25519, X25519) keys
 	Xs   *string `json:"xs,omitempty" bson:"xs,omitempty"`     // for Dilithium: shake256 of the public key (not the JWK) encoded in raw base64url [RFC4648]
 	Y    *string `json:"y,omitempty" bson:"y,omitempty"`       // for public Elliptic Curve keys
 	Use  *string `json:"use,omitempty" bson:"use,omitempty"`   // 'enc' or 'sig'
-	D    *string `json:"d,omitempty" bson:"d,omitempty"`       // for Crystals-Dilithium, Crystals-Kyber and Elliptic Curve keys
+	D    *string `json:"d,omitempty" bson:"d,omitempty"`       // for Crystals-Dilithium, Crystals-Kyber, Elliptic Curve, OKP and RSA (private exponent) keys
 	Ds   *string `json:"ds,omitempty" bson:"ds,omitempty"`     // for Dilithium: shake256 of the private key (not the JWK) encoded in raw base64url [RFC4648]
-	// N *string `json:"n,omitempty" bson:"n,omitempty"`       // for RSA keys
-	// E *string `json:"e,omitempty" bson:"e,omitempty"`       // for RSA keys
-	K *string `json:"k,omitempty" bson:"k,omitempty"` // for Symmetric Keys
+	N    *string `json:"n,omitempty" bson:"n,omitempty"`       // for RSA keys: the modulus
+	E    *string `json:"e,omitempty" bson:"e,omitempty"`       // for RSA keys: the public exponent
+	P    *string `json:"p,omitempty" bson:"p,omitempty"`       // for private RSA keys: the first prime factor
+	Q    *string `json:"q,omitempty" bson:"q,omitempty"`       // for private RSA keys: the second prime factor
+	DP   *string `json:"dp,omitempty" bson:"dp,omitempty"`     // for private RSA keys: the first factor CRT exponent
+	DQ   *string `json:"dq,omitempty" bson:"dq,omitempty"`     // for private RSA keys: the second factor CRT exponent
+	QI   *string `json:"qi,omitempty" bson:"qi,omitempty"`     // for private RSA keys: the first CRT coefficient
+	K    *string `json:"k,omitempty" bson:"k,omitempty"`       // for Symmetric Keys
 	// T *string `json:"t,omitempty" bson:"t,omitempty"`  	// use X for public Kyber keys too
-	// TODO: add X509
+
+	// Key operations (RFC 7517, section 4.3): should not be used together with "use", else they must be consistent.
+	KeyOps []string `json:"key_ops,omitempty" bson:"key_ops,omitempty"` // e.g.: "sign", "verify", "encrypt", "decrypt", "wrapKey", "unwrapKey", "deriveKey", "deriveBits"
+
+	// X.509 members (RFC 7517, sections 4.6 to 4.9): when used, the bare key members must match the first certificate.
+	X5c     []string `json:"x5c,omitempty" bson:"x5c,omitempty"`           // certificate chain: base64 (not base64url) DER certificates, the first one contains the key
+	X5tS256 *string  `json:"x5t#S256,omitempty" bson:"x5t#S256,omitempty"` // base64url SHA-256 thumbprint of the DER encoding of the first certificate
+	X5u     *string  `json:"x5u,omitempty" bson:"x5u,omitempty"`           // URI of the certificate chain (PEM encoded)
 }
 
 func SetPrivateKeyBytes(publicJWK *JWK, privateKeyBytesASN1 *[]byte) (privateJWK *JWK) {
@@ -94,35 +127,38 @@ func GetPublicJWK(jwk *JWK) (publicJWK *JWK) {
 	publicJWK.Pset = jwk.Pset
 	publicJWK.X = jwk.X
 	publicJWK.Xs = jwk.Xs
+	publicJWK.Y = jwk.Y
+	publicJWK.N = jwk.N
+	publicJWK.E = jwk.E
 	publicJWK.Use = jwk.Use
+	publicJWK.KeyOps = jwk.KeyOps
+	publicJWK.X5c = jwk.X5c
+	publicJWK.X5tS256 = jwk.X5tS256
+	publicJWK.X5u = jwk.X5u
 	return publicJWK
 }
 
-// ExportPublicJWK copies the private key and removes the private data.
+// ExportPublicJWK copies the private key and removes the private data (including the RSA private members and the symmetric "k").
 func ExportPublicJWK(jwk *JWK) (publicJWK JWK) {
 	publicJWK = *jwk
 	publicJWK.D = nil
 	publicJWK.Ds = nil
+	publicJWK.P = nil
+	publicJWK.Q = nil
+	publicJWK.DP = nil
+	publicJWK.DQ = nil
+	publicJWK.QI = nil
+	publicJWK.K = nil
 	return publicJWK
 }
 
-// CalculateThumbprintJWK returns the SHA-256 hash Base64Url encoded or empty string ("") if error.
+// CalculateThumbprintJWK returns the RFC 7638 SHA-256 thumbprint Base64Url encoded or empty string ("") if error.
 func CalculateThumbprintJWK(jwk *JWK) string {
-	// TODO: check if the Dilithium or ECDSA required fields are present
-	jwkBaseThumbprint := BaseThumbprintJWK{
-		Alg:  jwk.Alg,
-		Crv:  jwk.Crv,
-		Kty:  jwk.Kty,
-		Pset: jwk.Pset,
-		X:    jwk.X,
-		Y:    jwk.Y,
-	}
-	jwkBytes, err := json.Marshal(&jwkBaseThumbprint)
+	thumbprint, err := CalculateThumbprint(jwk, crypto.SHA256)
 	if err != nil {
 		return ""
-	} else {
-		return contentUtils.CalculateSHA256(&jwkBytes)
 	}
+	return thumbprint
 }
 
 /* JWK Thumbprint is The digest value for a JWK.
@@ -0,0 +1,321 @@
+package jwkUtils
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"math/big"
+)
+
+const (
+	KeyTypeEC  = "EC"
+	KeyTypeOKP = "OKP"
+	KeyTypePQK = "PQK"
+	KeyTypeRSA = "RSA"
+	KeyTypeOct = "oct"
+
+	CurveP256    = "P-256"
+	CurveP384    = "P-384"
+	CurveP521    = "P-521"
+	CurveEd25519 = "Ed25519"
+	CurveX25519  = "X25519"
+)
+
+var (
+	ErrMissingKeyMaterial = errors.New("the JWK does not contain the required key material")
+	ErrInvalidKeyMaterial = errors.New("the JWK contains invalid key material")
+)
+
+// GetEllipticCurve returns the NIST curve for the "crv" value of an EC JWK or nil if not supported.
+func GetEllipticCurve(crv string) elliptic.Curve {
+	switch crv {
+	case CurveP256:
+		return elliptic.P256()
+	case CurveP384:
+		return elliptic.P384()
+	case CurveP521:
+		return elliptic.P521()
+	default:
+		return nil
+	}
+}
+
+// GetECDSAPublicKey returns the ECDSA public key of an EC JWK ("kty", "crv", "x" and "y" are required).
+func GetECDSAPublicKey(jwk *JWK) (*ecdsa.PublicKey, error) {
+	if jwk == nil || jwk.Kty != KeyTypeEC || jwk.Crv == nil {
+		return nil, ErrUnsupportedKey
+	}
+
+	curve := GetEllipticCurve(*jwk.Crv)
+	if curve == nil {
+		return nil, ErrUnsupportedKey
+	}
+
+	if jwk.X == "" || jwk.Y == nil {
+		return nil, ErrMissingKeyMaterial
+	}
+
+	xBytes, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil {
+		return nil, ErrInvalidKeyMaterial
+	}
+
+	yBytes, err := base64.RawURLEncoding.DecodeString(*jwk.Y)
+	if err != nil {
+		return nil, ErrInvalidKeyMaterial
+	}
+
+	publicKey := &ecdsa.PublicKey{
+		Curve: curve,
+		X:     new(big.Int).SetBytes(xBytes),
+		Y:     new(big.Int).SetBytes(yBytes),
+	}
+
+	if !curve.IsOnCurve(publicKey.X, publicKey.Y) {
+		return nil, ErrInvalidKeyMaterial
+	}
+
+	return publicKey, nil
+}
+
+// GetECDSAPrivateKey returns the ECDSA private key of an EC JWK (the public members and "d" are required).
+func GetECDSAPrivateKey(jwk *JWK) (*ecdsa.PrivateKey, error) {
+	publicKey, err := GetECDSAPublicKey(jwk)
+	if err != nil {
+		return nil, err
+	}
+
+	if jwk.D == nil {
+		return nil, ErrMissingKeyMaterial
+	}
+
+	dBytes, err := base64.RawURLEncoding.DecodeString(*jwk.D)
+	if err != nil {
+		return nil, ErrInvalidKeyMaterial
+	}
+
+	return &ecdsa.PrivateKey{
+		PublicKey: *publicKey,
+		D:         new(big.Int).SetBytes(dBytes),
+	}, nil
+}
+
+// GetEd25519PublicKey returns the Ed25519 public key of an OKP JWK ("kty", "crv" and "x" are required).
+func GetEd25519PublicKey(jwk *JWK) (ed25519.PublicKey, error) {
+	if jwk == nil || jwk.Kty != KeyTypeOKP || jwk.Crv == nil || *jwk.Crv != CurveEd25519 {
+		return nil, ErrUnsupportedKey
+	}
+
+	xBytes, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil || len(xBytes) != ed25519.PublicKeySize {
+		return nil, ErrInvalidKeyMaterial
+	}
+
+	return ed25519.PublicKey(xBytes), nil
+}
+
+// GetEd25519PrivateKey returns the Ed25519 private key of an OKP JWK, where "d" is the 32 bytes seed (RFC 8037).
+func GetEd25519PrivateKey(jwk *JWK) (ed25519.PrivateKey, error) {
+	publicKey, err := GetEd25519PublicKey(jwk)
+	if err != nil {
+		return nil, err
+	}
+
+	if jwk.D == nil {
+		return nil, ErrMissingKeyMaterial
+	}
+
+	seed, err := base64.RawURLEncoding.DecodeString(*jwk.D)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, ErrInvalidKeyMaterial
+	}
+
+	privateKey := ed25519.NewKeyFromSeed(seed)
+	if !publicKey.Equal(privateKey.Public()) {
+		return nil, ErrInvalidKeyMaterial
+	}
+
+	return privateKey, nil
+}
+
+// GetECDHPublicKey returns the public key for key agreement of an EC (P-256, P-384, P-521) or OKP (X25519) JWK.
+func GetECDHPublicKey(jwk *JWK) (*ecdh.PublicKey, error) {
+	if jwk == nil || jwk.Crv == nil {
+		return nil, ErrUnsupportedKey
+	}
+
+	if jwk.Kty == KeyTypeOKP && *jwk.Crv == CurveX25519 {
+		xBytes, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, ErrInvalidKeyMaterial
+		}
+
+		publicKey, err := ecdh.X25519().NewPublicKey(xBytes)
+		if err != nil {
+			return nil, ErrInvalidKeyMaterial
+		}
+		return publicKey, nil
+	}
+
+	ecdsaPublicKey, err := GetECDSAPublicKey(jwk)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := ecdsaPublicKey.ECDH()
+	if err != nil {
+		return nil, ErrInvalidKeyMaterial
+	}
+	return publicKey, nil
+}
+
+// GetECDHPrivateKey returns the private key for key agreement of an EC (P-256, P-384, P-521) or OKP (X25519) JWK.
+func GetECDHPrivateKey(jwk *JWK) (*ecdh.PrivateKey, error) {
+	publicKey, err := GetECDHPublicKey(jwk)
+	if err != nil {
+		return nil, err
+	}
+
+	if jwk.D == nil {
+		return nil, ErrMissingKeyMaterial
+	}
+
+	dBytes, err := base64.RawURLEncoding.DecodeString(*jwk.D)
+	if err != nil {
+		return nil, ErrInvalidKeyMaterial
+	}
+
+	privateKey, err := publicKey.Curve().NewPrivateKey(dBytes)
+	if err != nil || !privateKey.PublicKey().Equal(publicKey) {
+		return nil, ErrInvalidKeyMaterial
+	}
+
+	return privateKey, nil
+}
+
+// GetPublicJWKFromECDH returns the public JWK of a key agreement public key (e.g.: for the "epk" JWE header).
+func GetPublicJWKFromECDH(publicKey *ecdh.PublicKey) (*JWK, error) {
+	if publicKey == nil {
+		return nil, ErrUnsupportedKey
+	}
+
+	var crv string
+	switch publicKey.Curve() {
+	case ecdh.X25519():
+		crv = CurveX25519
+		return &JWK{
+			Crv: &crv,
+			Kty: KeyTypeOKP,
+			X:   base64.RawURLEncoding.EncodeToString(publicKey.Bytes()),
+		}, nil
+	case ecdh.P256():
+		crv = CurveP256
+	case ecdh.P384():
+		crv = CurveP384
+	case ecdh.P521():
+		crv = CurveP521
+	default:
+		return nil, ErrUnsupportedKey
+	}
+
+	// uncompressed point: 0x04 || X || Y
+	pointBytes := publicKey.Bytes()
+	coordinateSize := (len(pointBytes) - 1) / 2
+	y := base64.RawURLEncoding.EncodeToString(pointBytes[1+coordinateSize:])
+	return &JWK{
+		Crv: &crv,
+		Kty: KeyTypeEC,
+		X:   base64.RawURLEncoding.EncodeToString(pointBytes[1 : 1+coordinateSize]),
+		Y:   &y,
+	}, nil
+}
+
+// MinimumRSAKeySize is the minimum size in bits of the RSA modulus (RFC 7518, section 3.3).
+const MinimumRSAKeySize = 2048
+
+// GetRSAPublicKey returns the RSA public key of an RSA JWK ("kty", "n" and "e" are required).
+// Moduli smaller than MinimumRSAKeySize are rejected.
+func GetRSAPublicKey(jwk *JWK) (*rsa.PublicKey, error) {
+	if jwk == nil || jwk.Kty != KeyTypeRSA {
+		return nil, ErrUnsupportedKey
+	}
+
+	if jwk.N == nil || jwk.E == nil {
+		return nil, ErrMissingKeyMaterial
+	}
+
+	n, err := decodeBigInt(*jwk.N)
+	if err != nil || n.BitLen() < MinimumRSAKeySize {
+		return nil, ErrInvalidKeyMaterial
+	}
+
+	e, err := decodeBigInt(*jwk.E)
+	if err != nil || !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 || e.Bit(0) == 0 {
+		return nil, ErrInvalidKeyMaterial
+	}
+
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+// GetRSAPrivateKey returns the RSA private key of an RSA JWK (the public members, "d", "p" and "q" are required).
+// The CRT values ("dp", "dq" and "qi") are recomputed and, if they exist, they must be the same.
+func GetRSAPrivateKey(jwk *JWK) (*rsa.PrivateKey, error) {
+	publicKey, err := GetRSAPublicKey(jwk)
+	if err != nil {
+		return nil, err
+	}
+
+	if jwk.D == nil || jwk.P == nil || jwk.Q == nil {
+		return nil, ErrMissingKeyMaterial
+	}
+
+	members := []*big.Int{nil, nil, nil}
+	for i, value := range []string{*jwk.D, *jwk.P, *jwk.Q} {
+		if members[i], err = decodeBigInt(value); err != nil {
+			return nil, ErrInvalidKeyMaterial
+		}
+	}
+
+	privateKey := &rsa.PrivateKey{PublicKey: *publicKey, D: members[0], Primes: members[1:]}
+	if err = privateKey.Validate(); err != nil {
+		return nil, ErrInvalidKeyMaterial
+	}
+	privateKey.Precompute()
+
+	precomputedValues := []*big.Int{privateKey.Precomputed.Dp, privateKey.Precomputed.Dq, privateKey.Precomputed.Qinv}
+	for i, value := range []*string{jwk.DP, jwk.DQ, jwk.QI} {
+		if value == nil {
+			continue
+		}
+
+		if decodedValue, err := decodeBigInt(*value); err != nil || decodedValue.Cmp(precomputedValues[i]) != 0 {
+			return nil, ErrInvalidKeyMaterial
+		}
+	}
+
+	return privateKey, nil
+}
+
+// decodeBigInt decodes a Base64urlUInt value (RFC 7518, section 2).
+func decodeBigInt(value string) (*big.Int, error) {
+	valueBytes, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(valueBytes) == 0 {
+		return nil, ErrInvalidKeyMaterial
+	}
+
+	return new(big.Int).SetBytes(valueBytes), nil
+}
+
+// encodeBigInt encodes a Base64urlUInt value (RFC 7518, section 2).
+func encodeBigInt(value *big.Int) *string {
+	encodedValue := base64.RawURLEncoding.EncodeToString(value.Bytes())
+	return &encodedValue
+}
@@ -0,0 +1,81 @@
+package jwkUtils
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRSAJWK(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	privateJWK, err := FromCryptoKey(rsaKey)
+	require.NoError(t, err)
+	assert.Equal(t, KeyTypeRSA, privateJWK.Kty)
+	for _, member := range []*string{privateJWK.N, privateJWK.E, privateJWK.D, privateJWK.P, privateJWK.Q, privateJWK.DP, privateJWK.DQ, privateJWK.QI} {
+		require.NotNil(t, member)
+	}
+	assert.Equal(t, "AQAB", *privateJWK.E)
+
+	privateKey, err := GetRSAPrivateKey(privateJWK)
+	require.NoError(t, err)
+	assert.True(t, rsaKey.Equal(privateKey))
+
+	// the public JWKs do not have the private members
+	publicJWK := ExportPublicJWK(privateJWK)
+	publicJWKBytes, err := json.Marshal(publicJWK)
+	require.NoError(t, err)
+	var members map[string]interface{}
+	require.NoError(t, json.Unmarshal(publicJWKBytes, &members))
+	assert.ElementsMatch(t, []string{"kid", "kty", "n", "e"}, mapKeys(members))
+	assert.Equal(t, publicJWK, *GetPublicJWK(privateJWK))
+
+	publicKey, err := GetRSAPublicKey(&publicJWK)
+	require.NoError(t, err)
+	assert.True(t, rsaKey.PublicKey.Equal(publicKey))
+	_, err = GetRSAPrivateKey(&publicJWK)
+	assert.ErrorIs(t, err, ErrMissingKeyMaterial)
+
+	// the CRT values must be the ones of the primes
+	wrongJWK := *privateJWK
+	wrongJWK.DP = privateJWK.DQ
+	_, err = GetRSAPrivateKey(&wrongJWK)
+	assert.ErrorIs(t, err, ErrInvalidKeyMaterial)
+
+	// the modulus must have at least 2048 bits
+	smallKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	smallJWK, err := FromCryptoKey(&smallKey.PublicKey)
+	require.NoError(t, err)
+	_, err = GetRSAPublicKey(smallJWK)
+	assert.ErrorIs(t, err, ErrInvalidKeyMaterial)
+}
+
+func TestOKPJWK(t *testing.T) {
+	for _, alg := range []string{AlgorithmEd25519, AlgorithmX25519} {
+		t.Run(alg, func(t *testing.T) {
+			privateJWK, err := GenerateJWK(alg, "")
+			require.NoError(t, err)
+			assert.Equal(t, KeyTypeOKP, privateJWK.Kty)
+			assert.Equal(t, alg, *privateJWK.Crv)
+
+			publicJWK := GetPublicJWK(privateJWK)
+			assert.Nil(t, publicJWK.D)
+			assert.Equal(t, privateJWK.X, publicJWK.X)
+			assert.Equal(t, privateJWK.Kid, CalculateThumbprintJWK(publicJWK))
+		})
+	}
+}
+
+func mapKeys(values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,314 @@
+package jwkUtils
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"math/big"
+
+	"github.com/cloudflare/circl/kem"
+	"github.com/cloudflare/circl/sign"
+	"github.com/lestrrat-go/jwx/jwk"
+)
+
+// Algorithms to generate OKP keys in addition to the JWA ones ("ES256", "ES384", "ES512" and "EdDSA").
+const (
+	AlgorithmEdDSA   = "EdDSA"
+	AlgorithmEd25519 = CurveEd25519
+	AlgorithmX25519  = CurveX25519
+)
+
+// RSAKeySize is the size in bits of the generated RSA keys.
+const RSAKeySize = 2048
+
+var ErrInvalidKeyUse = errors.New("the key use is not valid for the algorithm")
+
+// GenerateJWK returns a new private JWK with the "kid" set to its SHA-256 thumbprint (RFC 7638):
+//   - "ES256", "ES384" and "ES512": EC P-256, P-384 and P-521 keys, without "alg" if the use is "enc" (ECDH-ES).
+//   - "RS256", "RS384", "RS512", "PS256", "PS384" and "PS512": RSA signature keys of RSAKeySize bits.
+//   - "EdDSA" or "Ed25519": OKP Ed25519 signature keys.
+//   - "X25519": OKP X25519 key agreement keys (ECDH-ES).
+//   - "CRYDI2", "CRYDI3", "CRYDI5", "ML-DSA-44", "ML-DSA-65" and "ML-DSA-87": PQK signature keys.
+//   - "ML-KEM-768", "ML-KEM-1024" and "X25519MLKEM768": PQK key encapsulation keys.
+//
+// The use ("sig" or "enc") is optional: by default it is "enc" for the key agreement and encapsulation algorithms and "sig" for the rest.
+func GenerateJWK(alg string, use string) (*JWK, error) {
+	keyUse, err := getGenerationKeyUse(alg, use)
+	if err != nil {
+		return nil, err
+	}
+
+	var privateJWK *JWK
+	switch {
+	case JWAlgorithmToJWKCrvAndHashType[alg] != nil && keyUse == JWKeySignType:
+		privateJWK, err = generateECDSAJWK(GetEllipticCurve(JWAlgorithmToJWKCrvAndHashType[alg][jwk.ECDSACrvKey]))
+	case JWAlgorithmToJWKCrvAndHashType[alg] != nil:
+		privateJWK, err = generateECDHJWK(getECDHCurve(JWAlgorithmToJWKCrvAndHashType[alg][jwk.ECDSACrvKey]))
+	case JWAlgorithmRSAToHashType[alg] != nil:
+		privateJWK, err = generateRSAJWK(alg)
+	case alg == AlgorithmEdDSA || alg == AlgorithmEd25519:
+		privateJWK, err = generateEd25519JWK()
+	case alg == AlgorithmX25519:
+		privateJWK, err = generateECDHJWK(ecdh.X25519())
+	case GetPQSignatureScheme(alg) != nil:
+		privateJWK, err = GeneratePQSignatureJWK(alg)
+	default:
+		privateJWK, err = GeneratePQKemJWK(alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	privateJWK.Use = &keyUse
+	privateJWK.Kid = CalculateThumbprintJWK(privateJWK)
+	return privateJWK, nil
+}
+
+// getGenerationKeyUse returns the key use for the algorithm or an error if the requested one is not valid for it.
+func getGenerationKeyUse(alg, use string) (string, error) {
+	var keyUses []string
+	switch {
+	case JWAlgorithmToJWKCrvAndHashType[alg] != nil:
+		keyUses = []string{JWKeySignType, JWKeyEncType}
+	case JWAlgorithmRSAToHashType[alg] != nil, alg == AlgorithmEdDSA, alg == AlgorithmEd25519, GetPQSignatureScheme(alg) != nil:
+		keyUses = []string{JWKeySignType}
+	case alg == AlgorithmX25519 || GetPQKemScheme(alg) != nil:
+		keyUses = []string{JWKeyEncType}
+	default:
+		return "", ErrUnsupportedKey
+	}
+
+	if use == "" {
+		return keyUses[0], nil
+	}
+
+	for _, keyUse := range keyUses {
+		if use == keyUse {
+			return use, nil
+		}
+	}
+
+	return "", ErrInvalidKeyUse
+}
+
+func getECDHCurve(crv string) ecdh.Curve {
+	switch crv {
+	case CurveP256:
+		return ecdh.P256()
+	case CurveP384:
+		return ecdh.P384()
+	default:
+		return ecdh.P521()
+	}
+}
+
+func generateECDSAJWK(curve elliptic.Curve) (*JWK, error) {
+	privateKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return FromCryptoKey(privateKey)
+}
+
+func generateRSAJWK(alg string) (*JWK, error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, RSAKeySize)
+	if err != nil {
+		return nil, err
+	}
+
+	privateJWK, err := FromCryptoKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	privateJWK.Alg = alg
+	return privateJWK, nil
+}
+
+func generateEd25519JWK() (*JWK, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return FromCryptoKey(privateKey)
+}
+
+func generateECDHJWK(curve ecdh.Curve) (*JWK, error) {
+	privateKey, err := curve.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return FromCryptoKey(privateKey)
+}
+
+// FromCryptoKey returns the JWK of a Go crypto key with the "kid" set to its SHA-256 thumbprint (RFC 7638):
+//   - *ecdsa.PublicKey and *ecdsa.PrivateKey: EC keys with "alg" ES256, ES384 or ES512.
+//   - *rsa.PublicKey and *rsa.PrivateKey: RSA keys without "alg" (it can be RS256, PS256, etc.), only with two primes.
+//   - ed25519.PublicKey and ed25519.PrivateKey: OKP Ed25519 keys with "alg" EdDSA.
+//   - *ecdh.PublicKey and *ecdh.PrivateKey: EC or OKP X25519 key agreement keys.
+//   - sign.PublicKey and sign.PrivateKey (circl): Dilithium / ML-DSA keys.
+//   - kem.PublicKey (circl): ML-KEM / X25519MLKEM768 public keys (the private keys do not have the seed for "d").
+//
+// The private keys result in private JWKs (with "d").
+func FromCryptoKey(key crypto.PublicKey) (*JWK, error) {
+	var result *JWK
+	var err error
+	switch cryptoKey := key.(type) {
+	case *ecdsa.PrivateKey:
+		result, err = FromCryptoKey(&cryptoKey.PublicKey)
+		if err == nil {
+			d := base64.RawURLEncoding.EncodeToString(cryptoKey.D.FillBytes(make([]byte, (cryptoKey.Curve.Params().BitSize+7)/8)))
+			result.D = &d
+		}
+	case *ecdsa.PublicKey:
+		result, err = getPublicJWKFromECDSA(cryptoKey)
+	case *rsa.PrivateKey:
+		if len(cryptoKey.Primes) != 2 {
+			return nil, ErrUnsupportedKey
+		}
+		result, err = FromCryptoKey(&cryptoKey.PublicKey)
+		if err == nil {
+			cryptoKey.Precompute()
+			result.D = encodeBigInt(cryptoKey.D)
+			result.P = encodeBigInt(cryptoKey.Primes[0])
+			result.Q = encodeBigInt(cryptoKey.Primes[1])
+			result.DP = encodeBigInt(cryptoKey.Precomputed.Dp)
+			result.DQ = encodeBigInt(cryptoKey.Precomputed.Dq)
+			result.QI = encodeBigInt(cryptoKey.Precomputed.Qinv)
+		}
+	case *rsa.PublicKey:
+		result = &JWK{Kty: KeyTypeRSA, N: encodeBigInt(cryptoKey.N), E: encodeBigInt(big.NewInt(int64(cryptoKey.E)))}
+	case ed25519.PrivateKey:
+		if len(cryptoKey) != ed25519.PrivateKeySize {
+			return nil, ErrInvalidKeyMaterial
+		}
+		result, err = FromCryptoKey(cryptoKey.Public())
+		if err == nil {
+			d := base64.RawURLEncoding.EncodeToString(cryptoKey.Seed())
+			result.D = &d
+		}
+	case ed25519.PublicKey:
+		if len(cryptoKey) != ed25519.PublicKeySize {
+			return nil, ErrInvalidKeyMaterial
+		}
+		crv := CurveEd25519
+		result = &JWK{Alg: AlgorithmEdDSA, Crv: &crv, Kty: KeyTypeOKP, X: base64.RawURLEncoding.EncodeToString(cryptoKey)}
+	case *ecdh.PrivateKey:
+		result, err = GetPublicJWKFromECDH(cryptoKey.PublicKey())
+		if err == nil {
+			d := base64.RawURLEncoding.EncodeToString(cryptoKey.Bytes())
+			result.D = &d
+		}
+	case *ecdh.PublicKey:
+		result, err = GetPublicJWKFromECDH(cryptoKey)
+	case sign.PrivateKey:
+		publicKey, _ := cryptoKey.Public().(sign.PublicKey)
+		alg := getPQSignatureAlgorithm(cryptoKey.Scheme())
+		if publicKey == nil || alg == "" {
+			return nil, ErrUnsupportedKey
+		}
+		result, err = createPQSignatureJWK(alg, publicKey, cryptoKey)
+	case sign.PublicKey:
+		alg := getPQSignatureAlgorithm(cryptoKey.Scheme())
+		if alg == "" {
+			return nil, ErrUnsupportedKey
+		}
+		result, err = createPQSignaturePublicJWK(alg, cryptoKey)
+	case kem.PublicKey:
+		alg := getPQKemAlgorithm(cryptoKey.Scheme())
+		if alg == "" {
+			return nil, ErrUnsupportedKey
+		}
+		result, err = createPQKemPublicJWK(alg, cryptoKey)
+	default:
+		return nil, ErrUnsupportedKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result.Kid = CalculateThumbprintJWK(result)
+	return result, nil
+}
+
+func getPublicJWKFromECDSA(publicKey *ecdsa.PublicKey) (*JWK, error) {
+	ecdhPublicKey, err := publicKey.ECDH()
+	if err != nil {
+		return nil, ErrUnsupportedKey
+	}
+
+	result, err := GetPublicJWKFromECDH(ecdhPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	for alg, parameters := range JWAlgorithmToJWKCrvAndHashType {
+		if parameters[jwk.ECDSACrvKey] == *result.Crv {
+			result.Alg = alg
+		}
+	}
+	return result, nil
+}
+
+// PublicKey returns the Go crypto public key of the JWK:
+// *ecdsa.PublicKey (EC), *rsa.PublicKey (RSA), ed25519.PublicKey (OKP Ed25519), *ecdh.PublicKey (OKP X25519),
+// sign.PublicKey (PQK Dilithium / ML-DSA) or kem.PublicKey (PQK ML-KEM / X25519MLKEM768).
+func (jwk *JWK) PublicKey() (crypto.PublicKey, error) {
+	var publicKey crypto.PublicKey
+	var err error
+	switch {
+	case jwk.Kty == KeyTypeEC:
+		publicKey, err = GetECDSAPublicKey(jwk)
+	case jwk.Kty == KeyTypeRSA:
+		publicKey, err = GetRSAPublicKey(jwk)
+	case jwk.Kty == KeyTypeOKP && jwk.Crv != nil && *jwk.Crv == CurveEd25519:
+		publicKey, err = GetEd25519PublicKey(jwk)
+	case jwk.Kty == KeyTypeOKP:
+		publicKey, err = GetECDHPublicKey(jwk)
+	case jwk.Kty == KeyTypePQK && GetPQSignatureScheme(jwk.Alg) != nil:
+		publicKey, err = GetPQSignaturePublicKey(jwk)
+	case jwk.Kty == KeyTypePQK:
+		publicKey, err = GetPQKemPublicKey(jwk)
+	default:
+		err = ErrUnsupportedKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return publicKey, nil
+}
+
+// PrivateKey returns the Go crypto signer of a private signature JWK:
+// *ecdsa.PrivateKey (EC), *rsa.PrivateKey (RSA), ed25519.PrivateKey (OKP Ed25519) or sign.PrivateKey (PQK Dilithium / ML-DSA).
+// Key agreement and encapsulation keys are not signers, see GetECDHPrivateKey and GetPQKemPrivateKey.
+func (jwk *JWK) PrivateKey() (crypto.Signer, error) {
+	var privateKey crypto.Signer
+	var err error
+	switch jwk.Kty {
+	case KeyTypeEC:
+		privateKey, err = GetECDSAPrivateKey(jwk)
+	case KeyTypeRSA:
+		privateKey, err = GetRSAPrivateKey(jwk)
+	case KeyTypeOKP:
+		privateKey, err = GetEd25519PrivateKey(jwk)
+	case KeyTypePQK:
+		privateKey, err = GetPQSignaturePrivateKey(jwk)
+	default:
+		err = ErrUnsupportedKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return privateKey, nil
+}
@@ -0,0 +1,156 @@
+package jwkUtils
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/cloudflare/circl/kem"
+	"github.com/cloudflare/circl/sign"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateJWK(t *testing.T) {
+	testCases := []struct {
+		alg         string
+		use         string
+		expectedUse string
+		expectedKty string
+	}{
+		{"ES256", "", JWKeySignType, KeyTypeEC},
+		{"ES384", JWKeySignType, JWKeySignType, KeyTypeEC},
+		{"ES512", JWKeyEncType, JWKeyEncType, KeyTypeEC},
+		{"RS256", "", JWKeySignType, KeyTypeRSA},
+		{"PS384", JWKeySignType, JWKeySignType, KeyTypeRSA},
+		{AlgorithmEdDSA, "", JWKeySignType, KeyTypeOKP},
+		{AlgorithmEd25519, JWKeySignType, JWKeySignType, KeyTypeOKP},
+		{AlgorithmX25519, "", JWKeyEncType, KeyTypeOKP},
+		{AlgorithmMLDSA65, "", JWKeySignType, KeyTypePQK},
+		{AlgorithmCRYDI3, "", JWKeySignType, KeyTypePQK},
+		{AlgorithmMLKEM768, JWKeyEncType, JWKeyEncType, KeyTypePQK},
+		{AlgorithmX25519MLKEM768, "", JWKeyEncType, KeyTypePQK},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.alg+" "+testCase.use, func(t *testing.T) {
+			privateJWK, err := GenerateJWK(testCase.alg, testCase.use)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedKty, privateJWK.Kty)
+			require.NotNil(t, privateJWK.Use)
+			assert.Equal(t, testCase.expectedUse, *privateJWK.Use)
+			require.NotNil(t, privateJWK.D)
+
+			publicJWK := ExportPublicJWK(privateJWK)
+			assert.Equal(t, CalculateThumbprintJWK(&publicJWK), privateJWK.Kid)
+
+			publicKey, err := privateJWK.PublicKey()
+			require.NoError(t, err)
+
+			convertedJWK, err := FromCryptoKey(publicKey)
+			require.NoError(t, err)
+			assert.Equal(t, privateJWK.Kid, convertedJWK.Kid)
+			assert.Nil(t, convertedJWK.D)
+
+			if testCase.expectedUse == JWKeyEncType {
+				return
+			}
+
+			signer, err := privateJWK.PrivateKey()
+			require.NoError(t, err)
+			assertSignerVerifies(t, signer, publicKey)
+		})
+	}
+}
+
+func assertSignerVerifies(t *testing.T, signer crypto.Signer, publicKey crypto.PublicKey) {
+	message := []byte("message")
+	digest := sha256.Sum256(message)
+
+	switch verifier := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		signature, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+		require.NoError(t, err)
+		assert.True(t, ecdsa.VerifyASN1(verifier, digest[:], signature))
+	case *rsa.PublicKey:
+		signature, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+		require.NoError(t, err)
+		assert.NoError(t, rsa.VerifyPKCS1v15(verifier, crypto.SHA256, digest[:], signature))
+	case ed25519.PublicKey:
+		signature, err := signer.Sign(rand.Reader, message, crypto.Hash(0))
+		require.NoError(t, err)
+		assert.True(t, ed25519.Verify(verifier, message, signature))
+	case sign.PublicKey:
+		signature, err := signer.Sign(rand.Reader, message, crypto.Hash(0))
+		require.NoError(t, err)
+		assert.True(t, verifier.Scheme().Verify(verifier, message, signature, nil))
+	default:
+		t.Fatalf("unexpected public key type %T", publicKey)
+	}
+}
+
+func TestGenerateJWK_Errors(t *testing.T) {
+	_, err := GenerateJWK("HS256", "")
+	assert.ErrorIs(t, err, ErrUnsupportedKey)
+
+	_, err = GenerateJWK(AlgorithmEdDSA, JWKeyEncType)
+	assert.ErrorIs(t, err, ErrInvalidKeyUse)
+
+	_, err = GenerateJWK(AlgorithmMLKEM1024, JWKeySignType)
+	assert.ErrorIs(t, err, ErrInvalidKeyUse)
+
+	_, err = GenerateJWK("RS256", JWKeyEncType)
+	assert.ErrorIs(t, err, ErrInvalidKeyUse)
+
+	_, err = GenerateJWK("ES256", "wrap")
+	assert.ErrorIs(t, err, ErrInvalidKeyUse)
+}
+
+func TestFromCryptoKey(t *testing.T) {
+	ecdsaKey, err := ecdsa.GenerateKey(GetEllipticCurve(CurveP384), rand.Reader)
+	require.NoError(t, err)
+	privateJWK, err := FromCryptoKey(ecdsaKey)
+	require.NoError(t, err)
+	assert.Equal(t, "ES384", privateJWK.Alg)
+	convertedKey, err := GetECDSAPrivateKey(privateJWK)
+	require.NoError(t, err)
+	assert.True(t, ecdsaKey.Equal(convertedKey))
+
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	privateJWK, err = FromCryptoKey(ed25519Key)
+	require.NoError(t, err)
+	assert.Equal(t, AlgorithmEdDSA, privateJWK.Alg)
+	signer, err := privateJWK.PrivateKey()
+	require.NoError(t, err)
+	assert.True(t, ed25519Key.Equal(signer))
+
+	x25519Key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	privateJWK, err = FromCryptoKey(x25519Key)
+	require.NoError(t, err)
+	assert.Equal(t, CurveX25519, *privateJWK.Crv)
+	convertedX25519Key, err := GetECDHPrivateKey(privateJWK)
+	require.NoError(t, err)
+	assert.True(t, x25519Key.Equal(convertedX25519Key))
+	_, err = privateJWK.PrivateKey()
+	assert.ErrorIs(t, err, ErrUnsupportedKey)
+
+	kemJWK, err := GeneratePQKemJWK(AlgorithmMLKEM768)
+	require.NoError(t, err)
+	kemPublicKey, err := kemJWK.PublicKey()
+	require.NoError(t, err)
+	assert.Implements(t, (*kem.PublicKey)(nil), kemPublicKey)
+	publicJWK, err := FromCryptoKey(kemPublicKey)
+	require.NoError(t, err)
+	assert.Equal(t, kemJWK.X, publicJWK.X)
+	assert.Equal(t, *kemJWK.H, *publicJWK.H)
+
+	_, err = FromCryptoKey("not a key")
+	assert.ErrorIs(t, err, ErrUnsupportedKey)
+}
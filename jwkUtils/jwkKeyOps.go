@@ -0,0 +1,101 @@
+package jwkUtils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Key operations ("key_ops") as per RFC 7517, section 4.3.
+const (
+	KeyOperationSign       = "sign"
+	KeyOperationVerify     = "verify"
+	KeyOperationEncrypt    = "encrypt"
+	KeyOperationDecrypt    = "decrypt"
+	KeyOperationWrapKey    = "wrapKey"
+	KeyOperationUnwrapKey  = "unwrapKey"
+	KeyOperationDeriveKey  = "deriveKey"
+	KeyOperationDeriveBits = "deriveBits"
+)
+
+var (
+	ErrUnknownKeyOperation    = errors.New("unknown key operation")
+	ErrKeyUseMismatch         = errors.New("the key use (use) does not allow the operation")
+	ErrKeyOperationNotAllowed = errors.New("the key operations (key_ops) do not allow the operation")
+	ErrIncompatibleAlgorithm  = errors.New("the key cannot be used with the algorithm")
+)
+
+// keyOperationUses has the public key use ("sig" or "enc") which corresponds to every key operation.
+var keyOperationUses = map[string]string{
+	KeyOperationSign:       JWKeySignType,
+	KeyOperationVerify:     JWKeySignType,
+	KeyOperationEncrypt:    JWKeyEncType,
+	KeyOperationDecrypt:    JWKeyEncType,
+	KeyOperationWrapKey:    JWKeyEncType,
+	KeyOperationUnwrapKey:  JWKeyEncType,
+	KeyOperationDeriveKey:  JWKeyEncType,
+	KeyOperationDeriveBits: JWKeyEncType,
+}
+
+// CheckKeyOperation returns nil if the JWK can be used for the operation (e.g.: "verify"):
+// its "use" (if any) cannot contradict the operation (e.g.: an "enc" key cannot verify)
+// and the operation must be in its "key_ops" (if any).
+func (jwk *JWK) CheckKeyOperation(operation string) error {
+	use, found := keyOperationUses[operation]
+	if !found {
+		return fmt.Errorf("%w: %q", ErrUnknownKeyOperation, operation)
+	}
+
+	if jwk.Use != nil && *jwk.Use != use {
+		return fmt.Errorf("%w: %q key for %q", ErrKeyUseMismatch, *jwk.Use, operation)
+	}
+
+	if len(jwk.KeyOps) > 0 && !containsString(jwk.KeyOps, operation) {
+		return fmt.Errorf("%w: %q", ErrKeyOperationNotAllowed, operation)
+	}
+
+	return nil
+}
+
+// IsAlgorithmCompatible returns true if the JWK can be used with the JWS or JWE "alg".
+// If the JWK has "alg" it must be the same (or the KEM one for "+A256KW"),
+// else the key type and curve must be the ones required by the algorithm.
+func (jwk *JWK) IsAlgorithmCompatible(alg string) bool {
+	if jwk.Alg != "" {
+		return jwk.Alg == alg || (jwk.Kty == KeyTypePQK && jwk.Alg+"+A256KW" == alg)
+	}
+
+	crv := ""
+	if jwk.Crv != nil {
+		crv = *jwk.Crv
+	}
+
+	if algorithmData, found := JWAlgorithmToJWKCrvAndHashType[alg]; found {
+		return jwk.Kty == KeyTypeEC && crv == algorithmData["crv"]
+	}
+	if _, found := JWAlgorithmRSAToHashType[alg]; found {
+		return jwk.Kty == KeyTypeRSA
+	}
+
+	switch {
+	case alg == AlgorithmEdDSA || alg == AlgorithmEd25519:
+		return jwk.Kty == KeyTypeOKP && crv == CurveEd25519
+	case strings.HasPrefix(alg, "ECDH-ES") || strings.HasPrefix(alg, "ECDH-1PU"):
+		return jwk.Kty == KeyTypeEC || (jwk.Kty == KeyTypeOKP && crv == CurveX25519)
+	case strings.HasPrefix(alg, "RSA-OAEP"):
+		return jwk.Kty == KeyTypeRSA
+	case alg == "dir" || alg == "A128KW" || alg == "A192KW" || alg == "A256KW":
+		return jwk.Kty == KeyTypeOct
+	default:
+		return false // e.g.: the post-quantum keys always have "alg"
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+package jwkUtils
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/contentUtils"
+	"github.com/cloudflare/circl/kem"
+	"github.com/cloudflare/circl/kem/hybrid"
+	"github.com/cloudflare/circl/kem/mlkem/mlkem1024"
+	"github.com/cloudflare/circl/kem/mlkem/mlkem768"
+)
+
+// Post-quantum key encapsulation algorithms for PQK keys:
+//   - "ML-KEM-768" and "ML-KEM-1024" are the Module-Lattice-Based Key-Encapsulation Mechanism (FIPS 203).
+//   - "X25519MLKEM768" is the hybrid X25519 + ML-KEM-768 KEM (as in TLS), which remains secure if either of them is broken.
+//
+// The "x" member is the encapsulation (public) key, "h" is its SHA3-256 and "d" is the seed to regenerate the private key.
+const (
+	AlgorithmMLKEM768       = "ML-KEM-768"
+	AlgorithmMLKEM1024      = "ML-KEM-1024"
+	AlgorithmX25519MLKEM768 = "X25519MLKEM768"
+)
+
+var pqKemSchemes = map[string]kem.Scheme{
+	AlgorithmMLKEM768:       mlkem768.Scheme(),
+	AlgorithmMLKEM1024:      mlkem1024.Scheme(),
+	AlgorithmX25519MLKEM768: hybrid.X25519MLKEM768(),
+}
+
+// GetPQKemScheme returns the key encapsulation scheme for the "alg" of a PQK JWK or nil if not supported.
+func GetPQKemScheme(alg string) kem.Scheme {
+	return pqKemSchemes[alg]
+}
+
+// GeneratePQKemJWK returns a new private PQK JWK for the given ML-KEM or hybrid algorithm.
+func GeneratePQKemJWK(alg string) (*JWK, error) {
+	scheme := GetPQKemScheme(alg)
+	if scheme == nil {
+		return nil, ErrUnsupportedKey
+	}
+
+	seed := make([]byte, scheme.SeedSize())
+	if _, err := rand.Read(seed); err != nil {
+		return nil, err
+	}
+
+	return NewPQKemJWKFromSeed(alg, seed)
+}
+
+// NewPQKemJWKFromSeed returns the private PQK JWK deterministically derived from the seed
+// (64 bytes for ML-KEM, the "d" and "z" values of the FIPS 203 ML-KEM.KeyGen).
+func NewPQKemJWKFromSeed(alg string, seed []byte) (*JWK, error) {
+	scheme := GetPQKemScheme(alg)
+	if scheme == nil {
+		return nil, ErrUnsupportedKey
+	}
+
+	if len(seed) != scheme.SeedSize() {
+		return nil, ErrInvalidKeyMaterial
+	}
+
+	publicKey, _ := scheme.DeriveKeyPair(seed)
+	result, err := createPQKemPublicJWK(alg, publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	d := base64.RawURLEncoding.EncodeToString(seed)
+	result.D = &d
+	return result, nil
+}
+
+// createPQKemPublicJWK fills "x" with the encapsulation key and "h" with its SHA3-256.
+func createPQKemPublicJWK(alg string, publicKey kem.PublicKey) (*JWK, error) {
+	publicKeyBytes, err := publicKey.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	use := JWKeyEncType
+	h := contentUtils.CalculateSHA3_256(&publicKeyBytes)
+	return &JWK{
+		Alg: alg,
+		H:   &h,
+		Kty: KeyTypePQK,
+		X:   base64.RawURLEncoding.EncodeToString(publicKeyBytes),
+		Use: &use,
+	}, nil
+}
+
+// getPQKemAlgorithm returns the "alg" of the key encapsulation scheme or empty string ("") if not supported.
+func getPQKemAlgorithm(scheme kem.Scheme) string {
+	for alg, supportedScheme := range pqKemSchemes {
+		if supportedScheme.Name() == scheme.Name() {
+			return alg
+		}
+	}
+	return ""
+}
+
+// GetPQKemPublicKey returns the encapsulation key of a ML-KEM or hybrid JWK, checking "h" if it exists.
+func GetPQKemPublicKey(jwk *JWK) (kem.PublicKey, error) {
+	if jwk == nil || jwk.Kty != KeyTypePQK {
+		return nil, ErrUnsupportedKey
+	}
+
+	scheme := GetPQKemScheme(jwk.Alg)
+	if scheme == nil {
+		return nil, ErrUnsupportedKey
+	}
+
+	publicKeyBytes, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil || len(publicKeyBytes) != scheme.PublicKeySize() {
+		return nil, ErrInvalidKeyMaterial
+	}
+
+	if jwk.H != nil && !equalDigest(*jwk.H, contentUtils.CalculateSHA3_256(&publicKeyBytes)) {
+		return nil, ErrInvalidKeyMaterial
+	}
+
+	publicKey, err := scheme.UnmarshalBinaryPublicKey(publicKeyBytes)
+	if err != nil {
+		return nil, ErrInvalidKeyMaterial
+	}
+
+	return publicKey, nil
+}
+
+// GetPQKemPrivateKey returns the decapsulation key regenerated from the seed ("d") of a ML-KEM or hybrid JWK,
+// checking that it belongs to the public key ("x").
+func GetPQKemPrivateKey(jwk *JWK) (kem.PrivateKey, error) {
+	publicKey, err := GetPQKemPublicKey(jwk)
+	if err != nil {
+		return nil, err
+	}
+
+	if jwk.D == nil {
+		return nil, ErrMissingKeyMaterial
+	}
+
+	scheme := publicKey.Scheme()
+	seed, err := base64.RawURLEncoding.DecodeString(*jwk.D)
+	if err != nil || len(seed) != scheme.SeedSize() {
+		return nil, ErrInvalidKeyMaterial
+	}
+
+	derivedPublicKey, privateKey := scheme.DeriveKeyPair(seed)
+	if !derivedPublicKey.Equal(publicKey) {
+		return nil, ErrInvalidKeyMaterial
+	}
+
+	return privateKey, nil
+}
@@ -0,0 +1,97 @@
+package jwkUtils
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Known-answer test vectors of the NIST ACVP ML-KEM keyGen (FIPS 203, vsId 42, first test case of some test groups):
+// https://github.com/usnistgov/ACVP-Server/tree/master/gen-val/json-files/ML-KEM-keyGen-FIPS203
+// The seed is d || z and the expected encapsulation and decapsulation keys are compared by their SHA-256 digest.
+var mlKEMKeyGenVectors = []struct {
+	alg                 string
+	seed                string
+	publicKeyDigestHex  string
+	privateKeyDigestHex string
+}{
+	{
+		alg: AlgorithmMLKEM768, // tgId 2, tcId 26
+		seed: "E34A701C4C87582F42264EE422D3C684D97611F2523EFE0C998AF05056D693DC" +
+			"A85768F3486BD32A01BF9A8F21EA938E648EAE4E5448C34C3EB88820B159EEDD",
+		publicKeyDigestHex:  "7799c9d8eef172aa78c073514f2f039c240de8c5cb61bca82ba0bc46041ce279",
+		privateKeyDigestHex: "104b3444c3de2b81143788d27e17648f45c80f617f906156db2258da96dead40",
+	},
+	{
+		alg: AlgorithmMLKEM1024, // tgId 3, tcId 51
+		seed: "49AC8B99BB1E6A8EA818261F8BE68BDEAA52897E7EC6C40B530BC760AB77DCE3" +
+			"99E3246884181F8E1DD44E0C7629093330221FD67D9B7D6E1510B2DBAD8762F7",
+		publicKeyDigestHex:  "62fccf5fdf805b110670b39cd5e25b1811172961ea4047bfbd589e323ce7cfbc",
+		privateKeyDigestHex: "2f8af73000bd5247a74312ac70386444290bc4b80da6fae05aeb1196dbd8912e",
+	},
+}
+
+func TestNewPQKemJWKFromSeed_KnownAnswerTests(t *testing.T) {
+	for _, vector := range mlKEMKeyGenVectors {
+		t.Run(vector.alg, func(t *testing.T) {
+			seed, err := hex.DecodeString(vector.seed)
+			require.NoError(t, err)
+
+			privateJWK, err := NewPQKemJWKFromSeed(vector.alg, seed)
+			require.NoError(t, err)
+
+			publicKeyBytes, err := base64.RawURLEncoding.DecodeString(privateJWK.X)
+			require.NoError(t, err)
+			publicKeyDigest := sha256.Sum256(publicKeyBytes)
+			assert.Equal(t, vector.publicKeyDigestHex, hex.EncodeToString(publicKeyDigest[:]))
+
+			privateKey, err := GetPQKemPrivateKey(privateJWK)
+			require.NoError(t, err)
+			privateKeyBytes, err := privateKey.MarshalBinary()
+			require.NoError(t, err)
+			privateKeyDigest := sha256.Sum256(privateKeyBytes)
+			assert.Equal(t, vector.privateKeyDigestHex, hex.EncodeToString(privateKeyDigest[:]))
+		})
+	}
+}
+
+func TestGeneratePQKemJWK(t *testing.T) {
+	for _, alg := range []string{AlgorithmMLKEM768, AlgorithmMLKEM1024, AlgorithmX25519MLKEM768} {
+		t.Run(alg, func(t *testing.T) {
+			privateJWK, err := GeneratePQKemJWK(alg)
+			require.NoError(t, err)
+			assert.Equal(t, KeyTypePQK, privateJWK.Kty)
+			assert.Equal(t, JWKeyEncType, *privateJWK.Use)
+			require.NotNil(t, privateJWK.H)
+			require.NotNil(t, privateJWK.D)
+
+			_, err = GetPQKemPrivateKey(privateJWK)
+			assert.NoError(t, err)
+
+			publicJWK := ExportPublicJWK(privateJWK)
+			_, err = GetPQKemPublicKey(&publicJWK)
+			assert.NoError(t, err)
+			_, err = GetPQKemPrivateKey(&publicJWK)
+			assert.ErrorIs(t, err, ErrMissingKeyMaterial)
+
+			// "h" must be the SHA3-256 of "x"
+			otherJWK, err := GeneratePQKemJWK(alg)
+			require.NoError(t, err)
+			publicJWK.H = otherJWK.H
+			_, err = GetPQKemPublicKey(&publicJWK)
+			assert.ErrorIs(t, err, ErrInvalidKeyMaterial)
+
+			// the seed must belong to the public key
+			privateJWK.D = otherJWK.D
+			_, err = GetPQKemPrivateKey(privateJWK)
+			assert.ErrorIs(t, err, ErrInvalidKeyMaterial)
+		})
+	}
+
+	_, err := GeneratePQKemJWK("ML-KEM-512")
+	assert.ErrorIs(t, err, ErrUnsupportedKey)
+}
@@ -0,0 +1,186 @@
+package jwkUtils
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/contentUtils"
+	"github.com/cloudflare/circl/sign"
+	dilithium2 "github.com/cloudflare/circl/sign/dilithium/mode2"
+	dilithium3 "github.com/cloudflare/circl/sign/dilithium/mode3"
+	dilithium5 "github.com/cloudflare/circl/sign/dilithium/mode5"
+	"github.com/cloudflare/circl/sign/mldsa/mldsa44"
+	"github.com/cloudflare/circl/sign/mldsa/mldsa65"
+	"github.com/cloudflare/circl/sign/mldsa/mldsa87"
+)
+
+// Post-quantum signature algorithms for PQK keys:
+//   - "CRYDI2", "CRYDI3" and "CRYDI5" are CRYSTALS-Dilithium (round 3) as per draft-prorock-cose-post-quantum-signatures.
+//   - "ML-DSA-44", "ML-DSA-65" and "ML-DSA-87" are the Module-Lattice-Based Digital Signature Algorithm (FIPS 204).
+const (
+	AlgorithmCRYDI2  = "CRYDI2"
+	AlgorithmCRYDI3  = "CRYDI3"
+	AlgorithmCRYDI5  = "CRYDI5"
+	AlgorithmMLDSA44 = "ML-DSA-44"
+	AlgorithmMLDSA65 = "ML-DSA-65"
+	AlgorithmMLDSA87 = "ML-DSA-87"
+)
+
+var pqSignatureSchemes = map[string]sign.Scheme{
+	AlgorithmCRYDI2:  dilithium2.Scheme(),
+	AlgorithmCRYDI3:  dilithium3.Scheme(),
+	AlgorithmCRYDI5:  dilithium5.Scheme(),
+	AlgorithmMLDSA44: mldsa44.Scheme(),
+	AlgorithmMLDSA65: mldsa65.Scheme(),
+	AlgorithmMLDSA87: mldsa87.Scheme(),
+}
+
+// GetPQSignatureScheme returns the signature scheme for the "alg" of a PQK JWK or nil if not supported.
+func GetPQSignatureScheme(alg string) sign.Scheme {
+	return pqSignatureSchemes[alg]
+}
+
+// GeneratePQSignatureJWK returns a new private PQK JWK for the given Dilithium / ML-DSA algorithm.
+func GeneratePQSignatureJWK(alg string) (*JWK, error) {
+	scheme := GetPQSignatureScheme(alg)
+	if scheme == nil {
+		return nil, ErrUnsupportedKey
+	}
+
+	publicKey, privateKey, err := scheme.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return createPQSignatureJWK(alg, publicKey, privateKey)
+}
+
+// NewPQSignatureJWKFromSeed returns the private PQK JWK deterministically derived from the seed (32 bytes):
+// the "xi" value of the FIPS 204 ML-DSA.KeyGen or the random seed of the Dilithium round 3 crypto_sign_keypair.
+func NewPQSignatureJWKFromSeed(alg string, seed []byte) (*JWK, error) {
+	scheme := GetPQSignatureScheme(alg)
+	if scheme == nil {
+		return nil, ErrUnsupportedKey
+	}
+
+	if len(seed) != scheme.SeedSize() {
+		return nil, ErrInvalidKeyMaterial
+	}
+
+	publicKey, privateKey := scheme.DeriveKey(seed)
+	return createPQSignatureJWK(alg, publicKey, privateKey)
+}
+
+// createPQSignatureJWK fills "x" and "d" with the public and private keys and "xs" and "ds" with their shake256.
+func createPQSignatureJWK(alg string, publicKey sign.PublicKey, privateKey sign.PrivateKey) (*JWK, error) {
+	result, err := createPQSignaturePublicJWK(alg, publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKeyBytes, err := privateKey.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	d := base64.RawURLEncoding.EncodeToString(privateKeyBytes)
+	ds := contentUtils.CalculateShake256(&privateKeyBytes)
+	result.D = &d
+	result.Ds = &ds
+	return result, nil
+}
+
+// createPQSignaturePublicJWK fills "x" with the public key and "xs" with its shake256.
+func createPQSignaturePublicJWK(alg string, publicKey sign.PublicKey) (*JWK, error) {
+	publicKeyBytes, err := publicKey.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	use := JWKeySignType
+	xs := contentUtils.CalculateShake256(&publicKeyBytes)
+	return &JWK{
+		Alg: alg,
+		Kty: KeyTypePQK,
+		X:   base64.RawURLEncoding.EncodeToString(publicKeyBytes),
+		Xs:  &xs,
+		Use: &use,
+	}, nil
+}
+
+// getPQSignatureAlgorithm returns the "alg" of the signature scheme or empty string ("") if not supported.
+func getPQSignatureAlgorithm(scheme sign.Scheme) string {
+	for alg, supportedScheme := range pqSignatureSchemes {
+		if supportedScheme.Name() == scheme.Name() {
+			return alg
+		}
+	}
+	return ""
+}
+
+// GetPQSignaturePublicKey returns the public key of a Dilithium / ML-DSA JWK, checking "xs" if it exists.
+func GetPQSignaturePublicKey(jwk *JWK) (sign.PublicKey, error) {
+	if jwk == nil || jwk.Kty != KeyTypePQK {
+		return nil, ErrUnsupportedKey
+	}
+
+	scheme := GetPQSignatureScheme(jwk.Alg)
+	if scheme == nil {
+		return nil, ErrUnsupportedKey
+	}
+
+	publicKeyBytes, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil || len(publicKeyBytes) != scheme.PublicKeySize() {
+		return nil, ErrInvalidKeyMaterial
+	}
+
+	if jwk.Xs != nil && !equalDigest(*jwk.Xs, contentUtils.CalculateShake256(&publicKeyBytes)) {
+		return nil, ErrInvalidKeyMaterial
+	}
+
+	publicKey, err := scheme.UnmarshalBinaryPublicKey(publicKeyBytes)
+	if err != nil {
+		return nil, ErrInvalidKeyMaterial
+	}
+
+	return publicKey, nil
+}
+
+// GetPQSignaturePrivateKey returns the private key of a Dilithium / ML-DSA JWK, checking "ds" if it exists
+// and that the private key belongs to the public key.
+func GetPQSignaturePrivateKey(jwk *JWK) (sign.PrivateKey, error) {
+	publicKey, err := GetPQSignaturePublicKey(jwk)
+	if err != nil {
+		return nil, err
+	}
+
+	if jwk.D == nil {
+		return nil, ErrMissingKeyMaterial
+	}
+
+	scheme := publicKey.Scheme()
+	privateKeyBytes, err := base64.RawURLEncoding.DecodeString(*jwk.D)
+	if err != nil || len(privateKeyBytes) != scheme.PrivateKeySize() {
+		return nil, ErrInvalidKeyMaterial
+	}
+
+	if jwk.Ds != nil && !equalDigest(*jwk.Ds, contentUtils.CalculateShake256(&privateKeyBytes)) {
+		return nil, ErrInvalidKeyMaterial
+	}
+
+	privateKey, err := scheme.UnmarshalBinaryPrivateKey(privateKeyBytes)
+	if err != nil {
+		return nil, ErrInvalidKeyMaterial
+	}
+
+	derivedPublicKey, ok := privateKey.Public().(sign.PublicKey)
+	if !ok || !derivedPublicKey.Equal(publicKey) {
+		return nil, ErrInvalidKeyMaterial
+	}
+
+	return privateKey, nil
+}
+
+func equalDigest(expected, actual string) bool {
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(actual)) == 1
+}
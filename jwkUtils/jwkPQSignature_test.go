@@ -0,0 +1,198 @@
+package jwkUtils
+
+import (
+	"crypto/aes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Known-answer test vectors of the NIST ACVP ML-DSA keyGen (FIPS 204, vsId 42, first test case of each test group):
+// https://github.com/usnistgov/ACVP-Server/tree/master/gen-val/json-files/ML-DSA-keyGen-FIPS204
+// The expected public and private keys are compared by their SHA-256 digest.
+var mlDSAKeyGenVectors = []struct {
+	alg                 string
+	seed                string
+	publicKeyDigestHex  string
+	privateKeyDigestHex string
+}{
+	{
+		alg:                 AlgorithmMLDSA44, // tgId 1, tcId 1
+		seed:                "93EF2E6EF1FB08999D142ABE0295482370D3F43BDB254A78E2B0D5168ECA065F",
+		publicKeyDigestHex:  "6995b20ecd5cde41719035028a712ccf35b1adf53b913030423d9d6fa188d673",
+		privateKeyDigestHex: "16a35d4b59f932aeada987dc689b075add0df57b4815bb103be7443ee3c1c561",
+	},
+	{
+		alg:                 AlgorithmMLDSA65, // tgId 2, tcId 26
+		seed:                "70CEFB9AED5B68E018B079DA8284B9D5CAD5499ED9C265FF73588005D85C225C",
+		publicKeyDigestHex:  "646b26b8d09dbc9e865b6a006c693a3127b065e62fab5fbe8b159c416462feb6",
+		privateKeyDigestHex: "3894dc56a4553781d68ff0d1b6fcf1b4876085ea602fb6f8738def50ed7d4c75",
+	},
+	{
+		alg:                 AlgorithmMLDSA87, // tgId 3, tcId 51
+		seed:                "38359FBCD79582CFFE609E137EE2EFE8A8DBCBAD18BA92BB433AB4F09B49299D",
+		publicKeyDigestHex:  "ea374a09356e5f89be784f28f4ef938e8976cb5c4db00fbacb257663491748d4",
+		privateKeyDigestHex: "a0cc3d4f703057c09b9261336ba45563d2c781d173f7fc634910698e95eee375",
+	},
+}
+
+func TestNewPQSignatureJWKFromSeed_KnownAnswerTests(t *testing.T) {
+	for _, vector := range mlDSAKeyGenVectors {
+		t.Run(vector.alg, func(t *testing.T) {
+			seed, err := hex.DecodeString(vector.seed)
+			require.NoError(t, err)
+
+			privateJWK, err := NewPQSignatureJWKFromSeed(vector.alg, seed)
+			require.NoError(t, err)
+
+			publicKeyBytes, err := base64.RawURLEncoding.DecodeString(privateJWK.X)
+			require.NoError(t, err)
+			publicKeyDigest := sha256.Sum256(publicKeyBytes)
+			assert.Equal(t, vector.publicKeyDigestHex, hex.EncodeToString(publicKeyDigest[:]))
+
+			privateKeyBytes, err := base64.RawURLEncoding.DecodeString(*privateJWK.D)
+			require.NoError(t, err)
+			privateKeyDigest := sha256.Sum256(privateKeyBytes)
+			assert.Equal(t, vector.privateKeyDigestHex, hex.EncodeToString(privateKeyDigest[:]))
+		})
+	}
+}
+
+// Known-answer tests of the CRYSTALS-Dilithium round 3 submission: the SHA-256 digest of the whole "PQCsignKAT" response
+// file (100 test cases) generated by PQCgenKAT_sign.c of the reference implementation (pq-crystals/dilithium, commit 61b51a7).
+var dilithiumKATFileDigests = []struct {
+	alg           string
+	katName       string
+	fileDigestHex string
+}{
+	{alg: AlgorithmCRYDI2, katName: "Dilithium2", fileDigestHex: "38ed991c5ca11e39ab23945ca37af89e059d16c5474bf8ba96b15cb4e948af2a"},
+	{alg: AlgorithmCRYDI3, katName: "Dilithium3", fileDigestHex: "8196b32212753f525346201ffec1c7a0a852596fa0b57bd4e2746231dab44d55"},
+	{alg: AlgorithmCRYDI5, katName: "Dilithium5", fileDigestHex: "7ded97a6e6c809b43b54c248171d7504fa6a0cab651bf288bb00034782667481"},
+}
+
+// testKATRandom is the AES-256 CTR_DRBG of the NIST PQC "randombytes" used to generate the known-answer tests (rng.c).
+type testKATRandom struct {
+	key [32]byte
+	v   [16]byte
+}
+
+func newTestKATRandom(seed []byte) *testKATRandom {
+	random := &testKATRandom{}
+	random.update(seed)
+	return random
+}
+
+func (random *testKATRandom) incrementV() {
+	for i := len(random.v) - 1; i >= 0; i-- {
+		random.v[i]++
+		if random.v[i] != 0 {
+			return
+		}
+	}
+}
+
+func (random *testKATRandom) update(providedData []byte) {
+	block, _ := aes.NewCipher(random.key[:])
+	buffer := make([]byte, 48)
+	for i := 0; i < 3; i++ {
+		random.incrementV()
+		block.Encrypt(buffer[i*16:(i+1)*16], random.v[:])
+	}
+	for i := range providedData {
+		buffer[i] ^= providedData[i]
+	}
+	copy(random.key[:], buffer[:32])
+	copy(random.v[:], buffer[32:])
+}
+
+func (random *testKATRandom) fill(data []byte) {
+	block, _ := aes.NewCipher(random.key[:])
+	output := make([]byte, 16)
+	for len(data) > 0 {
+		random.incrementV()
+		block.Encrypt(output, random.v[:])
+		data = data[copy(data, output):]
+	}
+	random.update(nil)
+}
+
+func TestNewPQSignatureJWKFromSeed_DilithiumKnownAnswerTests(t *testing.T) {
+	for _, vector := range dilithiumKATFileDigests {
+		t.Run(vector.alg, func(t *testing.T) {
+			scheme := GetPQSignatureScheme(vector.alg)
+			require.NotNil(t, scheme)
+
+			entropy := make([]byte, 48)
+			for i := range entropy {
+				entropy[i] = byte(i)
+			}
+			katRandom := newTestKATRandom(entropy)
+
+			katFile := sha256.New()
+			_, _ = fmt.Fprintf(katFile, "# %s\n\n", vector.katName)
+			for count := 0; count < 100; count++ {
+				testCaseSeed := make([]byte, 48)
+				katRandom.fill(testCaseSeed)
+				message := make([]byte, 33*(count+1))
+				katRandom.fill(message)
+
+				// crypto_sign_keypair gets the key seed from the DRBG initialized with the seed of the test case
+				keySeed := make([]byte, scheme.SeedSize())
+				newTestKATRandom(testCaseSeed).fill(keySeed)
+				privateJWK, err := NewPQSignatureJWKFromSeed(vector.alg, keySeed)
+				require.NoError(t, err)
+
+				publicKeyBytes, err := base64.RawURLEncoding.DecodeString(privateJWK.X)
+				require.NoError(t, err)
+				privateKeyBytes, err := base64.RawURLEncoding.DecodeString(*privateJWK.D)
+				require.NoError(t, err)
+
+				privateKey, err := GetPQSignaturePrivateKey(privateJWK)
+				require.NoError(t, err)
+				signature := scheme.Sign(privateKey, message, nil)
+
+				_, _ = fmt.Fprintf(katFile, "count = %d\nseed = %X\nmlen = %d\nmsg = %X\n", count, testCaseSeed, len(message), message)
+				_, _ = fmt.Fprintf(katFile, "pk = %X\nsk = %X\n", publicKeyBytes, privateKeyBytes)
+				_, _ = fmt.Fprintf(katFile, "smlen = %d\nsm = %X%X\n\n", len(message)+len(signature), signature, message)
+			}
+
+			assert.Equal(t, vector.fileDigestHex, hex.EncodeToString(katFile.Sum(nil)))
+		})
+	}
+}
+
+func TestGeneratePQSignatureJWK(t *testing.T) {
+	for _, alg := range []string{AlgorithmCRYDI3, AlgorithmMLDSA65} {
+		t.Run(alg, func(t *testing.T) {
+			privateJWK, err := GeneratePQSignatureJWK(alg)
+			require.NoError(t, err)
+			assert.Equal(t, KeyTypePQK, privateJWK.Kty)
+			assert.Equal(t, alg, privateJWK.Alg)
+			assert.NotEmpty(t, privateJWK.X)
+			require.NotNil(t, privateJWK.Xs)
+			require.NotNil(t, privateJWK.D)
+			require.NotNil(t, privateJWK.Ds)
+
+			_, err = GetPQSignaturePrivateKey(privateJWK)
+			assert.NoError(t, err)
+
+			publicJWK := ExportPublicJWK(privateJWK)
+			_, err = GetPQSignaturePublicKey(&publicJWK)
+			assert.NoError(t, err)
+
+			// "xs" must be the shake256 of "x"
+			wrongDigest := *privateJWK.Ds
+			publicJWK.Xs = &wrongDigest
+			_, err = GetPQSignaturePublicKey(&publicJWK)
+			assert.ErrorIs(t, err, ErrInvalidKeyMaterial)
+		})
+	}
+
+	_, err := GeneratePQSignatureJWK("CRYDI4")
+	assert.ErrorIs(t, err, ErrUnsupportedKey)
+}
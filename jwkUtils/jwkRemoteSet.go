@@ -0,0 +1,312 @@
+package jwkUtils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRemoteKeySetTTL is the caching time of a JWK Set without "Cache-Control: max-age".
+	DefaultRemoteKeySetTTL = time.Hour
+	// DefaultRemoteKeySetMaxTTL is the maximum caching time of a JWK Set, even if its "max-age" is greater.
+	DefaultRemoteKeySetMaxTTL = 24 * time.Hour
+	// DefaultRemoteKeySetMinRefreshInterval is the minimum time between two requests for the same JWK Set URL.
+	DefaultRemoteKeySetMinRefreshInterval = time.Minute
+	// DefaultRemoteKeySetGracePeriod is how long the keys removed from a JWK Set are still available.
+	DefaultRemoteKeySetGracePeriod = time.Hour
+
+	// maxRemoteKeySetSize is the maximum size in bytes of a JWK Set response.
+	maxRemoteKeySetSize = 1 << 20
+)
+
+var (
+	ErrRemoteKeySetFetch       = errors.New("unable to fetch the remote JWK Set")
+	ErrRemoteKeySetInvalid     = errors.New("the remote JWK Set is not valid")
+	ErrRemoteKeyNotFound       = errors.New("the key is not in the remote JWK Set")
+	ErrRemoteKeySetRateLimited = errors.New("the remote JWK Set was refreshed too recently")
+)
+
+// RemoteKeySetOptions are the optional settings of a RemoteKeySetCache (zero values use the defaults):
+//   - HTTPClient: the client to fetch the JWK Sets, http.DefaultClient by default.
+//   - DefaultTTL: the caching time when the response has no "Cache-Control: max-age".
+//   - MaxTTL: the maximum caching time.
+//   - MinRefreshInterval: the minimum time between two requests for the same URL (e.g.: when the "kid" is unknown).
+//   - GracePeriod: how long the keys removed from a JWK Set during a key rotation are still available.
+//   - Now: the clock, time.Now by default.
+type RemoteKeySetOptions struct {
+	HTTPClient         *http.Client
+	DefaultTTL         time.Duration
+	MaxTTL             time.Duration
+	MinRefreshInterval time.Duration
+	GracePeriod        time.Duration
+	Now                func() time.Time
+}
+
+// RemoteKeySetCache fetches and caches the remote JWK Sets ("jwks_uri" or "jku") by URL.
+// It honours the "Cache-Control" (max-age, no-cache and no-store) and "ETag" response headers,
+// refreshes the set when a "kid" is not found (rate limited by MinRefreshInterval)
+// and keeps the previous keys during the GracePeriod after a key rotation.
+// It is safe for concurrent use.
+type RemoteKeySetCache struct {
+	options RemoteKeySetOptions
+	mutex   sync.Mutex
+	entries map[string]*remoteKeySetEntry
+}
+
+type remoteKeySetEntry struct {
+	mutex        sync.Mutex // serializes the requests for the same URL
+	keys         []JWK
+	previousKeys []previousRemoteKey
+	etag         string
+	expiresAt    time.Time
+	lastFetchAt  time.Time
+}
+
+// previousRemoteKey is a key removed from the JWK Set, available until the end of its own grace period.
+type previousRemoteKey struct {
+	key       JWK
+	expiresAt time.Time
+}
+
+// NewRemoteKeySetCache returns an empty cache with the given options (nil for the defaults).
+func NewRemoteKeySetCache(options *RemoteKeySetOptions) *RemoteKeySetCache {
+	cache := &RemoteKeySetCache{entries: map[string]*remoteKeySetEntry{}}
+	if options != nil {
+		cache.options = *options
+	}
+
+	if cache.options.HTTPClient == nil {
+		cache.options.HTTPClient = http.DefaultClient
+	}
+	if cache.options.DefaultTTL <= 0 {
+		cache.options.DefaultTTL = DefaultRemoteKeySetTTL
+	}
+	if cache.options.MaxTTL <= 0 {
+		cache.options.MaxTTL = DefaultRemoteKeySetMaxTTL
+	}
+	if cache.options.MinRefreshInterval <= 0 {
+		cache.options.MinRefreshInterval = DefaultRemoteKeySetMinRefreshInterval
+	}
+	if cache.options.GracePeriod <= 0 {
+		cache.options.GracePeriod = DefaultRemoteKeySetGracePeriod
+	}
+	if cache.options.Now == nil {
+		cache.options.Now = time.Now
+	}
+
+	return cache
+}
+
+// GetKeySet returns the JWK Set of the URL (including the previous keys in the grace period),
+// fetching it if it is not cached or expired. If the refresh fails but the keys were already cached,
+// the cached ones are returned.
+func (cache *RemoteKeySetCache) GetKeySet(ctx context.Context, url string) (*JWKeySet, error) {
+	entry := cache.getEntry(url)
+	entry.mutex.Lock()
+	defer entry.mutex.Unlock()
+
+	now := cache.options.Now()
+	if entry.lastFetchAt.IsZero() || !now.Before(entry.expiresAt) {
+		if err := cache.refreshEntry(ctx, url, entry, now); err != nil && entry.keys == nil {
+			return nil, err
+		}
+	}
+
+	if entry.keys == nil {
+		// a previous request failed less than MinRefreshInterval ago
+		return nil, ErrRemoteKeySetFetch
+	}
+
+	return entry.keySet(now), nil
+}
+
+// GetKey returns the key of the JWK Set of the URL with the given "kid".
+// If it is not found the set is refreshed (at most once every MinRefreshInterval) and searched again.
+func (cache *RemoteKeySetCache) GetKey(ctx context.Context, url, kid string) (*JWK, error) {
+	keySet, err := cache.GetKeySet(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	if key := keySet.FindByKid(kid); key != nil {
+		return key, nil
+	}
+
+	if err = cache.Refresh(ctx, url); err != nil && !errors.Is(err, ErrRemoteKeySetRateLimited) {
+		return nil, err
+	}
+
+	keySet, err = cache.GetKeySet(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	if key := keySet.FindByKid(kid); key != nil {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrRemoteKeyNotFound, kid)
+}
+
+// Refresh fetches the JWK Set of the URL unless it was fetched less than MinRefreshInterval ago.
+func (cache *RemoteKeySetCache) Refresh(ctx context.Context, url string) error {
+	entry := cache.getEntry(url)
+	entry.mutex.Lock()
+	defer entry.mutex.Unlock()
+
+	now := cache.options.Now()
+	if !entry.lastFetchAt.IsZero() && now.Sub(entry.lastFetchAt) < cache.options.MinRefreshInterval {
+		return ErrRemoteKeySetRateLimited
+	}
+
+	return cache.refreshEntry(ctx, url, entry, now)
+}
+
+func (cache *RemoteKeySetCache) getEntry(url string) *remoteKeySetEntry {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	entry, found := cache.entries[url]
+	if !found {
+		entry = &remoteKeySetEntry{}
+		cache.entries[url] = entry
+	}
+	return entry
+}
+
+// refreshEntry requests the JWK Set (conditionally if there is an "ETag") and updates the entry, which must be locked.
+func (cache *RemoteKeySetCache) refreshEntry(ctx context.Context, url string, entry *remoteKeySetEntry, now time.Time) error {
+	entry.lastFetchAt = now
+	entry.expiresAt = now.Add(cache.options.MinRefreshInterval) // retry after the minimum interval if the request fails
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRemoteKeySetFetch, err)
+	}
+	request.Header.Set("Accept", "application/jwk-set+json, application/json")
+	if entry.etag != "" && entry.keys != nil {
+		request.Header.Set("If-None-Match", entry.etag)
+	}
+
+	response, err := cache.options.HTTPClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRemoteKeySetFetch, err)
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusNotModified:
+		if entry.keys == nil {
+			return fmt.Errorf("%w: unexpected status %d", ErrRemoteKeySetFetch, response.StatusCode)
+		}
+	case http.StatusOK:
+		body, err := io.ReadAll(io.LimitReader(response.Body, maxRemoteKeySetSize+1))
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrRemoteKeySetFetch, err)
+		}
+
+		keys, err := parseRemoteKeySet(body)
+		if err != nil {
+			return err
+		}
+
+		entry.rotateKeys(keys, now, cache.options.GracePeriod)
+		entry.etag = response.Header.Get("ETag")
+	default:
+		return fmt.Errorf("%w: unexpected status %d", ErrRemoteKeySetFetch, response.StatusCode)
+	}
+
+	entry.expiresAt = now.Add(cache.getTTL(response.Header.Get("Cache-Control")))
+	return nil
+}
+
+// getTTL returns the caching time of the "Cache-Control" header: MinRefreshInterval for "no-cache" and "no-store",
+// else "max-age" or DefaultTTL, between MinRefreshInterval and MaxTTL.
+func (cache *RemoteKeySetCache) getTTL(cacheControl string) time.Duration {
+	ttl := cache.options.DefaultTTL
+	for _, directive := range strings.Split(cacheControl, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(strings.ToLower(directive)), "=")
+		switch name {
+		case "no-cache", "no-store":
+			return cache.options.MinRefreshInterval
+		case "max-age":
+			if seconds, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 64); err == nil && seconds >= 0 {
+				ttl = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+
+	if ttl < cache.options.MinRefreshInterval {
+		return cache.options.MinRefreshInterval
+	}
+	if ttl > cache.options.MaxTTL {
+		return cache.options.MaxTTL
+	}
+	return ttl
+}
+
+// rotateKeys replaces the keys and keeps the removed ones (by "kid") until the end of the grace period:
+// the keys removed by a previous rotation keep their own expiry, so they are merged with the new removed ones.
+func (entry *remoteKeySetEntry) rotateKeys(keys []JWK, now time.Time, gracePeriod time.Duration) {
+	newKids := map[string]bool{}
+	for _, key := range keys {
+		newKids[key.Kid] = true
+	}
+
+	var previousKeys []previousRemoteKey
+	for _, previousKey := range entry.previousKeys {
+		if now.Before(previousKey.expiresAt) && !newKids[previousKey.key.Kid] {
+			previousKeys = append(previousKeys, previousKey)
+		}
+	}
+
+	for _, key := range entry.keys {
+		if !newKids[key.Kid] {
+			previousKeys = append(previousKeys, previousRemoteKey{key: key, expiresAt: now.Add(gracePeriod)})
+		}
+	}
+
+	entry.previousKeys = previousKeys
+	entry.keys = keys
+}
+
+// keySet returns a copy of the current keys and of the previous ones if they did not expire.
+func (entry *remoteKeySetEntry) keySet(now time.Time) *JWKeySet {
+	keys := append([]JWK{}, entry.keys...)
+	for _, previousKey := range entry.previousKeys {
+		if now.Before(previousKey.expiresAt) {
+			keys = append(keys, previousKey.key)
+		}
+	}
+	return &JWKeySet{Keys: keys}
+}
+
+// parseRemoteKeySet decodes a JWK Set and returns only the public members of its keys.
+func parseRemoteKeySet(body []byte) ([]JWK, error) {
+	if len(body) > maxRemoteKeySetSize {
+		return nil, fmt.Errorf("%w: too large", ErrRemoteKeySetInvalid)
+	}
+
+	var keySet JWKeySet
+	if err := json.Unmarshal(body, &keySet); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRemoteKeySetInvalid, err)
+	}
+
+	if keySet.Keys == nil {
+		return nil, fmt.Errorf("%w: missing keys", ErrRemoteKeySetInvalid)
+	}
+
+	keys := make([]JWK, 0, len(keySet.Keys))
+	for i := range keySet.Keys {
+		keys = append(keys, ExportPublicJWK(&keySet.Keys[i]))
+	}
+	return keys, nil
+}
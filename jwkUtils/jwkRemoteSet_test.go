@@ -0,0 +1,241 @@
+package jwkUtils
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testJWKSServer serves a JWK Set with an "ETag" and counts the requests.
+type testJWKSServer struct {
+	mutex        sync.Mutex
+	keys         []JWK
+	etag         string
+	cacheControl string
+	status       int
+	requests     int
+}
+
+func (server *testJWKSServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	server.requests++
+
+	if server.status != 0 {
+		w.WriteHeader(server.status)
+		return
+	}
+
+	w.Header().Set("ETag", server.etag)
+	if server.cacheControl != "" {
+		w.Header().Set("Cache-Control", server.cacheControl)
+	}
+	if r.Header.Get("If-None-Match") == server.etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/jwk-set+json")
+	_ = json.NewEncoder(w).Encode(JWKeySet{Keys: server.keys})
+}
+
+func (server *testJWKSServer) setKeys(etag string, keys ...*JWK) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	server.etag = etag
+	server.keys = nil
+	for _, key := range keys {
+		server.keys = append(server.keys, *key) // including the private members, which must be removed by the cache
+	}
+}
+
+func (server *testJWKSServer) setStatus(status int) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	server.status = status
+}
+
+func (server *testJWKSServer) getRequests() int {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	return server.requests
+}
+
+type testClock struct {
+	now time.Time
+}
+
+func (clock *testClock) Now() time.Time {
+	return clock.now
+}
+
+func newTestRemoteKeySetCache(t *testing.T) (*RemoteKeySetCache, *testJWKSServer, *httptest.Server, *testClock) {
+	jwksServer := &testJWKSServer{cacheControl: "public, max-age=600"}
+	httpServer := httptest.NewServer(jwksServer)
+	t.Cleanup(httpServer.Close)
+
+	clock := &testClock{now: time.Now()}
+	cache := NewRemoteKeySetCache(&RemoteKeySetOptions{
+		HTTPClient:         httpServer.Client(),
+		MinRefreshInterval: time.Minute,
+		GracePeriod:        time.Hour,
+		Now:                clock.Now,
+	})
+	return cache, jwksServer, httpServer, clock
+}
+
+func TestRemoteKeySetCache_CacheControlAndETag(t *testing.T) {
+	cache, jwksServer, httpServer, clock := newTestRemoteKeySetCache(t)
+	key, err := GenerateJWK("ES256", JWKeySignType)
+	require.NoError(t, err)
+	jwksServer.setKeys(`"v1"`, key)
+
+	keySet, err := cache.GetKeySet(context.Background(), httpServer.URL)
+	require.NoError(t, err)
+	require.Len(t, keySet.Keys, 1)
+	assert.Equal(t, key.Kid, keySet.Keys[0].Kid)
+	assert.Nil(t, keySet.Keys[0].D, "the private members must be removed")
+
+	// cached during "max-age"
+	clock.now = clock.now.Add(5 * time.Minute)
+	_, err = cache.GetKeySet(context.Background(), httpServer.URL)
+	require.NoError(t, err)
+	assert.Equal(t, 1, jwksServer.getRequests())
+
+	// revalidated with "If-None-Match" after "max-age"
+	clock.now = clock.now.Add(6 * time.Minute)
+	keySet, err = cache.GetKeySet(context.Background(), httpServer.URL)
+	require.NoError(t, err)
+	assert.Equal(t, 2, jwksServer.getRequests())
+	require.Len(t, keySet.Keys, 1)
+	assert.Equal(t, key.Kid, keySet.Keys[0].Kid)
+
+	// the cached keys are returned if the server fails
+	jwksServer.setStatus(http.StatusInternalServerError)
+	clock.now = clock.now.Add(11 * time.Minute)
+	keySet, err = cache.GetKeySet(context.Background(), httpServer.URL)
+	require.NoError(t, err)
+	assert.Len(t, keySet.Keys, 1)
+	assert.Equal(t, 3, jwksServer.getRequests())
+}
+
+func TestRemoteKeySetCache_GetKeyRotation(t *testing.T) {
+	cache, jwksServer, httpServer, clock := newTestRemoteKeySetCache(t)
+	oldKey, err := GenerateJWK("ES256", JWKeySignType)
+	require.NoError(t, err)
+	newKey, err := GenerateJWK("ES256", JWKeySignType)
+	require.NoError(t, err)
+	jwksServer.setKeys(`"v1"`, oldKey)
+
+	key, err := cache.GetKey(context.Background(), httpServer.URL, oldKey.Kid)
+	require.NoError(t, err)
+	assert.Equal(t, oldKey.X, key.X)
+
+	// the issuer rotates its keys: the unknown "kid" is not refreshed before MinRefreshInterval
+	jwksServer.setKeys(`"v2"`, newKey)
+	_, err = cache.GetKey(context.Background(), httpServer.URL, newKey.Kid)
+	assert.ErrorIs(t, err, ErrRemoteKeyNotFound)
+	assert.Equal(t, 1, jwksServer.getRequests())
+
+	clock.now = clock.now.Add(2 * time.Minute)
+	key, err = cache.GetKey(context.Background(), httpServer.URL, newKey.Kid)
+	require.NoError(t, err)
+	assert.Equal(t, newKey.X, key.X)
+	assert.Equal(t, 2, jwksServer.getRequests())
+
+	// the previous key is still available during the grace period
+	key, err = cache.GetKey(context.Background(), httpServer.URL, oldKey.Kid)
+	require.NoError(t, err)
+	assert.Equal(t, oldKey.X, key.X)
+
+	clock.now = clock.now.Add(2 * time.Hour)
+	_, err = cache.GetKey(context.Background(), httpServer.URL, oldKey.Kid)
+	assert.ErrorIs(t, err, ErrRemoteKeyNotFound)
+}
+
+func TestRemoteKeySetCache_GetKeyRotationInGracePeriod(t *testing.T) {
+	cache, jwksServer, httpServer, clock := newTestRemoteKeySetCache(t)
+	firstKey, err := GenerateJWK("ES256", JWKeySignType)
+	require.NoError(t, err)
+	secondKey, err := GenerateJWK("ES256", JWKeySignType)
+	require.NoError(t, err)
+	thirdKey, err := GenerateJWK("ES256", JWKeySignType)
+	require.NoError(t, err)
+
+	jwksServer.setKeys(`"v1"`, firstKey)
+	_, err = cache.GetKeySet(context.Background(), httpServer.URL)
+	require.NoError(t, err)
+
+	// two rotations in the grace period of the first one
+	jwksServer.setKeys(`"v2"`, secondKey)
+	clock.now = clock.now.Add(2 * time.Minute)
+	require.NoError(t, cache.Refresh(context.Background(), httpServer.URL))
+
+	jwksServer.setKeys(`"v3"`, thirdKey)
+	clock.now = clock.now.Add(30 * time.Minute)
+	require.NoError(t, cache.Refresh(context.Background(), httpServer.URL))
+
+	for _, key := range []*JWK{firstKey, secondKey, thirdKey} {
+		_, err = cache.GetKey(context.Background(), httpServer.URL, key.Kid)
+		assert.NoError(t, err)
+	}
+
+	// the first key expires at the end of its own grace period, but not the second one
+	clock.now = clock.now.Add(45 * time.Minute)
+	_, err = cache.GetKey(context.Background(), httpServer.URL, secondKey.Kid)
+	assert.NoError(t, err)
+
+	keySet, err := cache.GetKeySet(context.Background(), httpServer.URL)
+	require.NoError(t, err)
+	assert.Nil(t, keySet.FindByKid(firstKey.Kid))
+}
+
+func TestRemoteKeySetCache_Concurrent(t *testing.T) {
+	cache, jwksServer, httpServer, _ := newTestRemoteKeySetCache(t)
+	key, err := GenerateJWK("ES256", JWKeySignType)
+	require.NoError(t, err)
+	jwksServer.setKeys(`"v1"`, key)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cache.GetKey(context.Background(), httpServer.URL, key.Kid)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, jwksServer.getRequests())
+}
+
+func TestRemoteKeySetCache_Errors(t *testing.T) {
+	cache, jwksServer, httpServer, _ := newTestRemoteKeySetCache(t)
+	jwksServer.setStatus(http.StatusNotFound)
+
+	_, err := cache.GetKeySet(context.Background(), httpServer.URL)
+	assert.ErrorIs(t, err, ErrRemoteKeySetFetch)
+
+	assert.ErrorIs(t, cache.Refresh(context.Background(), httpServer.URL), ErrRemoteKeySetRateLimited)
+
+	_, err = parseRemoteKeySet([]byte(`{"kty":"EC"}`))
+	assert.ErrorIs(t, err, ErrRemoteKeySetInvalid)
+}
+
+func TestRemoteKeySetCache_GetTTL(t *testing.T) {
+	cache := NewRemoteKeySetCache(nil)
+
+	assert.Equal(t, DefaultRemoteKeySetTTL, cache.getTTL(""))
+	assert.Equal(t, 10*time.Minute, cache.getTTL("public, max-age=600"))
+	assert.Equal(t, DefaultRemoteKeySetMinRefreshInterval, cache.getTTL("max-age=1"))
+	assert.Equal(t, DefaultRemoteKeySetMinRefreshInterval, cache.getTTL("no-store"))
+	assert.Equal(t, DefaultRemoteKeySetMaxTTL, cache.getTTL("max-age=31536000"))
+}
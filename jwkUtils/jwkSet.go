@@ -1,6 +1,12 @@
 package jwkUtils
 
-import "strings"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrJWKNotFound = errors.New("no key in the JWK Set matches")
 
 // JSON Web Key (JWK) is a JSON object that represents a cryptographic key.
 // JWK specification to represent the cryptographic keys used for signing and encryption defines
@@ -61,3 +67,134 @@ func CreateJWKeySet(jwKeys *[]JWK) *JWKeySet {
 	// fmt.Printf(`number of keys in the JWKS = %v\n`, len(jwkeySet.Keys))
 	return jwkeySet
 }
+
+// FindByKid returns the key with the exact "kid" or nil.
+func (jwks *JWKeySet) FindByKid(kid string) *JWK {
+	if jwks == nil {
+		return nil
+	}
+
+	for i := range jwks.Keys {
+		if jwks.Keys[i].Kid == kid {
+			return &jwks.Keys[i]
+		}
+	}
+	return nil
+}
+
+// JWKFilter has the exact values to filter the keys of a JWK Set (empty values match any key):
+//   - Kid, Kty and Crv must be the key ones.
+//   - Use: the key "use" must be the same, but a key without "use" matches.
+//   - Alg: the key must be compatible with the algorithm (see JWK.IsAlgorithmCompatible).
+//   - KeyOp: the key must allow the operation (see JWK.CheckKeyOperation).
+type JWKFilter struct {
+	Kid   string
+	Use   string
+	Kty   string
+	Crv   string
+	Alg   string
+	KeyOp string
+}
+
+// Filter returns the keys matching all the filter values (the original keys, not copies).
+func (jwks *JWKeySet) Filter(filter JWKFilter) []*JWK {
+	if jwks == nil {
+		return nil
+	}
+
+	var keys []*JWK
+	for i := range jwks.Keys {
+		if filter.matches(&jwks.Keys[i]) {
+			keys = append(keys, &jwks.Keys[i])
+		}
+	}
+	return keys
+}
+
+func (filter JWKFilter) matches(key *JWK) bool {
+	switch {
+	case filter.Kid != "" && key.Kid != filter.Kid:
+		return false
+	case filter.Kty != "" && key.Kty != filter.Kty:
+		return false
+	case filter.Crv != "" && (key.Crv == nil || *key.Crv != filter.Crv):
+		return false
+	case filter.Use != "" && key.Use != nil && *key.Use != filter.Use:
+		return false
+	case filter.Alg != "" && !key.IsAlgorithmCompatible(filter.Alg):
+		return false
+	case filter.KeyOp != "" && key.CheckKeyOperation(filter.KeyOp) != nil:
+		return false
+	default:
+		return true
+	}
+}
+
+// KeySelector has the JOSE header values to select a key ("alg", "kid" and "x5t#S256")
+// and the operation to do with it (e.g.: "verify" for a JWS or "decrypt" for a JWE).
+// The "jku" header must be checked against the JWK Set URL before, see joseUtils.SelectHeaderJWK.
+type KeySelector struct {
+	Alg       string
+	Kid       string
+	X5tS256   string
+	Operation string
+}
+
+// SelectKey returns the best key for the selector: the "kid" and "x5t#S256" (if any) must be the key ones,
+// the key must be compatible with the "alg" and allow the operation. A key with the same "alg"
+// is preferred to one without it. If the only matching keys cannot be used (e.g.: an "enc" key to verify a JWS)
+// the error explains why, else ErrJWKNotFound is returned.
+func (jwks *JWKeySet) SelectKey(selector KeySelector) (*JWK, error) {
+	if jwks == nil {
+		return nil, ErrJWKNotFound
+	}
+
+	var selectedKey *JWK
+	var keyErr error
+	for i := range jwks.Keys {
+		key := &jwks.Keys[i]
+		if (selector.Kid != "" && key.Kid != selector.Kid) || (selector.X5tS256 != "" && !key.matchesX509Thumbprint(selector.X5tS256)) {
+			continue
+		}
+
+		if selector.Operation != "" {
+			if err := key.CheckKeyOperation(selector.Operation); err != nil {
+				keyErr = err
+				continue
+			}
+		}
+
+		if selector.Alg != "" && !key.IsAlgorithmCompatible(selector.Alg) {
+			keyErr = fmt.Errorf("%w: %q", ErrIncompatibleAlgorithm, selector.Alg)
+			continue
+		}
+
+		if selector.Alg != "" && key.Alg == selector.Alg {
+			return key, nil
+		}
+		if selectedKey == nil {
+			selectedKey = key
+		}
+	}
+
+	if selectedKey != nil {
+		return selectedKey, nil
+	}
+	if keyErr != nil {
+		return nil, keyErr
+	}
+	return nil, ErrJWKNotFound
+}
+
+// matchesX509Thumbprint compares the "x5t#S256" of the key or else the thumbprint of the first certificate of its "x5c".
+func (jwk *JWK) matchesX509Thumbprint(x5tS256 string) bool {
+	if jwk.X5tS256 != nil {
+		return equalDigest(*jwk.X5tS256, x5tS256)
+	}
+
+	certificates, err := ParseX509CertificateChain(jwk.X5c)
+	if err != nil {
+		return false
+	}
+	return equalDigest(CalculateX509ThumbprintSHA256(certificates[0]), x5tS256)
+}
@@ -0,0 +1,126 @@
+package jwkUtils
+
+import (
+	"crypto/x509"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestJWKeySet(t *testing.T) (*JWKeySet, *JWK, *JWK, *JWK) {
+	signKey, err := GenerateJWK("ES256", JWKeySignType)
+	require.NoError(t, err)
+	encKey, err := GenerateJWK("ES256", JWKeyEncType)
+	require.NoError(t, err)
+	rsaKey, err := GenerateJWK("PS256", "")
+	require.NoError(t, err)
+	rsaKey.Alg, rsaKey.Use, rsaKey.KeyOps = "", nil, []string{KeyOperationVerify}
+
+	return CreateJWKeySet(&[]JWK{*signKey, *encKey, *rsaKey}), signKey, encKey, rsaKey
+}
+
+func TestJWK_CheckKeyOperation(t *testing.T) {
+	_, signKey, encKey, rsaKey := newTestJWKeySet(t)
+
+	assert.NoError(t, signKey.CheckKeyOperation(KeyOperationVerify))
+	assert.ErrorIs(t, signKey.CheckKeyOperation(KeyOperationDecrypt), ErrKeyUseMismatch)
+	assert.NoError(t, encKey.CheckKeyOperation(KeyOperationDeriveKey))
+	assert.ErrorIs(t, encKey.CheckKeyOperation(KeyOperationVerify), ErrKeyUseMismatch)
+	assert.NoError(t, rsaKey.CheckKeyOperation(KeyOperationVerify))
+	assert.ErrorIs(t, rsaKey.CheckKeyOperation(KeyOperationEncrypt), ErrKeyOperationNotAllowed)
+	assert.ErrorIs(t, rsaKey.CheckKeyOperation("print"), ErrUnknownKeyOperation)
+}
+
+func TestJWK_IsAlgorithmCompatible(t *testing.T) {
+	_, signKey, encKey, rsaKey := newTestJWKeySet(t)
+
+	assert.True(t, signKey.IsAlgorithmCompatible("ES256"))
+	assert.False(t, signKey.IsAlgorithmCompatible("ES384"))
+	assert.True(t, encKey.IsAlgorithmCompatible("ECDH-ES+A256KW"))
+	assert.False(t, encKey.IsAlgorithmCompatible("ES384"))
+	assert.True(t, rsaKey.IsAlgorithmCompatible("RS256"))
+	assert.True(t, rsaKey.IsAlgorithmCompatible("PS512"))
+	assert.False(t, rsaKey.IsAlgorithmCompatible("ES256"))
+
+	kemKey, err := GenerateJWK(AlgorithmMLKEM768, "")
+	require.NoError(t, err)
+	assert.True(t, kemKey.IsAlgorithmCompatible(AlgorithmMLKEM768))
+	assert.True(t, kemKey.IsAlgorithmCompatible(AlgorithmMLKEM768+"+A256KW"))
+	assert.False(t, kemKey.IsAlgorithmCompatible(AlgorithmMLKEM1024))
+}
+
+func TestJWKeySet_FindByKidAndFilter(t *testing.T) {
+	keySet, signKey, encKey, rsaKey := newTestJWKeySet(t)
+
+	assert.Equal(t, signKey.X, keySet.FindByKid(signKey.Kid).X)
+	assert.Nil(t, keySet.FindByKid("unknown"))
+	assert.Nil(t, (*JWKeySet)(nil).FindByKid(signKey.Kid))
+
+	keys := keySet.Filter(JWKFilter{Use: JWKeySignType, Kty: KeyTypeEC, Crv: CurveP256})
+	require.Len(t, keys, 1)
+	assert.Equal(t, signKey.Kid, keys[0].Kid)
+
+	keys = keySet.Filter(JWKFilter{Use: JWKeySignType}) // the RSA key has no "use"
+	require.Len(t, keys, 2)
+	assert.Equal(t, rsaKey.Kid, keys[1].Kid)
+
+	keys = keySet.Filter(JWKFilter{KeyOp: KeyOperationDeriveKey})
+	require.Len(t, keys, 1)
+	assert.Equal(t, encKey.Kid, keys[0].Kid)
+
+	keys = keySet.Filter(JWKFilter{Alg: "RS256"})
+	require.Len(t, keys, 1)
+	assert.Equal(t, rsaKey.Kid, keys[0].Kid)
+
+	assert.Empty(t, keySet.Filter(JWKFilter{Kty: KeyTypeOKP}))
+}
+
+func TestJWKeySet_SelectKey(t *testing.T) {
+	keySet, signKey, encKey, rsaKey := newTestJWKeySet(t)
+
+	key, err := keySet.SelectKey(KeySelector{Alg: "ES256", Kid: signKey.Kid, Operation: KeyOperationVerify})
+	require.NoError(t, err)
+	assert.Equal(t, signKey.Kid, key.Kid)
+
+	key, err = keySet.SelectKey(KeySelector{Alg: "PS256", Operation: KeyOperationVerify})
+	require.NoError(t, err)
+	assert.Equal(t, rsaKey.Kid, key.Kid)
+
+	// the key with the same "alg" is preferred
+	keySet.Keys = append([]JWK{*rsaKey}, keySet.Keys...)
+	keySet.Keys[len(keySet.Keys)-1].Alg = "PS256"
+	key, err = keySet.SelectKey(KeySelector{Alg: "PS256", Operation: KeyOperationVerify})
+	require.NoError(t, err)
+	assert.Equal(t, "PS256", key.Alg)
+
+	// the "use" of the key contradicts the operation
+	_, err = keySet.SelectKey(KeySelector{Alg: "ES256", Kid: encKey.Kid, Operation: KeyOperationVerify})
+	assert.ErrorIs(t, err, ErrKeyUseMismatch)
+
+	_, err = keySet.SelectKey(KeySelector{Alg: "ES384", Kid: signKey.Kid, Operation: KeyOperationVerify})
+	assert.ErrorIs(t, err, ErrIncompatibleAlgorithm)
+
+	_, err = keySet.SelectKey(KeySelector{Kid: "unknown"})
+	assert.ErrorIs(t, err, ErrJWKNotFound)
+}
+
+func TestJWKeySet_SelectKeyByX509Thumbprint(t *testing.T) {
+	leafKey, err := GenerateJWK("ES256", JWKeySignType)
+	require.NoError(t, err)
+	leafPrivateKey, err := leafKey.PrivateKey()
+	require.NoError(t, err)
+
+	_, leafCertificate := newTestCertificateChain(t, leafPrivateKey)
+	leafKey.X5c = EncodeX509CertificateChain([]*x509.Certificate{leafCertificate})
+	otherKey, err := GenerateJWK("ES256", JWKeySignType)
+	require.NoError(t, err)
+	keySet := CreateJWKeySet(&[]JWK{*otherKey, *leafKey})
+
+	key, err := keySet.SelectKey(KeySelector{Alg: "ES256", X5tS256: CalculateX509ThumbprintSHA256(leafCertificate)})
+	require.NoError(t, err)
+	assert.Equal(t, leafKey.Kid, key.Kid)
+
+	_, err = keySet.SelectKey(KeySelector{X5tS256: "unknown"})
+	assert.ErrorIs(t, err, ErrJWKNotFound)
+}
@@ -0,0 +1,27 @@
+package jwkUtils
+
+import (
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var ErrUnwrappedPrivateKey = errors.New("the JWK has private members (d, ds or k) and it must be wrapped before storing it")
+
+// HasPrivateMembers returns true if the JWK has the private key ("d"), the Dilithium "ds", the RSA private members
+// or the symmetric key ("k").
+func (jwk *JWK) HasPrivateMembers() bool {
+	return jwk.D != nil || jwk.Ds != nil || jwk.K != nil ||
+		jwk.P != nil || jwk.Q != nil || jwk.DP != nil || jwk.DQ != nil || jwk.QI != nil
+}
+
+// MarshalBSON refuses to store a JWK with private members in a database (e.g.: MongoDB):
+// it must be wrapped before (see joseUtils.JWKWrapper) and stored as the wrapped (encrypted) value.
+func (jwk JWK) MarshalBSON() ([]byte, error) {
+	if jwk.HasPrivateMembers() {
+		return nil, ErrUnwrappedPrivateKey
+	}
+
+	type bsonJWK JWK // without the MarshalBSON method
+	return bson.Marshal(bsonJWK(jwk))
+}
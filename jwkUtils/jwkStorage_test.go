@@ -0,0 +1,31 @@
+package jwkUtils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestJWK_MarshalBSON(t *testing.T) {
+	privateJWK, err := GenerateJWK("ES256", JWKeySignType)
+	require.NoError(t, err)
+	assert.True(t, privateJWK.HasPrivateMembers())
+
+	_, err = bson.Marshal(privateJWK)
+	assert.ErrorIs(t, err, ErrUnwrappedPrivateKey)
+
+	_, err = bson.Marshal(struct{ Key JWK }{Key: *privateJWK})
+	assert.ErrorIs(t, err, ErrUnwrappedPrivateKey)
+
+	publicJWK := ExportPublicJWK(privateJWK)
+	assert.False(t, publicJWK.HasPrivateMembers())
+
+	publicJWKBSON, err := bson.Marshal(publicJWK)
+	require.NoError(t, err)
+
+	var decodedJWK JWK
+	require.NoError(t, bson.Unmarshal(publicJWKBSON, &decodedJWK))
+	assert.Equal(t, publicJWK, decodedJWK)
+}
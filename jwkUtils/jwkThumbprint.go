@@ -0,0 +1,103 @@
+package jwkUtils
+
+import (
+	"crypto"
+	_ "crypto/sha256" // registers SHA-256 for crypto.Hash
+	_ "crypto/sha512" // registers SHA-384 and SHA-512 for crypto.Hash
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// ThumbprintURIPrefix is the prefix of a JWK Thumbprint URI (RFC 9278), followed by the hash name, ":" and the thumbprint.
+const ThumbprintURIPrefix = "urn:ietf:params:oauth:jwk-thumbprint:"
+
+var (
+	ErrMissingThumbprintMember   = errors.New("the JWK does not contain a required member for the thumbprint")
+	ErrUnsupportedThumbprintHash = errors.New("unsupported hash for the JWK thumbprint")
+)
+
+// thumbprintHashNames are the names of the hash algorithms in the IANA "Named Information Hash Algorithm" registry.
+var thumbprintHashNames = map[crypto.Hash]string{
+	crypto.SHA256: "sha-256",
+	crypto.SHA384: "sha-384",
+	crypto.SHA512: "sha-512",
+}
+
+// GetThumbprintMembers returns the required members of the JWK to calculate its thumbprint (RFC 7638, section 3.2):
+//   - EC: "crv", "kty", "x" and "y".
+//   - OKP: "crv", "kty" and "x" (RFC 8037).
+//   - RSA: "e", "kty" and "n".
+//   - oct: "k" and "kty".
+//   - PQK: "alg", "kty" and "x", since the "alg" determines the parameter set of the public key ("x").
+func GetThumbprintMembers(jwk *JWK) (map[string]string, error) {
+	if jwk == nil {
+		return nil, ErrUnsupportedKey
+	}
+
+	members := map[string]string{"kty": jwk.Kty}
+	switch jwk.Kty {
+	case KeyTypeEC:
+		if jwk.Crv == nil || jwk.X == "" || jwk.Y == nil {
+			return nil, ErrMissingThumbprintMember
+		}
+		members["crv"], members["x"], members["y"] = *jwk.Crv, jwk.X, *jwk.Y
+	case KeyTypeOKP:
+		if jwk.Crv == nil || jwk.X == "" {
+			return nil, ErrMissingThumbprintMember
+		}
+		members["crv"], members["x"] = *jwk.Crv, jwk.X
+	case KeyTypeRSA:
+		if jwk.E == nil || jwk.N == nil {
+			return nil, ErrMissingThumbprintMember
+		}
+		members["e"], members["n"] = *jwk.E, *jwk.N
+	case KeyTypeOct:
+		if jwk.K == nil {
+			return nil, ErrMissingThumbprintMember
+		}
+		members["k"] = *jwk.K
+	case KeyTypePQK:
+		if jwk.Alg == "" || jwk.X == "" {
+			return nil, ErrMissingThumbprintMember
+		}
+		members["alg"], members["x"] = jwk.Alg, jwk.X
+	default:
+		return nil, ErrUnsupportedKey
+	}
+
+	return members, nil
+}
+
+// CalculateThumbprint returns the RFC 7638 thumbprint Base64Url encoded by using SHA-256, SHA-384 or SHA-512.
+func CalculateThumbprint(jwk *JWK, hash crypto.Hash) (string, error) {
+	if _, supported := thumbprintHashNames[hash]; !supported {
+		return "", ErrUnsupportedThumbprintHash
+	}
+
+	members, err := GetThumbprintMembers(jwk)
+	if err != nil {
+		return "", err
+	}
+
+	// the members of a map are marshaled in lexicographic order and without whitespace
+	membersJSON, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+
+	hashFunc := hash.New()
+	hashFunc.Write(membersJSON)
+	return base64.RawURLEncoding.EncodeToString(hashFunc.Sum(nil)), nil
+}
+
+// CalculateThumbprintURI returns the JWK Thumbprint URI (RFC 9278),
+// e.g.: "urn:ietf:params:oauth:jwk-thumbprint:sha-256:NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs".
+func CalculateThumbprintURI(jwk *JWK, hash crypto.Hash) (string, error) {
+	thumbprint, err := CalculateThumbprint(jwk, hash)
+	if err != nil {
+		return "", err
+	}
+
+	return ThumbprintURIPrefix + thumbprintHashNames[hash] + ":" + thumbprint, nil
+}
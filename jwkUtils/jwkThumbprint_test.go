@@ -0,0 +1,98 @@
+package jwkUtils
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalculateThumbprint_RFC7638(t *testing.T) {
+	// RFC 7638, section 3.1
+	n := "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw"
+	e := "AQAB"
+	rsaJWK := &JWK{Kty: KeyTypeRSA, N: &n, E: &e, Alg: "RS256", Kid: "2011-04-29"}
+
+	thumbprint, err := CalculateThumbprint(rsaJWK, crypto.SHA256)
+	require.NoError(t, err)
+	assert.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", thumbprint)
+	assert.Equal(t, thumbprint, CalculateThumbprintJWK(rsaJWK))
+
+	// RFC 9278, section 3
+	thumbprintURI, err := CalculateThumbprintURI(rsaJWK, crypto.SHA256)
+	require.NoError(t, err)
+	assert.Equal(t, "urn:ietf:params:oauth:jwk-thumbprint:sha-256:NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", thumbprintURI)
+}
+
+func TestCalculateThumbprint_RFC8037(t *testing.T) {
+	// RFC 8037, appendix A.3
+	crv := CurveEd25519
+	okpJWK := &JWK{Kty: KeyTypeOKP, Crv: &crv, X: "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}
+
+	thumbprint, err := CalculateThumbprint(okpJWK, crypto.SHA256)
+	require.NoError(t, err)
+	assert.Equal(t, "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k", thumbprint)
+
+	// RFC 9278, section 3
+	thumbprintURI, err := CalculateThumbprintURI(okpJWK, crypto.SHA256)
+	require.NoError(t, err)
+	assert.Equal(t, "urn:ietf:params:oauth:jwk-thumbprint:sha-256:kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k", thumbprintURI)
+}
+
+func TestCalculateThumbprint_RequiredMembersOnly(t *testing.T) {
+	crv := CurveP256
+	y := "x_FEzRu9m36HLN_tue659LNpXW6pCyStikYjKIWI5a0"
+	d := "jpsQnnGQmL-YBIffH1136cspYG6-0iY7X1fCE9-E9LI"
+	use := JWKeySignType
+	ecJWK := &JWK{Alg: "ES256", Crv: &crv, Kid: "key-1", Kty: KeyTypeEC, X: "f83OJ3D2xF1Bg8vub9tLe1gHMzV76e8Tus9uPHvRVEU", Y: &y, Use: &use, D: &d}
+
+	membersJSON := `{"crv":"P-256","kty":"EC","x":"f83OJ3D2xF1Bg8vub9tLe1gHMzV76e8Tus9uPHvRVEU","y":"x_FEzRu9m36HLN_tue659LNpXW6pCyStikYjKIWI5a0"}`
+	digest := sha256.Sum256([]byte(membersJSON))
+
+	thumbprint, err := CalculateThumbprint(ecJWK, crypto.SHA256)
+	require.NoError(t, err)
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString(digest[:]), thumbprint)
+
+	publicJWK := &JWK{Crv: &crv, Kty: KeyTypeEC, X: ecJWK.X, Y: &y}
+	assert.Equal(t, thumbprint, CalculateThumbprintJWK(publicJWK))
+
+	for _, hash := range []crypto.Hash{crypto.SHA384, crypto.SHA512} {
+		thumbprint, err = CalculateThumbprint(ecJWK, hash)
+		require.NoError(t, err)
+		assert.Len(t, thumbprint, base64.RawURLEncoding.EncodedLen(hash.Size()))
+	}
+
+	thumbprintURI, err := CalculateThumbprintURI(ecJWK, crypto.SHA512)
+	require.NoError(t, err)
+	assert.Equal(t, ThumbprintURIPrefix+"sha-512:"+thumbprint, thumbprintURI)
+}
+
+func TestCalculateThumbprint_PostQuantum(t *testing.T) {
+	privateJWK, err := GeneratePQSignatureJWK(AlgorithmMLDSA44)
+	require.NoError(t, err)
+
+	thumbprint, err := CalculateThumbprint(privateJWK, crypto.SHA256)
+	require.NoError(t, err)
+
+	membersJSON := `{"alg":"ML-DSA-44","kty":"PQK","x":"` + privateJWK.X + `"}`
+	digest := sha256.Sum256([]byte(membersJSON))
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString(digest[:]), thumbprint)
+}
+
+func TestCalculateThumbprint_Errors(t *testing.T) {
+	crv := CurveP256
+	_, err := CalculateThumbprint(&JWK{Kty: KeyTypeEC, Crv: &crv, X: "f83OJ3D2xF1Bg8vub9tLe1gHMzV76e8Tus9uPHvRVEU"}, crypto.SHA256)
+	assert.ErrorIs(t, err, ErrMissingThumbprintMember)
+
+	_, err = CalculateThumbprint(&JWK{Kty: "unknown"}, crypto.SHA256)
+	assert.ErrorIs(t, err, ErrUnsupportedKey)
+
+	k := "GawgguFyGrWKav7AX4VKUg"
+	_, err = CalculateThumbprint(&JWK{Kty: KeyTypeOct, K: &k}, crypto.SHA1)
+	assert.ErrorIs(t, err, ErrUnsupportedThumbprintHash)
+
+	assert.Equal(t, "", CalculateThumbprintJWK(&JWK{Kty: KeyTypeOKP}))
+}
@@ -0,0 +1,165 @@
+package jwkUtils
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrMissingX509Chain         = errors.New("the JWK does not contain an X.509 certificate chain (x5c)")
+	ErrInvalidX509Chain         = errors.New("invalid X.509 certificate chain")
+	ErrX509KeyMismatch          = errors.New("the key of the X.509 certificate does not match the JWK")
+	ErrX509ThumbprintMismatch   = errors.New("the X.509 certificate SHA-256 thumbprint (x5t#S256) does not match the certificate")
+	ErrUnsupportedX509PublicKey = errors.New("unsupported public key in the X.509 certificate")
+	ErrMissingX509Roots         = errors.New("the X.509 chain verifier requires the trusted root certificates")
+)
+
+// X509ChainVerifier checks the X.509 certificate chain ("x5c") of a JWK:
+//   - Roots: REQUIRED, the trusted root certificates (e.g.: the eIDAS or EBSI trust anchors).
+//     The system roots are never used implicitly: pass x509.SystemCertPool() to trust them.
+//   - Intermediates: additional intermediate certificates (optional) to the ones in the chain.
+//   - KeyUsages: the accepted extended key usages, any of them by default.
+//   - CurrentTime: the time to check the validity of the certificates, time.Now by default.
+type X509ChainVerifier struct {
+	Roots         *x509.CertPool
+	Intermediates *x509.CertPool
+	KeyUsages     []x509.ExtKeyUsage
+	CurrentTime   func() time.Time
+}
+
+// NewX509ChainVerifier returns a verifier which uses the given trusted root certificates (required to verify).
+func NewX509ChainVerifier(roots *x509.CertPool) *X509ChainVerifier {
+	return &X509ChainVerifier{Roots: roots}
+}
+
+// VerifyJWK validates the "x5c" chain of the JWK up to a trusted root, checks the "x5t#S256" (if any)
+// and that the bare key members of the JWK are the public key of the first (leaf) certificate.
+// It returns the verified chains. The "x5u" member is not fetched.
+func (verifier *X509ChainVerifier) VerifyJWK(jwk *JWK) ([][]*x509.Certificate, error) {
+	if jwk == nil || len(jwk.X5c) == 0 {
+		return nil, ErrMissingX509Chain
+	}
+
+	certificates, err := ParseX509CertificateChain(jwk.X5c)
+	if err != nil {
+		return nil, err
+	}
+
+	leaf := certificates[0]
+	if jwk.X5tS256 != nil && !equalDigest(*jwk.X5tS256, CalculateX509ThumbprintSHA256(leaf)) {
+		return nil, ErrX509ThumbprintMismatch
+	}
+
+	if err = CheckX509CertificateKey(leaf, jwk); err != nil {
+		return nil, err
+	}
+
+	return verifier.VerifyChain(certificates)
+}
+
+// VerifyChain validates the certificate chain (the leaf first) up to a trusted root.
+// It returns ErrMissingX509Roots if the verifier is nil or it has no trusted roots.
+func (verifier *X509ChainVerifier) VerifyChain(certificates []*x509.Certificate) ([][]*x509.Certificate, error) {
+	if verifier == nil || verifier.Roots == nil {
+		return nil, ErrMissingX509Roots
+	}
+
+	if len(certificates) == 0 {
+		return nil, ErrMissingX509Chain
+	}
+
+	intermediates := x509.NewCertPool()
+	if verifier.Intermediates != nil {
+		intermediates = verifier.Intermediates.Clone()
+	}
+	for _, certificate := range certificates[1:] {
+		intermediates.AddCert(certificate)
+	}
+
+	keyUsages := verifier.KeyUsages
+	if len(keyUsages) == 0 {
+		keyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageAny}
+	}
+
+	currentTime := time.Now()
+	if verifier.CurrentTime != nil {
+		currentTime = verifier.CurrentTime()
+	}
+
+	chains, err := certificates[0].Verify(x509.VerifyOptions{
+		Roots:         verifier.Roots,
+		Intermediates: intermediates,
+		KeyUsages:     keyUsages,
+		CurrentTime:   currentTime,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidX509Chain, err)
+	}
+
+	return chains, nil
+}
+
+// ParseX509CertificateChain decodes the base64 (not base64url) DER certificates of a "x5c" member (RFC 7517, section 4.7).
+func ParseX509CertificateChain(x5c []string) ([]*x509.Certificate, error) {
+	if len(x5c) == 0 {
+		return nil, ErrMissingX509Chain
+	}
+
+	certificates := make([]*x509.Certificate, 0, len(x5c))
+	for _, encodedCertificate := range x5c {
+		der, err := base64.StdEncoding.DecodeString(encodedCertificate)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidX509Chain, err)
+		}
+
+		certificate, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidX509Chain, err)
+		}
+
+		certificates = append(certificates, certificate)
+	}
+
+	return certificates, nil
+}
+
+// EncodeX509CertificateChain returns the "x5c" member value for the certificates (the leaf first).
+func EncodeX509CertificateChain(certificates []*x509.Certificate) []string {
+	x5c := make([]string, 0, len(certificates))
+	for _, certificate := range certificates {
+		x5c = append(x5c, base64.StdEncoding.EncodeToString(certificate.Raw))
+	}
+	return x5c
+}
+
+// CalculateX509ThumbprintSHA256 returns the "x5t#S256" value: the base64url SHA-256 of the DER encoding of the certificate.
+func CalculateX509ThumbprintSHA256(certificate *x509.Certificate) string {
+	digest := sha256.Sum256(certificate.Raw)
+	return base64.RawURLEncoding.EncodeToString(digest[:])
+}
+
+// CheckX509CertificateKey returns nil if the public key of the certificate is the one of the JWK bare key members,
+// by comparing their RFC 7638 thumbprints.
+func CheckX509CertificateKey(certificate *x509.Certificate, jwk *JWK) error {
+	certificateJWK, err := FromCryptoKey(certificate.PublicKey)
+	if err != nil {
+		return ErrUnsupportedX509PublicKey
+	}
+
+	certificateThumbprint, err := CalculateThumbprint(certificateJWK, crypto.SHA256)
+	if err != nil {
+		return ErrUnsupportedX509PublicKey
+	}
+
+	jwkThumbprint, err := CalculateThumbprint(jwk, crypto.SHA256)
+	if err != nil || !equalDigest(certificateThumbprint, jwkThumbprint) {
+		return ErrX509KeyMismatch
+	}
+
+	return nil
+}
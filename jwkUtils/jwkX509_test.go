@@ -0,0 +1,129 @@
+package jwkUtils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCertificate(t *testing.T, template, parent *x509.Certificate, publicKey, signer interface{}) *x509.Certificate {
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, publicKey, signer)
+	require.NoError(t, err)
+
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return certificate
+}
+
+// newTestCertificateChain returns a root CA and a leaf certificate for the private key.
+func newTestCertificateChain(t *testing.T, leafKey crypto.Signer) (*x509.Certificate, *x509.Certificate) {
+	rootKey, err := ecdsa.GenerateKey(GetEllipticCurve(CurveP256), rand.Reader)
+	require.NoError(t, err)
+
+	rootTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Root CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	root := newTestCertificate(t, rootTemplate, rootTemplate, &rootKey.PublicKey, rootKey)
+
+	leafTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "Test Seal"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	leaf := newTestCertificate(t, leafTemplate, root, leafKey.Public(), rootKey)
+	return root, leaf
+}
+
+func TestX509ChainVerifier_VerifyJWK(t *testing.T) {
+	_, leafKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	root, leaf := newTestCertificateChain(t, leafKey)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+	verifier := NewX509ChainVerifier(roots)
+
+	publicJWK, err := FromCryptoKey(leafKey.Public())
+	require.NoError(t, err)
+	publicJWK.X5c = EncodeX509CertificateChain([]*x509.Certificate{leaf})
+	x5t := CalculateX509ThumbprintSHA256(leaf)
+	publicJWK.X5tS256 = &x5t
+
+	chains, err := verifier.VerifyJWK(publicJWK)
+	require.NoError(t, err)
+	require.Len(t, chains, 1)
+	assert.Equal(t, root.Raw, chains[0][len(chains[0])-1].Raw)
+
+	t.Run("Untrusted root", func(t *testing.T) {
+		_, err := NewX509ChainVerifier(x509.NewCertPool()).VerifyJWK(publicJWK)
+		assert.ErrorIs(t, err, ErrInvalidX509Chain)
+	})
+
+	t.Run("Missing roots", func(t *testing.T) {
+		_, err := (&X509ChainVerifier{}).VerifyJWK(publicJWK)
+		assert.ErrorIs(t, err, ErrMissingX509Roots)
+
+		var nilVerifier *X509ChainVerifier
+		_, err = nilVerifier.VerifyJWK(publicJWK)
+		assert.ErrorIs(t, err, ErrMissingX509Roots)
+	})
+
+	t.Run("Expired certificate", func(t *testing.T) {
+		expiredVerifier := NewX509ChainVerifier(roots)
+		expiredVerifier.CurrentTime = func() time.Time { return time.Now().Add(2 * time.Hour) }
+		_, err := expiredVerifier.VerifyJWK(publicJWK)
+		assert.ErrorIs(t, err, ErrInvalidX509Chain)
+	})
+
+	t.Run("Key mismatch", func(t *testing.T) {
+		otherJWK, err := GenerateJWK(AlgorithmEd25519, "")
+		require.NoError(t, err)
+		otherJWK.X5c = publicJWK.X5c
+		_, err = verifier.VerifyJWK(otherJWK)
+		assert.ErrorIs(t, err, ErrX509KeyMismatch)
+	})
+
+	t.Run("Thumbprint mismatch", func(t *testing.T) {
+		wrongJWK := *publicJWK
+		wrongThumbprint := CalculateX509ThumbprintSHA256(root)
+		wrongJWK.X5tS256 = &wrongThumbprint
+		_, err := verifier.VerifyJWK(&wrongJWK)
+		assert.ErrorIs(t, err, ErrX509ThumbprintMismatch)
+	})
+
+	t.Run("Missing or invalid chain", func(t *testing.T) {
+		_, err := verifier.VerifyJWK(&JWK{Kty: KeyTypeOKP})
+		assert.ErrorIs(t, err, ErrMissingX509Chain)
+
+		_, err = verifier.VerifyJWK(&JWK{Kty: KeyTypeOKP, X5c: []string{"not base64!"}})
+		assert.ErrorIs(t, err, ErrInvalidX509Chain)
+	})
+}
+
+func TestGetPublicJWK_X509Members(t *testing.T) {
+	x5u := "https://example.com/chain.pem"
+	x5t := "thumbprint"
+	privateJWK := &JWK{Kty: KeyTypeEC, X5c: []string{"MIIB"}, X5tS256: &x5t, X5u: &x5u}
+
+	publicJWK := GetPublicJWK(privateJWK)
+	assert.Equal(t, privateJWK.X5c, publicJWK.X5c)
+	assert.Equal(t, x5t, *publicJWK.X5tS256)
+	assert.Equal(t, x5u, *publicJWK.X5u)
+}
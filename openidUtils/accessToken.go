@@ -66,6 +66,7 @@ type AccessTokenHeader struct {
 // - "auth_time": OPTIONAL - as defined in Section 2 of [OpenID.Core].
 // - "acr":  OPTIONAL - as defined in Section 2 of [OpenID.Core].
 // - "amr":  OPTIONAL - as defined in Section 2 of [OpenID.Core].
+// - "cnf":  OPTIONAL - as defined in [RFC7800], the "jkt" of a DPoP-bound access token (RFC 9449, section 6).
 //
 type AccessTokenPayload struct {
 	Audience    string `json:"aud,omitempty" bson:"aud,omitempty"`
@@ -76,6 +77,14 @@ type AccessTokenPayload struct {
 	JSONTokenID string `json:"jti,omitempty" bson:"jti,omitempty"`
 	Scope       string `json:"scope,omitempty" bson:"scope,omitempty"`
 	Subject     string `json:"sub,omitempty" bson:"sub,omitempty"`
+
+	// Confirmation of the key the access token is bound to, e.g.: the "jkt" of a DPoP key (see BindToDPoPProof).
+	Confirmation *Confirmation `json:"cnf,omitempty" bson:"cnf,omitempty"`
+
+	// Authentication of the resource owner (see AccessTokenIssuer) and "nbf", which is required by CheckBearerPayloadDataJWT.
+	AuthTime                   int64  `json:"auth_time,omitempty" bson:"auth_time,omitempty"`
+	AuthenticationContextClass string `json:"acr,omitempty" bson:"acr,omitempty"`
+	NotBefore                  int64  `json:"nbf,omitempty" bson:"nbf,omitempty"`
 }
 
 // **FUNCTIONS**
@@ -83,6 +92,9 @@ type AccessTokenPayload struct {
 // CheckCompactAccessToken returns DataJWT (can be nil) and error message (can be empty) after checking:
 // - mandatory fields exist in the DPoP header ("alg", "kid", "typ") and are supported.
 // - checks the mandatory fields exist for the access token ("aud", "iss", "exp", "nbf", "sub").
+//
+// Deprecated: the signature is not verified, so the claims cannot be trusted.
+// Use VerifyCompactAccessToken with an AccessTokenKeyResolver and the AccessTokenConstraints.
 func CheckCompactAccessToken(compactJWT *string, audience, issuer, subject, client *string, expiry, notBefore int64) (*joseUtils.DataJWT, string) {
 	dataJWT := joseUtils.GetDataJWT(compactJWT)
 	if dataJWT == nil {
@@ -97,6 +109,9 @@ func CheckCompactAccessToken(compactJWT *string, audience, issuer, subject, clie
 // - mandatory fields exist in the Access Token header ("alg", "jwk", "typ") and are supported.
 // - checks the mandatory fields exist in the Access Token payload ("aud", "iss", "exp", "nbf", "sub")
 // and additionally match with the provided ones (optional).
+//
+// Deprecated: the signature is not verified, so the claims cannot be trusted.
+// Use VerifyCompactAccessToken with an AccessTokenKeyResolver and the AccessTokenConstraints.
 func CheckDataAccessToken(compactJWT *joseUtils.DataJWT, audience, issuer, subject, client *string, expiry, notBefore int64) (*joseUtils.DataJWT, string) {
 	errorHeader := CheckBearerHeaderDataJWT(compactJWT)
 	if errorHeader != nil {
@@ -155,23 +170,20 @@ func CheckBearerHeaderDataJWT(dataJWT *joseUtils.DataJWT) (errMsg *string) {
 //	- "iat": It identifies the time at which the JWT access token was issued.
 //	- "jti": The ID of the issued JWT.
 //
+// The "iss", "sub", "nbf" and "exp" claims are required, the "exp", "nbf" and "iat" claims are checked with the current time
+// and the provided expiry and notBefore (if not zero) are the latest "exp" and the earliest "nbf" allowed (see AccessTokenConstraints).
 func CheckBearerPayloadDataJWT(bearerDataJWT *joseUtils.DataJWT, audience, issuer, subject, client *string, expiry, notBefore int64) (errMsg *string) {
-	if bearerDataJWT == nil {
-		return &ErrAccessTokenInvalid
-	}
-
-	payloadBytes, _ := json.Marshal(bearerDataJWT.Payload)
-	openidPayload := &OpenidPayload{}
-	err := json.Unmarshal(payloadBytes, openidPayload)
-	if err != nil {
-		return &ErrAccessTokenInvalid
+	constraints := &AccessTokenConstraints{
+		Audience:  getStringConstraint(audience),
+		Issuer:    getStringConstraint(issuer),
+		Subject:   getStringConstraint(subject),
+		ClientID:  getStringConstraint(client),
+		Expiry:    expiry,
+		NotBefore: notBefore,
 	}
 
-	if openidPayload.Issuer == nil ||
-		openidPayload.Subject == nil ||
-		openidPayload.NotBefore == nil ||
-		openidPayload.Expiry == nil {
-		return &ErrAccessTokenMissingRequiredPayloadData
+	if accessTokenError := checkAccessTokenClaims(bearerDataJWT, bearerRequiredClaims, constraints); accessTokenError != nil {
+		return &accessTokenError.Description
 	}
 
 	return nil
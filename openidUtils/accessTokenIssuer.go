@@ -0,0 +1,229 @@
+package openidUtils
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/joseUtils"
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+	"github.com/google/uuid"
+)
+
+// AccessTokenLifetime is the default lifetime of the issued access tokens (a five-minute token lifetime is recommended).
+const AccessTokenLifetime = 5 * time.Minute
+
+var (
+	ErrAccessTokenIssuerConfig  = errors.New("invalid access token issuer")
+	ErrAccessTokenMissingClaims = errors.New("required claims are missing to issue the access token")
+)
+
+// AccessTokenIssuer creates JWT access tokens (RFC 9068) signed with the private key of the authorization server.
+//   - Issuer: the "iss" claim (issuer organization's identity DID).
+//   - SigningKey: the private key, which "alg" and "kid" (the JWK Thumbprint if empty) are used in the header.
+//   - Lifetime: the lifetime of the access tokens (AccessTokenLifetime if zero).
+//   - Now: returns the current time (time.Now if nil).
+//   - KeyManager and KeyID: the key manager and the "kid" of the signing key, used instead of SigningKey if KeyManager is not nil.
+type AccessTokenIssuer struct {
+	Issuer     string
+	SigningKey *jwkUtils.JWK
+	Lifetime   time.Duration
+	Now        func() time.Time
+
+	// the signing key in a key manager (e.g.: a KMS or a HSM)
+	KeyManager joseUtils.KeyManager
+	KeyID      string
+}
+
+// AccessTokenClaims are the claims of the access token to be issued (see AccessTokenPayload):
+//   - Subject: the "sub" claim (the "client_id" if empty, when no resource owner is involved).
+//   - ClientID: the "client_id" claim (REQUIRED).
+//   - Audience: the "aud" claim (REQUIRED), e.g.: the "software_id" of the client or the resource server.
+//   - Scope: the "scope" claim (OPTIONAL), the scope of access authorized.
+//   - AuthTime: the "auth_time" claim (OPTIONAL), when the resource owner was authenticated.
+//   - AuthenticationContextClass: the "acr" claim (OPTIONAL).
+//   - Confirmation: the "cnf" claim (OPTIONAL), e.g.: the "jkt" of a DPoP key, then the token type is "DPoP".
+type AccessTokenClaims struct {
+	Subject                    string
+	ClientID                   string
+	Audience                   string
+	Scope                      string
+	AuthTime                   time.Time
+	AuthenticationContextClass string
+	Confirmation               *Confirmation
+}
+
+// NewAccessTokenIssuer returns an access token issuer after checking the private key can sign with its "alg".
+func NewAccessTokenIssuer(issuer string, signingKey *jwkUtils.JWK, lifetime time.Duration) (*AccessTokenIssuer, error) {
+	accessTokenIssuer := &AccessTokenIssuer{
+		Issuer:     issuer,
+		SigningKey: signingKey,
+		Lifetime:   lifetime,
+	}
+
+	if err := accessTokenIssuer.check(); err != nil {
+		return nil, err
+	}
+	return accessTokenIssuer, nil
+}
+
+// NewAccessTokenIssuerWithKeyManager returns an access token issuer which signs with the key of the key manager.
+func NewAccessTokenIssuerWithKeyManager(issuer string, keyManager joseUtils.KeyManager, kid string, lifetime time.Duration) (*AccessTokenIssuer, error) {
+	accessTokenIssuer := &AccessTokenIssuer{
+		Issuer:     issuer,
+		Lifetime:   lifetime,
+		KeyManager: keyManager,
+		KeyID:      kid,
+	}
+
+	if err := accessTokenIssuer.check(); err != nil {
+		return nil, err
+	}
+	return accessTokenIssuer, nil
+}
+
+// CreateAccessToken returns the compact "at+jwt" and its payload with new "iat", "nbf", "exp" and "jti" claims.
+func (issuer *AccessTokenIssuer) CreateAccessToken(claims AccessTokenClaims) (string, *AccessTokenPayload, error) {
+	if err := issuer.check(); err != nil {
+		return "", nil, err
+	}
+
+	if claims.ClientID == "" || claims.Audience == "" {
+		return "", nil, ErrAccessTokenMissingClaims
+	}
+
+	randomUUID, err := uuid.NewRandom()
+	if err != nil {
+		return "", nil, err
+	}
+
+	issuedAt := issuer.now().Unix()
+	payload := &AccessTokenPayload{
+		Audience:                   claims.Audience,
+		ClientID:                   claims.ClientID,
+		Expiry:                     issuedAt + int64(issuer.getLifetime().Seconds()),
+		IssuedAt:                   issuedAt,
+		Issuer:                     issuer.Issuer,
+		JSONTokenID:                randomUUID.String(),
+		Scope:                      claims.Scope,
+		Subject:                    claims.Subject,
+		Confirmation:               claims.Confirmation,
+		AuthenticationContextClass: claims.AuthenticationContextClass,
+		NotBefore:                  issuedAt,
+	}
+
+	if payload.Subject == "" {
+		payload.Subject = claims.ClientID
+	}
+	if !claims.AuthTime.IsZero() {
+		payload.AuthTime = claims.AuthTime.Unix()
+	}
+
+	compactJWT, err := issuer.sign(payload)
+	if err != nil {
+		return "", nil, err
+	}
+	return compactJWT, payload, nil
+}
+
+// CreateResponseOAuthToken returns the token response with a new access token and the ID Token (optional).
+// The token type is "DPoP" if the access token is bound to a DPoP key, else "Bearer".
+func (issuer *AccessTokenIssuer) CreateResponseOAuthToken(claims AccessTokenClaims, idToken *string) (*ResponseOauthAccessToken, error) {
+	compactJWT, payload, err := issuer.CreateAccessToken(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResponseOauthAccessToken{
+		AccessToken:   compactJWT,
+		TokenType:     getAccessTokenType(payload),
+		ExpiresIn:     int(payload.Expiry - payload.IssuedAt),
+		Scope:         payload.Scope,
+		IdentityToken: idToken,
+	}, nil
+}
+
+// CreateAccessTokenResponseData returns the OpenidAccessTokenResponseData with a new access token and the ID Token (optional).
+func (issuer *AccessTokenIssuer) CreateAccessTokenResponseData(claims AccessTokenClaims, idToken *string) (*OpenidAccessTokenResponseData, error) {
+	compactJWT, payload, err := issuer.CreateAccessToken(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OpenidAccessTokenResponseData{
+		AccessToken: compactJWT,
+		TokenType:   getAccessTokenType(payload),
+		ExpiresIn:   payload.Expiry - payload.IssuedAt,
+		Scope:       payload.Scope,
+		IDToken:     idToken,
+	}, nil
+}
+
+// sign returns the compact "at+jwt" signed with the SigningKey or the key manager.
+func (issuer *AccessTokenIssuer) sign(payload *AccessTokenPayload) (string, error) {
+	headers := joseUtils.Headers{joseUtils.HeaderType: AccessTokenHeaderType}
+	if issuer.KeyManager != nil {
+		return joseUtils.SignCompactJWTWithKeyManager(headers, payload, issuer.KeyManager, issuer.KeyID)
+	}
+
+	kid := issuer.SigningKey.Kid
+	if kid == "" {
+		kid = jwkUtils.CalculateThumbprintJWK(issuer.SigningKey)
+	}
+	headers[joseUtils.HeaderKeyID] = kid
+
+	return joseUtils.SignCompactJWT(headers, payload, issuer.SigningKey)
+}
+
+func (issuer *AccessTokenIssuer) check() error {
+	if issuer.KeyManager != nil {
+		return issuer.checkKeyManager()
+	}
+
+	if issuer.Issuer == "" || issuer.SigningKey == nil || !issuer.SigningKey.HasPrivateMembers() {
+		return ErrAccessTokenIssuerConfig
+	}
+
+	if err := joseUtils.CheckSigningKey(issuer.SigningKey.Alg, issuer.SigningKey); err != nil {
+		return fmt.Errorf("%w: %v", ErrAccessTokenIssuerConfig, err)
+	}
+	return nil
+}
+
+// checkKeyManager checks the key manager has the signing key and its "alg" is supported.
+func (issuer *AccessTokenIssuer) checkKeyManager() error {
+	if issuer.Issuer == "" {
+		return ErrAccessTokenIssuerConfig
+	}
+
+	publicJWK, err := issuer.KeyManager.PublicJWK(issuer.KeyID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrAccessTokenIssuerConfig, err)
+	}
+
+	if err = joseUtils.CheckSigningKey(publicJWK.Alg, publicJWK); err != nil {
+		return fmt.Errorf("%w: %v", ErrAccessTokenIssuerConfig, err)
+	}
+	return nil
+}
+
+func (issuer *AccessTokenIssuer) getLifetime() time.Duration {
+	if issuer.Lifetime <= 0 {
+		return AccessTokenLifetime
+	}
+	return issuer.Lifetime
+}
+
+func (issuer *AccessTokenIssuer) now() time.Time {
+	if issuer.Now == nil {
+		return time.Now()
+	}
+	return issuer.Now()
+}
+
+func getAccessTokenType(payload *AccessTokenPayload) string {
+	if payload.Confirmation != nil && payload.Confirmation.JWKThumbprint != "" {
+		return TokenTypeDPoP
+	}
+	return AuthorizationBearerType
+}
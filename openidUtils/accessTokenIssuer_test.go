@@ -0,0 +1,125 @@
+package openidUtils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/joseUtils"
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testAccessTokenIssuer   = "did:example:issuer"
+	testAccessTokenClientID = "did:example:client#key-1"
+	testAccessTokenAudience = "https://resource.example.org"
+)
+
+func TestAccessTokenIssuer_CreateAccessToken(t *testing.T) {
+	key, err := jwkUtils.GenerateJWK(joseUtils.AlgorithmES256, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+	issuer, err := NewAccessTokenIssuer(testAccessTokenIssuer, key, 10*time.Minute)
+	require.NoError(t, err)
+
+	now := time.Now().Truncate(time.Second)
+	issuer.Now = func() time.Time { return now }
+
+	compactJWT, payload, err := issuer.CreateAccessToken(AccessTokenClaims{
+		ClientID:                   testAccessTokenClientID,
+		Audience:                   testAccessTokenAudience,
+		Scope:                      "openid patient/*.read",
+		AuthTime:                   now.Add(-time.Minute),
+		AuthenticationContextClass: "urn:mace:incommon:iap:silver",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, testAccessTokenClientID, payload.Subject)
+	assert.Equal(t, now.Add(10*time.Minute).Unix(), payload.Expiry)
+	assert.NotEmpty(t, payload.JSONTokenID)
+
+	keySet := &jwkUtils.JWKeySet{Keys: []jwkUtils.JWK{jwkUtils.ExportPublicJWK(key)}}
+	dataJWT, err := joseUtils.VerifyCompactJWT(compactJWT, keySet)
+	require.NoError(t, err)
+	assert.Equal(t, AccessTokenHeaderType, dataJWT.Header["typ"])
+	assert.Equal(t, key.Kid, dataJWT.Header["kid"])
+	assert.Equal(t, testAccessTokenIssuer, dataJWT.Payload["iss"])
+	assert.Equal(t, testAccessTokenAudience, dataJWT.Payload["aud"])
+	assert.Equal(t, "openid patient/*.read", dataJWT.Payload["scope"])
+	assert.EqualValues(t, now.Add(-time.Minute).Unix(), dataJWT.Payload["auth_time"])
+	assert.Equal(t, "urn:mace:incommon:iap:silver", dataJWT.Payload["acr"])
+	assert.NotContains(t, dataJWT.Payload, "cnf")
+
+	_, err = VerifyCompactAccessToken(&compactJWT, NewJWKeySetAccessTokenKeyResolver(keySet), nil)
+	assert.NoError(t, err)
+
+	// the "client_id" and "aud" claims are required
+	_, _, err = issuer.CreateAccessToken(AccessTokenClaims{ClientID: testAccessTokenClientID})
+	assert.ErrorIs(t, err, ErrAccessTokenMissingClaims)
+}
+
+func TestAccessTokenIssuer_CreateResponse(t *testing.T) {
+	key, err := jwkUtils.GenerateJWK(joseUtils.AlgorithmEdDSA, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+	issuer, err := NewAccessTokenIssuer(testAccessTokenIssuer, key, 0)
+	require.NoError(t, err)
+
+	claims := AccessTokenClaims{ClientID: testAccessTokenClientID, Audience: testAccessTokenAudience, Scope: "openid"}
+	idToken := "id-token"
+	response, err := issuer.CreateResponseOAuthToken(claims, &idToken)
+	require.NoError(t, err)
+	assert.Equal(t, AuthorizationBearerType, response.TokenType)
+	assert.Equal(t, int(AccessTokenLifetime.Seconds()), response.ExpiresIn)
+	assert.Equal(t, "openid", response.Scope)
+	assert.Equal(t, &idToken, response.IdentityToken)
+	assert.NotEmpty(t, response.AccessToken)
+
+	// a DPoP-bound access token
+	claims.Confirmation = &Confirmation{JWKThumbprint: "0ZcOCORZNYy-DWpqq30jZyJGHTN0d2HglBV3uiguA4I"}
+	responseData, err := issuer.CreateAccessTokenResponseData(claims, nil)
+	require.NoError(t, err)
+	assert.Equal(t, TokenTypeDPoP, responseData.TokenType)
+	assert.Equal(t, int64(AccessTokenLifetime.Seconds()), responseData.ExpiresIn)
+
+	dataJWT := joseUtils.GetDataJWT(&responseData.AccessToken)
+	require.NotNil(t, dataJWT)
+	assert.Equal(t, claims.Confirmation.JWKThumbprint, getConfirmationThumbprint(dataJWT))
+}
+
+func TestAccessTokenIssuer_KeyManager(t *testing.T) {
+	keyManager := joseUtils.NewInMemoryKeyManager()
+	kid, err := keyManager.Create(joseUtils.AlgorithmES256, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+	issuer, err := NewAccessTokenIssuerWithKeyManager(testAccessTokenIssuer, keyManager, kid, 0)
+	require.NoError(t, err)
+
+	compactJWT, _, err := issuer.CreateAccessToken(AccessTokenClaims{ClientID: testAccessTokenClientID, Audience: testAccessTokenAudience})
+	require.NoError(t, err)
+
+	publicJWK, err := keyManager.PublicJWK(kid)
+	require.NoError(t, err)
+	dataJWT, err := joseUtils.VerifyCompactJWT(compactJWT, &jwkUtils.JWKeySet{Keys: []jwkUtils.JWK{*publicJWK}})
+	require.NoError(t, err)
+	assert.Equal(t, AccessTokenHeaderType, dataJWT.Header["typ"])
+	assert.Equal(t, kid, dataJWT.Header["kid"])
+
+	_, err = NewAccessTokenIssuerWithKeyManager(testAccessTokenIssuer, keyManager, "unknown", 0)
+	assert.ErrorIs(t, err, ErrAccessTokenIssuerConfig)
+	_, err = NewAccessTokenIssuerWithKeyManager("", keyManager, kid, 0)
+	assert.ErrorIs(t, err, ErrAccessTokenIssuerConfig)
+}
+
+func TestNewAccessTokenIssuer(t *testing.T) {
+	key, err := jwkUtils.GenerateJWK(joseUtils.AlgorithmES256, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+
+	_, err = NewAccessTokenIssuer("", key, 0)
+	assert.ErrorIs(t, err, ErrAccessTokenIssuerConfig)
+
+	publicJWK := jwkUtils.ExportPublicJWK(key)
+	_, err = NewAccessTokenIssuer(testAccessTokenIssuer, &publicJWK, 0)
+	assert.ErrorIs(t, err, ErrAccessTokenIssuerConfig)
+
+	key.Alg = joseUtils.AlgorithmES384
+	_, err = NewAccessTokenIssuer(testAccessTokenIssuer, key, 0)
+	assert.ErrorIs(t, err, ErrAccessTokenIssuerConfig)
+}
@@ -0,0 +1,360 @@
+package openidUtils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/didDocumentUtils"
+	"github.com/Universal-Health-Chain/common-utils-golang/joseUtils"
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+)
+
+// ClaimClientID is the "client_id" claim of a JWT access token (RFC 9068, section 2.2).
+const ClaimClientID = "client_id"
+
+var (
+	ErrAccessTokenInvalidSignature    = `invalid access token signature`
+	ErrAccessTokenIssuerKeyNotFound   = `the issuer's key of the access token was not found`
+	ErrAccessTokenInvalidIssuer       = `the access token issuer does not match`
+	ErrAccessTokenInvalidAudience     = `the access token audience does not match`
+	ErrAccessTokenInvalidSubject      = `the access token subject does not match`
+	ErrAccessTokenInvalidClient       = `the access token client does not match`
+	ErrAccessTokenNotValidYet         = `the access token is not valid yet`
+	ErrAccessTokenIssuedInTheFuture   = `the access token is issued in the future`
+	ErrAccessTokenExpiryNotAllowed    = `the access token expires later than allowed`
+	ErrAccessTokenNotBeforeNotAllowed = `the access token is valid earlier than allowed`
+
+	// ErrInvalidClientID indicates that the "client_id" claim does not match (it can be checked with errors.Is).
+	ErrInvalidClientID = errors.New("validation failed, invalid client claim (client_id)")
+	// ErrInvalidTimeRange indicates that the "exp" or "nbf" claims are out of the allowed range.
+	ErrInvalidTimeRange = errors.New("validation failed, the token is valid out of the allowed time range")
+)
+
+// AccessTokenRequiredClaims are the claims a JWT access token MUST contain (RFC 9068, section 2.2).
+var AccessTokenRequiredClaims = []string{
+	joseUtils.ClaimIssuer,
+	joseUtils.ClaimExpirationTime,
+	joseUtils.ClaimAudience,
+	joseUtils.ClaimSubject,
+	ClaimClientID,
+	joseUtils.ClaimIssuedAt,
+	joseUtils.ClaimJWTID,
+}
+
+// bearerRequiredClaims are the claims required by CheckBearerPayloadDataJWT.
+var bearerRequiredClaims = []string{
+	joseUtils.ClaimIssuer,
+	joseUtils.ClaimSubject,
+	joseUtils.ClaimNotBefore,
+	joseUtils.ClaimExpirationTime,
+}
+
+// claimErrors maps the validation errors of joseUtils.Validator to the access token error messages and claims.
+var claimErrors = []struct {
+	err         error
+	description string
+	claim       string
+}{
+	{joseUtils.ErrInvalidIssuer, ErrAccessTokenInvalidIssuer, joseUtils.ClaimIssuer},
+	{joseUtils.ErrInvalidSubject, ErrAccessTokenInvalidSubject, joseUtils.ClaimSubject},
+	{joseUtils.ErrInvalidAudience, ErrAccessTokenInvalidAudience, joseUtils.ClaimAudience},
+	{joseUtils.ErrInvalidID, ErrAccessTokenInvalid, joseUtils.ClaimJWTID},
+	{joseUtils.ErrExpired, ErrAccessTokenExpired, joseUtils.ClaimExpirationTime},
+	{joseUtils.ErrNotValidYet, ErrAccessTokenNotValidYet, joseUtils.ClaimNotBefore},
+	{joseUtils.ErrIssuedInTheFuture, ErrAccessTokenIssuedInTheFuture, joseUtils.ClaimIssuedAt},
+}
+
+// AccessTokenKeyResolver returns the issuer's public key for the "kid" header of a JWT access token
+// (e.g.: from a JWK Set or from the "assertionMethod" of the issuer's DID Document).
+type AccessTokenKeyResolver func(kid string) (*jwkUtils.JWK, error)
+
+// DidDocumentResolver returns the DID Document of the DID.
+type DidDocumentResolver func(did string) (*didDocumentUtils.DidDoc, error)
+
+// AccessTokenError is the structured error of the access token validation:
+//   - Code: the error code for the "WWW-Authenticate" challenge, e.g.: "invalid_token" or "invalid_dpop_proof".
+//   - Description: the error message, e.g.: ErrAccessTokenExpired.
+//   - Claim: the claim which is missing or not valid (if any), e.g.: "aud".
+//   - Err: the cause (can be nil), e.g.: joseUtils.ErrExpired or joseUtils.ErrSignature, so it can be checked with errors.Is.
+type AccessTokenError struct {
+	Code        string
+	Description string
+	Claim       string
+	Err         error
+}
+
+func (accessTokenError *AccessTokenError) Error() string {
+	if accessTokenError.Err == nil {
+		return accessTokenError.Description
+	}
+	return accessTokenError.Description + ": " + accessTokenError.Err.Error()
+}
+
+func (accessTokenError *AccessTokenError) Unwrap() error {
+	return accessTokenError.Err
+}
+
+// AccessTokenConstraints are the expected values of a JWT access token (empty values are not checked):
+//   - Audience, Issuer, Subject and ClientID: the "aud" (it can be an array), "iss", "sub" and "client_id" claims.
+//   - Expiry: the latest "exp" allowed (Unix seconds), e.g.: to reject access tokens with a too long lifetime.
+//   - NotBefore: the earliest "nbf" (or "iat" if there is no "nbf") allowed (Unix seconds),
+//     e.g.: to reject the access tokens issued before a revocation.
+//   - Leeway: the clock skew allowed for the "exp", "nbf" and "iat" claims.
+//   - Now: returns the current time (time.Now if nil).
+//
+// The access token is always rejected if it is expired ("exp"), not valid yet ("nbf") or issued in the future ("iat").
+type AccessTokenConstraints struct {
+	Audience  string
+	Issuer    string
+	Subject   string
+	ClientID  string
+	Expiry    int64
+	NotBefore int64
+	Leeway    time.Duration
+	Now       func() time.Time
+}
+
+// NewJWKeySetAccessTokenKeyResolver returns an AccessTokenKeyResolver for the keys of the set with the same "kid".
+func NewJWKeySetAccessTokenKeyResolver(keys *jwkUtils.JWKeySet) AccessTokenKeyResolver {
+	return func(kid string) (*jwkUtils.JWK, error) {
+		if key := keys.FindByKid(kid); key != nil {
+			return key, nil
+		}
+		return nil, fmt.Errorf("%w: %q", joseUtils.ErrKeyNotFound, kid)
+	}
+}
+
+// NewDidDocumentAccessTokenKeyResolver returns an AccessTokenKeyResolver for a "kid" which is a DID URL
+// (e.g.: "did:example:issuer#key-1"). The DID Document is resolved and the public key is the "publicKeyJwk"
+// of the verification method with the same ID (or the "#fragment" one) in the "assertionMethod":
+// the method can be embedded or only referenced by its ID, then the "verificationMethod" with the same ID is used.
+// The other verification methods (e.g.: the "authentication" or "keyAgreement" ones) cannot sign access tokens.
+// VerifyCompactAccessToken also requires the DID of the "kid" to be the "iss" claim.
+func NewDidDocumentAccessTokenKeyResolver(resolveDidDocument DidDocumentResolver) AccessTokenKeyResolver {
+	return func(kid string) (*jwkUtils.JWK, error) {
+		did, fragment, isDidURL := strings.Cut(kid, "#")
+		if !isDidURL || !strings.HasPrefix(did, "did:") || fragment == "" {
+			return nil, fmt.Errorf("%w: %q is not a DID URL", joseUtils.ErrKeyNotFound, kid)
+		}
+
+		didDocument, err := resolveDidDocument(did)
+		if err != nil {
+			return nil, err
+		}
+		if didDocument == nil || didDocument.ID != did {
+			return nil, fmt.Errorf("%w: the DID Document of %q was not resolved", joseUtils.ErrKeyNotFound, did)
+		}
+
+		if didDocument.AssertionMethod == nil {
+			return nil, fmt.Errorf("%w: %q is not an assertion method", joseUtils.ErrKeyNotFound, kid)
+		}
+
+		for _, assertionMethod := range *didDocument.AssertionMethod {
+			if !isVerificationMethodID(assertionMethod.ID, kid, fragment) {
+				continue
+			}
+			if assertionMethod.PublicKeyJwk != nil {
+				return assertionMethod.PublicKeyJwk, nil
+			}
+
+			// the assertion method references a verification method
+			for _, verificationMethod := range didDocument.VerificationMethod {
+				if isVerificationMethodID(verificationMethod.ID, kid, fragment) && verificationMethod.PublicKeyJwk != nil {
+					return verificationMethod.PublicKeyJwk, nil
+				}
+			}
+		}
+
+		return nil, fmt.Errorf("%w: %q is not an assertion method", joseUtils.ErrKeyNotFound, kid)
+	}
+}
+
+// isVerificationMethodID reports whether the ID of a verification method is the DID URL or its relative "#fragment".
+func isVerificationMethodID(id, didURL, fragment string) bool {
+	return id == didURL || id == "#"+fragment
+}
+
+// VerifyCompactAccessToken returns the DataJWT of the access token (nil if error) and an *AccessTokenError (can be nil)
+// after checking:
+// - the header has "typ" ("at+jwt"), "alg" and "kid" (see CheckBearerHeaderDataJWT).
+// - the signature, with the issuer's key returned by the resolver for the "kid".
+// - the DID of the "kid" is the "iss" claim (if the "kid" is a DID URL).
+// - the required claims (see AccessTokenRequiredClaims) and the constraints (see AccessTokenConstraints).
+// - the access token is not bound to a DPoP key ("cnf" claim with "jkt"), which requires VerifyCompactDPoPAccessToken (RFC 9449 section 7.2).
+func VerifyCompactAccessToken(compactJWT *string, resolveKey AccessTokenKeyResolver, constraints *AccessTokenConstraints) (*joseUtils.DataJWT, error) {
+	accessTokenDataJWT, err := verifyCompactAccessToken(compactJWT, resolveKey, constraints)
+	if err != nil {
+		return nil, err
+	}
+
+	// a DPoP-bound access token cannot be used as a bearer token
+	if getConfirmationThumbprint(accessTokenDataJWT) != "" {
+		return nil, newAccessTokenError(ErrDPoPKeyBindingMissing, "cnf", nil)
+	}
+
+	return accessTokenDataJWT, nil
+}
+
+// verifyCompactAccessToken does the checks of VerifyCompactAccessToken except the DPoP key binding ("cnf" claim).
+func verifyCompactAccessToken(compactJWT *string, resolveKey AccessTokenKeyResolver, constraints *AccessTokenConstraints) (*joseUtils.DataJWT, error) {
+	dataJWT := joseUtils.GetDataJWT(compactJWT)
+	if dataJWT == nil {
+		return nil, newAccessTokenError(ErrAccessTokenInvalid, "", joseUtils.ErrInvalidJWT)
+	}
+
+	if errMsg := CheckBearerHeaderDataJWT(dataJWT); errMsg != nil {
+		return nil, newAccessTokenError(*errMsg, "", joseUtils.ErrInvalidJWT)
+	}
+
+	kid, _ := dataJWT.Header.KeyID()
+	if resolveKey == nil {
+		return nil, newAccessTokenError(ErrAccessTokenIssuerKeyNotFound, "", joseUtils.ErrKeyNotFound)
+	}
+	key, err := resolveKey(kid)
+	if err != nil || key == nil {
+		return nil, newAccessTokenError(ErrAccessTokenIssuerKeyNotFound, "", err)
+	}
+
+	// the resolved key is used for the "kid" of the header
+	verificationKey := jwkUtils.ExportPublicJWK(key)
+	verificationKey.Kid = kid
+	verifiedDataJWT, err := joseUtils.VerifyCompactJWT(*compactJWT, &jwkUtils.JWKeySet{Keys: []jwkUtils.JWK{verificationKey}})
+	if err != nil {
+		return nil, newAccessTokenError(ErrAccessTokenInvalidSignature, "", err)
+	}
+
+	if did, _, isDidURL := strings.Cut(kid, "#"); isDidURL && strings.HasPrefix(did, "did:") {
+		if issuer, _ := verifiedDataJWT.Payload[joseUtils.ClaimIssuer].(string); issuer != did {
+			return nil, newAccessTokenError(ErrAccessTokenInvalidIssuer, joseUtils.ClaimIssuer, joseUtils.ErrInvalidIssuer)
+		}
+	}
+
+	if accessTokenError := checkAccessTokenClaims(verifiedDataJWT, AccessTokenRequiredClaims, constraints); accessTokenError != nil {
+		return nil, accessTokenError
+	}
+
+	return verifiedDataJWT, nil
+}
+
+// VerifyCompactDPoPAccessToken returns the DataJWT of the access token (nil if error) and an *AccessTokenError (can be nil)
+// after verifying the access token (see VerifyCompactAccessToken, but the "cnf" claim is allowed) and requiring a matching DPoP proof:
+// - the access token has the "cnf" claim with the "jkt" of a DPoP key.
+// - the DPoP proof is valid for the request and the access token ("ath"), see VerifyCompactDPoP.
+// - the key of the DPoP proof has the same "jkt".
+// The error code is "invalid_dpop_proof" or "use_dpop_nonce" for the DPoP proof errors.
+func VerifyCompactDPoPAccessToken(compactJWT, dpopCompactJWT *string, httpMethod, httpURL *string, resolveKey AccessTokenKeyResolver, constraints *AccessTokenConstraints, dpopOptions *DPoPVerificationOptions) (*joseUtils.DataJWT, error) {
+	accessTokenDataJWT, err := verifyCompactAccessToken(compactJWT, resolveKey, constraints)
+	if err != nil {
+		return nil, err
+	}
+
+	if errMsg := checkDPoPKeyBinding(accessTokenDataJWT, compactJWT, dpopCompactJWT, httpMethod, httpURL, dpopOptions); errMsg != "" {
+		accessTokenError := newAccessTokenError(errMsg, "", nil)
+		if errMsg == ErrDPoPKeyBindingMissing || errMsg == ErrDPoPInvalidKeyBinding {
+			accessTokenError.Claim = "cnf"
+		} else if IsDPoPNonceError(errMsg) {
+			accessTokenError.Code = ErrOpenidUseDPoPNonce
+		} else {
+			accessTokenError.Code = ErrOpenidInvalidDPoPProof
+		}
+		return nil, accessTokenError
+	}
+
+	return accessTokenDataJWT, nil
+}
+
+// checkAccessTokenClaims checks the required claims, the time claims and the constraints (if any).
+func checkAccessTokenClaims(dataJWT *joseUtils.DataJWT, requiredClaims []string, constraints *AccessTokenConstraints) *AccessTokenError {
+	if dataJWT == nil || dataJWT.Payload == nil {
+		return newAccessTokenError(ErrAccessTokenInvalid, "", joseUtils.ErrInvalidClaims)
+	}
+	if constraints == nil {
+		constraints = &AccessTokenConstraints{}
+	}
+
+	for _, claim := range requiredClaims {
+		if _, found := dataJWT.Payload[claim]; !found {
+			return newAccessTokenError(ErrAccessTokenMissingRequiredPayloadData, claim, joseUtils.ErrMissingClaim)
+		}
+	}
+
+	validator := &joseUtils.Validator{
+		Subject: constraints.Subject,
+		Leeway:  constraints.Leeway,
+		Now:     constraints.Now,
+	}
+	if constraints.Issuer != "" {
+		validator.Issuers = []string{constraints.Issuer}
+	}
+	if constraints.Audience != "" {
+		validator.Audiences = []string{constraints.Audience}
+	}
+
+	if err := validator.ValidateClaims(dataJWT.Header, dataJWT.Payload); err != nil {
+		for _, claimError := range claimErrors {
+			if errors.Is(err, claimError.err) {
+				return newAccessTokenError(claimError.description, claimError.claim, err)
+			}
+		}
+		return newAccessTokenError(ErrAccessTokenInvalid, "", err)
+	}
+
+	clientID, isString := dataJWT.Payload[ClaimClientID].(string)
+	if (!isString && dataJWT.Payload[ClaimClientID] != nil) || (constraints.ClientID != "" && clientID != constraints.ClientID) {
+		return newAccessTokenError(ErrAccessTokenInvalidClient, ClaimClientID, ErrInvalidClientID)
+	}
+
+	// the time claims have a valid type after the validation
+	if expiry, found := getUnixTimeClaim(dataJWT.Payload, joseUtils.ClaimExpirationTime); constraints.Expiry > 0 && (!found || expiry > constraints.Expiry) {
+		return newAccessTokenError(ErrAccessTokenExpiryNotAllowed, joseUtils.ClaimExpirationTime, ErrInvalidTimeRange)
+	}
+
+	if constraints.NotBefore > 0 {
+		notBefore, found := getUnixTimeClaim(dataJWT.Payload, joseUtils.ClaimNotBefore)
+		if !found {
+			notBefore, found = getUnixTimeClaim(dataJWT.Payload, joseUtils.ClaimIssuedAt)
+		}
+		if !found || notBefore < constraints.NotBefore {
+			return newAccessTokenError(ErrAccessTokenNotBeforeNotAllowed, joseUtils.ClaimNotBefore, ErrInvalidTimeRange)
+		}
+	}
+
+	return nil
+}
+
+func newAccessTokenError(description, claim string, err error) *AccessTokenError {
+	return &AccessTokenError{
+		Code:        ErrOpenidInvalidToken,
+		Description: description,
+		Claim:       claim,
+		Err:         err,
+	}
+}
+
+// getUnixTimeClaim returns the seconds of a NumericDate claim (without the fraction) and whether it exists.
+func getUnixTimeClaim(claims map[string]interface{}, name string) (int64, bool) {
+	switch value := claims[name].(type) {
+	case float64:
+		return int64(value), true
+	case json.Number:
+		floatValue, err := value.Float64()
+		return int64(floatValue), err == nil
+	case int64:
+		return value, true
+	case int:
+		return int64(value), true
+	default:
+		return 0, false
+	}
+}
+
+// getStringConstraint returns the value of an optional parameter (empty if nil).
+func getStringConstraint(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
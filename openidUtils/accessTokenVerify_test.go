@@ -0,0 +1,251 @@
+package openidUtils
+
+import (
+	"crypto"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/didDocumentUtils"
+	"github.com/Universal-Health-Chain/common-utils-golang/joseUtils"
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAccessTokenIssuer(t *testing.T, issuerDid, kid string) (*AccessTokenIssuer, *jwkUtils.JWK) {
+	key, err := jwkUtils.GenerateJWK(joseUtils.AlgorithmES256, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+	if kid != "" {
+		key.Kid = kid
+	}
+
+	issuer, err := NewAccessTokenIssuer(issuerDid, key, 0)
+	require.NoError(t, err)
+	return issuer, key
+}
+
+func requireAccessTokenError(t *testing.T, err error, description, claim string) *AccessTokenError {
+	accessTokenError := &AccessTokenError{}
+	require.True(t, errors.As(err, &accessTokenError), err)
+	assert.Equal(t, ErrOpenidInvalidToken, accessTokenError.Code)
+	assert.Equal(t, description, accessTokenError.Description)
+	assert.Equal(t, claim, accessTokenError.Claim)
+	return accessTokenError
+}
+
+func TestVerifyCompactAccessToken(t *testing.T) {
+	issuer, key := newTestAccessTokenIssuer(t, testAccessTokenIssuer, "")
+	claims := AccessTokenClaims{Subject: "did:example:alice", ClientID: testAccessTokenClientID, Audience: testAccessTokenAudience}
+	accessToken, payload, err := issuer.CreateAccessToken(claims)
+	require.NoError(t, err)
+
+	resolveKey := NewJWKeySetAccessTokenKeyResolver(jwkUtils.CreateJWKeySet(&[]jwkUtils.JWK{*key}))
+	constraints := &AccessTokenConstraints{
+		Audience:  testAccessTokenAudience,
+		Issuer:    testAccessTokenIssuer,
+		Subject:   "did:example:alice",
+		ClientID:  testAccessTokenClientID,
+		Expiry:    payload.Expiry,
+		NotBefore: payload.IssuedAt,
+	}
+
+	dataJWT, err := VerifyCompactAccessToken(&accessToken, resolveKey, constraints)
+	require.NoError(t, err)
+	assert.Equal(t, payload.JSONTokenID, dataJWT.Payload["jti"])
+
+	// every constraint is enforced
+	testCases := map[string]struct {
+		constraints AccessTokenConstraints
+		description string
+		claim       string
+	}{
+		"audience":   {AccessTokenConstraints{Audience: "https://other.example.org"}, ErrAccessTokenInvalidAudience, "aud"},
+		"issuer":     {AccessTokenConstraints{Issuer: "did:example:other"}, ErrAccessTokenInvalidIssuer, "iss"},
+		"subject":    {AccessTokenConstraints{Subject: "did:example:bob"}, ErrAccessTokenInvalidSubject, "sub"},
+		"client":     {AccessTokenConstraints{ClientID: "did:example:other#key-1"}, ErrAccessTokenInvalidClient, "client_id"},
+		"expiry":     {AccessTokenConstraints{Expiry: payload.Expiry - 1}, ErrAccessTokenExpiryNotAllowed, "exp"},
+		"not before": {AccessTokenConstraints{NotBefore: payload.IssuedAt + 1}, ErrAccessTokenNotBeforeNotAllowed, "nbf"},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := VerifyCompactAccessToken(&accessToken, resolveKey, &testCase.constraints)
+			requireAccessTokenError(t, err, testCase.description, testCase.claim)
+		})
+	}
+
+	// the time claims are always checked
+	_, err = VerifyCompactAccessToken(&accessToken, resolveKey, &AccessTokenConstraints{Now: func() time.Time { return time.Now().Add(time.Hour) }})
+	requireAccessTokenError(t, err, ErrAccessTokenExpired, "exp")
+	assert.ErrorIs(t, err, joseUtils.ErrExpired)
+}
+
+func TestVerifyCompactAccessToken_Signature(t *testing.T) {
+	issuer, key := newTestAccessTokenIssuer(t, testAccessTokenIssuer, "")
+	accessToken, _, err := issuer.CreateAccessToken(AccessTokenClaims{ClientID: testAccessTokenClientID, Audience: testAccessTokenAudience})
+	require.NoError(t, err)
+
+	// another key with the same "kid"
+	otherKey, err := jwkUtils.GenerateJWK(joseUtils.AlgorithmES256, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+	otherKey.Kid = key.Kid
+	_, err = VerifyCompactAccessToken(&accessToken, NewJWKeySetAccessTokenKeyResolver(jwkUtils.CreateJWKeySet(&[]jwkUtils.JWK{*otherKey})), nil)
+	requireAccessTokenError(t, err, ErrAccessTokenInvalidSignature, "")
+
+	_, err = VerifyCompactAccessToken(&accessToken, NewJWKeySetAccessTokenKeyResolver(nil), nil)
+	requireAccessTokenError(t, err, ErrAccessTokenIssuerKeyNotFound, "")
+	assert.ErrorIs(t, err, joseUtils.ErrKeyNotFound)
+
+	_, err = VerifyCompactAccessToken(&accessToken, nil, nil)
+	requireAccessTokenError(t, err, ErrAccessTokenIssuerKeyNotFound, "")
+
+	// the required claims of RFC 9068
+	unsignedClaims := map[string]interface{}{"iss": testAccessTokenIssuer, "sub": "did:example:alice", "exp": time.Now().Add(time.Minute).Unix()}
+	incompleteToken, err := joseUtils.SignCompactJWT(joseUtils.Headers{"typ": AccessTokenHeaderType}, unsignedClaims, key)
+	require.NoError(t, err)
+	_, err = VerifyCompactAccessToken(&incompleteToken, NewJWKeySetAccessTokenKeyResolver(jwkUtils.CreateJWKeySet(&[]jwkUtils.JWK{*key})), nil)
+	requireAccessTokenError(t, err, ErrAccessTokenMissingRequiredPayloadData, "aud")
+
+	idToken, err := joseUtils.SignCompactJWT(joseUtils.Headers{"typ": "JWT"}, unsignedClaims, key)
+	require.NoError(t, err)
+	_, err = VerifyCompactAccessToken(&idToken, NewJWKeySetAccessTokenKeyResolver(jwkUtils.CreateJWKeySet(&[]jwkUtils.JWK{*key})), nil)
+	requireAccessTokenError(t, err, ErrAccessTokenWrongType, "")
+}
+
+func TestVerifyCompactAccessToken_DidDocument(t *testing.T) {
+	issuerDid := "did:example:issuer"
+	issuer, key := newTestAccessTokenIssuer(t, issuerDid, issuerDid+"#key-1")
+	publicJWK := jwkUtils.ExportPublicJWK(key)
+	publicJWK.Kid = ""
+
+	didDocuments := map[string]*didDocumentUtils.DidDoc{
+		issuerDid: {
+			ID:              issuerDid,
+			AssertionMethod: &[]didDocumentUtils.VerificationMethod{{ID: "#key-1", Type: didDocumentUtils.TypeVerificationJsonWebKey2020, PublicKeyJwk: &publicJWK}},
+		},
+	}
+	resolveKey := NewDidDocumentAccessTokenKeyResolver(func(did string) (*didDocumentUtils.DidDoc, error) {
+		return didDocuments[did], nil
+	})
+
+	accessToken, _, err := issuer.CreateAccessToken(AccessTokenClaims{ClientID: testAccessTokenClientID, Audience: testAccessTokenAudience})
+	require.NoError(t, err)
+	_, err = VerifyCompactAccessToken(&accessToken, resolveKey, &AccessTokenConstraints{Issuer: issuerDid})
+	require.NoError(t, err)
+
+	// the key of the DID Document cannot be used by other issuers
+	otherIssuer, err := NewAccessTokenIssuer("did:example:other", key, 0)
+	require.NoError(t, err)
+	otherAccessToken, _, err := otherIssuer.CreateAccessToken(AccessTokenClaims{ClientID: testAccessTokenClientID, Audience: testAccessTokenAudience})
+	require.NoError(t, err)
+	_, err = VerifyCompactAccessToken(&otherAccessToken, resolveKey, nil)
+	requireAccessTokenError(t, err, ErrAccessTokenInvalidIssuer, "iss")
+
+	unknownIssuer, _ := newTestAccessTokenIssuer(t, "did:example:unknown", "did:example:unknown#key-1")
+	unknownAccessToken, _, err := unknownIssuer.CreateAccessToken(AccessTokenClaims{ClientID: testAccessTokenClientID, Audience: testAccessTokenAudience})
+	require.NoError(t, err)
+	_, err = VerifyCompactAccessToken(&unknownAccessToken, resolveKey, nil)
+	requireAccessTokenError(t, err, ErrAccessTokenIssuerKeyNotFound, "")
+}
+
+func TestNewDidDocumentAccessTokenKeyResolver(t *testing.T) {
+	issuerDid := "did:example:issuer"
+	key, err := jwkUtils.GenerateJWK(joseUtils.AlgorithmES256, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+	publicJWK := jwkUtils.ExportPublicJWK(key)
+
+	didDocument := &didDocumentUtils.DidDoc{
+		ID: issuerDid,
+		VerificationMethod: []didDocumentUtils.VerificationMethod{
+			{ID: issuerDid + "#key-1", Type: didDocumentUtils.TypeVerificationJsonWebKey2020, PublicKeyJwk: &publicJWK},
+			{ID: issuerDid + "#key-2", Type: didDocumentUtils.TypeVerificationJsonWebKey2020, PublicKeyJwk: &publicJWK},
+		},
+		// the assertion method references the first verification method
+		AssertionMethod: &[]didDocumentUtils.VerificationMethod{{ID: issuerDid + "#key-1"}},
+	}
+	resolveKey := NewDidDocumentAccessTokenKeyResolver(func(did string) (*didDocumentUtils.DidDoc, error) {
+		return didDocument, nil
+	})
+
+	resolvedKey, err := resolveKey(issuerDid + "#key-1")
+	require.NoError(t, err)
+	assert.Equal(t, &publicJWK, resolvedKey)
+
+	// a verification method which is not an assertion method cannot sign access tokens
+	_, err = resolveKey(issuerDid + "#key-2")
+	assert.ErrorIs(t, err, joseUtils.ErrKeyNotFound)
+
+	didDocument.AssertionMethod = nil
+	_, err = resolveKey(issuerDid + "#key-1")
+	assert.ErrorIs(t, err, joseUtils.ErrKeyNotFound)
+}
+
+func TestCheckBearerPayloadDataJWT(t *testing.T) {
+	issuer, _ := newTestAccessTokenIssuer(t, testAccessTokenIssuer, "")
+	claims := AccessTokenClaims{Subject: "did:example:alice", ClientID: testAccessTokenClientID, Audience: testAccessTokenAudience}
+	accessToken, _, err := issuer.CreateAccessToken(claims)
+	require.NoError(t, err)
+	dataJWT := joseUtils.GetDataJWT(&accessToken)
+
+	audience, issuerDid, subject, client := testAccessTokenAudience, testAccessTokenIssuer, claims.Subject, testAccessTokenClientID
+	assert.Nil(t, CheckBearerPayloadDataJWT(dataJWT, &audience, &issuerDid, &subject, &client, 0, 0))
+	assert.Nil(t, CheckBearerPayloadDataJWT(dataJWT, nil, nil, nil, nil, 0, 0))
+
+	otherAudience, otherClient := "https://other.example.org", "com.example.other-app"
+	assert.Equal(t, &ErrAccessTokenInvalidAudience, CheckBearerPayloadDataJWT(dataJWT, &otherAudience, nil, nil, nil, 0, 0))
+	assert.Equal(t, &ErrAccessTokenInvalidClient, CheckBearerPayloadDataJWT(dataJWT, nil, nil, nil, &otherClient, 0, 0))
+	assert.Equal(t, &ErrAccessTokenExpiryNotAllowed, CheckBearerPayloadDataJWT(dataJWT, nil, nil, nil, nil, time.Now().Unix(), 0))
+
+	// the deprecated functions also check the provided values
+	_, errMsg := CheckCompactAccessToken(&accessToken, &otherAudience, nil, nil, nil, 0, 0)
+	assert.Equal(t, ErrAccessTokenInvalidAudience, errMsg)
+	_, errMsg = CheckDataAccessToken(dataJWT, nil, nil, nil, &otherClient, 0, 0)
+	assert.Equal(t, ErrAccessTokenInvalidClient, errMsg)
+
+	issuer.Now = func() time.Time { return time.Now().Add(-time.Hour) }
+	expiredAccessToken, _, err := issuer.CreateAccessToken(claims)
+	require.NoError(t, err)
+	assert.Equal(t, &ErrAccessTokenExpired, CheckBearerPayloadDataJWT(joseUtils.GetDataJWT(&expiredAccessToken), nil, nil, nil, nil, 0, 0))
+
+	delete(dataJWT.Payload, "nbf")
+	assert.Equal(t, &ErrAccessTokenMissingRequiredPayloadData, CheckBearerPayloadDataJWT(dataJWT, nil, nil, nil, nil, 0, 0))
+}
+
+func TestVerifyCompactDPoPAccessToken(t *testing.T) {
+	issuer, key := newTestAccessTokenIssuer(t, testAccessTokenIssuer, "")
+	dpopKey, err := jwkUtils.GenerateJWK(joseUtils.AlgorithmES256, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+	thumbprint, err := jwkUtils.CalculateThumbprint(dpopKey, crypto.SHA256)
+	require.NoError(t, err)
+
+	accessToken, _, err := issuer.CreateAccessToken(AccessTokenClaims{
+		ClientID:     testAccessTokenClientID,
+		Audience:     testAccessTokenAudience,
+		Confirmation: &Confirmation{JWKThumbprint: thumbprint},
+	})
+	require.NoError(t, err)
+
+	resolveKey := NewJWKeySetAccessTokenKeyResolver(jwkUtils.CreateJWKeySet(&[]jwkUtils.JWK{*key}))
+	httpMethod, httpURL := testDPoPHttpMethod, testDPoPHttpURL
+	dpopProof, err := NewDPoPProof(dpopKey, httpMethod, httpURL, accessToken, "")
+	require.NoError(t, err)
+
+	dataJWT, err := VerifyCompactDPoPAccessToken(&accessToken, &dpopProof, &httpMethod, &httpURL, resolveKey, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, thumbprint, getConfirmationThumbprint(dataJWT))
+
+	// the DPoP-bound access token is not accepted as a bearer token
+	_, err = VerifyCompactAccessToken(&accessToken, resolveKey, nil)
+	requireAccessTokenError(t, err, ErrDPoPKeyBindingMissing, "cnf")
+
+	_, err = VerifyCompactDPoPAccessToken(&accessToken, nil, &httpMethod, &httpURL, resolveKey, nil, nil)
+	accessTokenError := &AccessTokenError{}
+	require.ErrorAs(t, err, &accessTokenError)
+	assert.Equal(t, ErrOpenidInvalidDPoPProof, accessTokenError.Code)
+	assert.Equal(t, ErrDPoPInvalid, accessTokenError.Description)
+
+	otherProof, err := NewDPoPProof(key, httpMethod, httpURL, accessToken, "")
+	require.NoError(t, err)
+	_, err = VerifyCompactDPoPAccessToken(&accessToken, &otherProof, &httpMethod, &httpURL, resolveKey, nil, nil)
+	requireAccessTokenError(t, err, ErrDPoPInvalidKeyBinding, "cnf")
+}
@@ -1,9 +1,16 @@
 package openidUtils
 
 import (
+	"crypto"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/Universal-Health-Chain/common-utils-golang/joseUtils"
 	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
@@ -19,6 +26,23 @@ import (
 
 const DPoPHeaderType = "dpop+jwt"
 
+const (
+	// DPoPMaxAge is how old the "iat" of a DPoP proof can be by default.
+	DPoPMaxAge = 5 * time.Minute
+	// DPoPLeeway is the clock skew allowed for the "iat" of a DPoP proof by default.
+	DPoPLeeway = 30 * time.Second
+	// DPoPReplayWindow is how long the "jti" of a DPoP proof is remembered after its "iat" to detect a replay
+	// (a DPoP proof has no "exp" claim, but it is rejected after DPoPMaxAge).
+	DPoPReplayWindow = DPoPMaxAge + DPoPLeeway
+)
+
+// DPoPAllowedAlgorithms are the asymmetric algorithms allowed by default for a DPoP proof.
+var DPoPAllowedAlgorithms = []string{
+	joseUtils.AlgorithmES256, joseUtils.AlgorithmES384, joseUtils.AlgorithmES512,
+	joseUtils.AlgorithmPS256, joseUtils.AlgorithmPS384, joseUtils.AlgorithmPS512,
+	joseUtils.AlgorithmEdDSA,
+}
+
 var (
 	ErrDPoPInvalid                    = `invalid DPoP token`
 	ErrDPoPMissingRequiredPayloadData = `required "htu", "htm", and/or "iat" data is missing in the DPoP token`
@@ -29,6 +53,12 @@ var (
 	ErrDPoPExpired                    = `the DPoP token is expired`
 	ErrDPoPUnsupportedAlgorithm       = `unsupported algorithm for DPoP token`
 	ErrDPoPUnsupportedKey             = `unsupported JSON Web Key for DPoP token`
+	ErrDPoPReplayed                   = `the DPoP token has already been used`
+	ErrDPoPInvalidType                = `the type of the DPoP token must be "dpop+jwt"`
+	ErrDPoPInvalidSignature           = `invalid signature of the DPoP token`
+	ErrDPoPIssuedInTheFuture          = `the DPoP token is issued in the future`
+
+	ErrInvalidHttpURL = errors.New("invalid HTTP URL")
 )
 
 //	DPoPHeader structure.
@@ -80,71 +110,197 @@ type DPoPPayload struct {
 
 // **FUNCTIONS**
 
-// CheckCompactDPoP returns DataJWT (can be nil) and error message (can be empty) after checking:
-// - mandatory fields exist in the DPoP header ("alg", "jwk", "typ") and are supported.
-// - mandatory fields in the DPoP payload exist and are valid: "jti", "iat" (and not expired), "htm" and "htu" match with the provided ones (optional).
-// Additionally it checks:
-// - the "ath" value which is the SHA-256 [SHS] hash of the ASCII encoding of the access token.
-// - the "nonce" value (the parent function has stored it previously to authorize the request).
+// DPoPVerificationOptions are the options to verify a DPoP proof (a nil value uses the defaults):
+//   - AllowedAlgorithms: the allowed "alg" values (DPoPAllowedAlgorithms if empty). "none" and MAC algorithms are never supported.
+//   - MaxAge: how old the "iat" of the DPoP proof can be (DPoPMaxAge if zero).
+//   - Leeway: the clock skew allowed for the "iat" (DPoPLeeway if zero).
+//   - ReplayCache: if not nil, the "jti" of the verified DPoP proof cannot be used twice with the same key.
+//   - NonceValidator: if not nil, the "nonce" provided by the server is required (see DPoPNonceProvider).
+//   - Now: returns the current time (time.Now if nil).
+type DPoPVerificationOptions struct {
+	AllowedAlgorithms []string
+	MaxAge            time.Duration
+	Leeway            time.Duration
+	ReplayCache       joseUtils.ReplayCache
+	NonceValidator    DPoPNonceValidator
+	Now               func() time.Time
+}
+
+// CheckCompactDPoP returns DataJWT (can be nil) and error message (can be empty) after verifying the DPoP proof
+// with the default options (see VerifyCompactDPoP).
 func CheckCompactDPoP(dpopCompactJWT *string, accessToken, httpMethod, httpURL *string) (*joseUtils.DataJWT, string) {
+	return VerifyCompactDPoP(dpopCompactJWT, accessToken, httpMethod, httpURL, nil)
+}
+
+// CheckCompactDPoPWithReplayCache does the same as CheckCompactDPoP and then, if the replay cache is not nil,
+// it checks the "jti" of the verified DPoP proof has not been used before with the same key.
+func CheckCompactDPoPWithReplayCache(dpopCompactJWT *string, accessToken, httpMethod, httpURL *string, replayCache joseUtils.ReplayCache) (*joseUtils.DataJWT, string) {
+	return VerifyCompactDPoP(dpopCompactJWT, accessToken, httpMethod, httpURL, &DPoPVerificationOptions{ReplayCache: replayCache})
+}
+
+// VerifyCompactDPoP returns DataJWT (can be nil) and error message (can be empty) after checking the DPoP proof
+// as per RFC 9449, section 4.3:
+// - the DPoP header ("typ", "alg" and "jwk") is valid and supported (see CheckDPoPTokenHeaderDataJWT).
+// - the signature is valid for the public key in the "jwk" header.
+// - the DPoP payload is valid (see CheckDPoPTokenPayloadDataJWT), "iat" being in the window of the options.
+// - the "nonce" was provided by the server (if the options have a nonce validator), see IsDPoPNonceError.
+// - the "jti" has not been used before (if the options have a replay cache).
+func VerifyCompactDPoP(dpopCompactJWT *string, accessToken, httpMethod, httpURL *string, options *DPoPVerificationOptions) (*joseUtils.DataJWT, string) {
+	if dpopCompactJWT == nil {
+		return nil, ErrDPoPInvalid
+	}
+
 	dpopDataJWT := joseUtils.GetDataJWT(dpopCompactJWT)
 	if dpopDataJWT == nil {
 		return nil, ErrDPoPInvalid
-	} else {
-		return CheckDataDPoP(dpopDataJWT, accessToken, httpMethod, httpURL)
 	}
 
+	publicJWK, errorHeader := checkDPoPHeader(dpopDataJWT, options)
+	if errorHeader != nil {
+		return nil, *errorHeader
+	}
+
+	// the "kid" header (if any) is not needed to select the key
+	verificationKey := *publicJWK
+	verificationKey.Kid, _ = dpopDataJWT.Header.KeyID()
+	verifiedDataJWT, err := joseUtils.VerifyCompactJWT(*dpopCompactJWT, &jwkUtils.JWKeySet{Keys: []jwkUtils.JWK{verificationKey}})
+	if err != nil {
+		return nil, ErrDPoPInvalidSignature
+	}
+
+	checkedDataJWT, errMsg := checkDataDPoP(verifiedDataJWT, accessToken, httpMethod, httpURL, options)
+	if errMsg != "" {
+		return nil, errMsg
+	}
+
+	// the "jti" is only stored once the DPoP proof is verified, so a forged proof cannot block a legitimate one
+	if options != nil {
+		if errorReplay := checkDPoPReplay(checkedDataJWT, publicJWK, options.ReplayCache, options.getMaxAge()+options.getLeeway()); errorReplay != nil {
+			return nil, *errorReplay
+		}
+	}
+
+	return checkedDataJWT, ""
 }
 
-// CheckDataDPoP returns DataJWT (can be nil) and error message (can be empty) after checking:
+// CheckDataDPoP returns DataJWT (can be nil) and error message (can be empty) after checking with the default options:
 // - mandatory fields exist in the DPoP header ("alg", "jwk", "typ") and are supported.
 // - mandatory fields in the DPoP payload exist and are valid: "jti", "iat" (and not expired), "htm" and "htu" match with the provided ones (optional).
 // Additionally it checks:
 // - the "ath" value which is the SHA-256 [SHS] hash of the ASCII encoding of the access token.
 // - the "nonce" value (the parent function has stored it previously to authorize the request).
+// The signature is not checked, so the DPoP proof must be verified before (see VerifyCompactDPoP).
 func CheckDataDPoP(dpopDataJWT *joseUtils.DataJWT, accessToken, httpMethod, httpURL *string) (*joseUtils.DataJWT, string) {
-	errorHeader := CheckDPoPTokenHeaderDataJWT(dpopDataJWT)
+	return checkDataDPoP(dpopDataJWT, accessToken, httpMethod, httpURL, nil)
+}
+
+// CheckDPoPTokenHeaderDataJWT checks that the mandatory fields exist ("alg", "jwk", "typ") and are supported:
+// "typ" is "dpop+jwt", "alg" is in DPoPAllowedAlgorithms and "jwk" is a public key which can be used with it.
+func CheckDPoPTokenHeaderDataJWT(dpopDataJWT *joseUtils.DataJWT) (errMsg *string) {
+	_, errMsg = checkDPoPHeader(dpopDataJWT, nil)
+	return errMsg
+}
+
+// CheckDPoPTokenPayloadDataJWT checks that the mandatory fields exist ("htm", "htu", "iat", "jti"),
+// "htm" and "htu" match with the provided ones (if any, see NormalizeDPoPHttpURL)
+// and "iat" is not older than DPoPMaxAge (nor in the future), and additionally:
+// - the "ath" value which is the SHA-256 [SHS] hash of the ASCII encoding of the access token.
+// - the "nonce" value (the parent function has stored it previously to authorize the request).
+func CheckDPoPTokenPayloadDataJWT(dpopDataJWT *joseUtils.DataJWT, accessToken, httpMethod, httpURL *string) (errMsg *string) {
+	return checkDPoPPayload(dpopDataJWT, accessToken, httpMethod, httpURL, nil)
+}
+
+// NormalizeDPoPHttpURL returns the URL to compare with the "htu" claim (RFC 9449, section 4.3):
+// the scheme and host in lowercase, without the default port, the user info, the query and the fragment parts.
+func NormalizeDPoPHttpURL(rawURL string) (string, error) {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidHttpURL, err)
+	}
+	if parsedURL.Scheme == "" || parsedURL.Host == "" {
+		return "", fmt.Errorf("%w: it must be an absolute URL", ErrInvalidHttpURL)
+	}
+
+	scheme := strings.ToLower(parsedURL.Scheme)
+	host := strings.ToLower(parsedURL.Hostname())
+	port := parsedURL.Port()
+	if (scheme == "https" && port == "443") || (scheme == "http" && port == "80") {
+		port = ""
+	}
+
+	if port != "" {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]" // IPv6 address
+	}
+
+	path := parsedURL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	return scheme + "://" + host + path, nil
+}
+
+func checkDataDPoP(dpopDataJWT *joseUtils.DataJWT, accessToken, httpMethod, httpURL *string, options *DPoPVerificationOptions) (*joseUtils.DataJWT, string) {
+	_, errorHeader := checkDPoPHeader(dpopDataJWT, options)
 	if errorHeader != nil {
 		return nil, *errorHeader
 	}
 
-	errorPayload := CheckDPoPTokenPayloadDataJWT(dpopDataJWT, accessToken, httpMethod, httpURL)
+	errorPayload := checkDPoPPayload(dpopDataJWT, accessToken, httpMethod, httpURL, options)
 	if errorPayload != nil {
 		return nil, *errorPayload
 	}
 
 	return dpopDataJWT, ""
-
 }
 
-// CheckDPoPTokenHeaderDataJWT checks that the mandatory fields exist ("alg", "jwk", "typ") and are supported.
-func CheckDPoPTokenHeaderDataJWT(dpopDataJWT *joseUtils.DataJWT) (errMsg *string) {
+// checkDPoPHeader returns the public key in the "jwk" header after checking the header.
+func checkDPoPHeader(dpopDataJWT *joseUtils.DataJWT, options *DPoPVerificationOptions) (*jwkUtils.JWK, *string) {
 	if dpopDataJWT == nil {
-		return &ErrDPoPInvalid
+		return nil, &ErrDPoPInvalid
 	}
 
 	headerBytes, _ := json.Marshal(dpopDataJWT.Header)
 	dpopHeader := &DPoPHeader{}
 	err := json.Unmarshal(headerBytes, dpopHeader)
 	if err != nil {
-		return &ErrDPoPInvalid
+		return nil, &ErrDPoPInvalid
 	}
 
 	if dpopHeader.Algorithm == "" {
-		return &joseUtils.ErrMsgInvalidDPoPToken
+		return nil, &joseUtils.ErrMsgInvalidDPoPToken
+	}
+
+	if !strings.EqualFold(dpopHeader.Type, DPoPHeaderType) {
+		return nil, &ErrDPoPInvalidType
+	}
+
+	if !options.isAllowedAlgorithm(dpopHeader.Algorithm) {
+		return nil, &ErrDPoPUnsupportedAlgorithm
+	}
+
+	// the "jwk" must be a public key (never a symmetric or private key) that can be used to verify with the "alg"
+	publicJWK := &dpopHeader.JSONWebKey
+	if publicJWK.Kty == "" || publicJWK.Kty == jwkUtils.KeyTypeOct || publicJWK.HasPrivateMembers() ||
+		publicJWK.CheckKeyOperation(jwkUtils.KeyOperationVerify) != nil {
+		return nil, &ErrDPoPUnsupportedKey
 	}
 
-	// TODO: CheckPublicJWK format
+	err = joseUtils.CheckSigningKey(dpopHeader.Algorithm, publicJWK)
+	if errors.Is(err, joseUtils.ErrUnsupportedAlgorithm) {
+		return nil, &ErrDPoPUnsupportedAlgorithm
+	}
+	if err != nil {
+		return nil, &ErrDPoPUnsupportedKey
+	}
 
 	// done!
-	return nil
+	return publicJWK, nil
 }
 
-// CheckDPoPTokenPayloadDataJWT checks that the mandatory fields exist ("htm", "htu", "iat", "jti"
-// and additionally:
-// - the "ath" value which is the SHA-256 [SHS] hash of the ASCII encoding of the access token.
-// - the "nonce" value (the parent function has stored it previously to authorize the request).
-func CheckDPoPTokenPayloadDataJWT(dpopDataJWT *joseUtils.DataJWT, accessToken, httpMethod, httpURL *string) (errMsg *string) {
+func checkDPoPPayload(dpopDataJWT *joseUtils.DataJWT, accessToken, httpMethod, httpURL *string, options *DPoPVerificationOptions) (errMsg *string) {
 	if dpopDataJWT == nil {
 		return &ErrDPoPInvalid
 	}
@@ -158,6 +314,7 @@ func CheckDPoPTokenPayloadDataJWT(dpopDataJWT *joseUtils.DataJWT, accessToken, h
 
 	if dpopPayload.HttpMethod == "" ||
 		dpopPayload.HttpURL == "" ||
+		dpopPayload.IssuedAt == 0 ||
 		dpopPayload.JSONTokenID == "" {
 		return &ErrDPoPMissingRequiredPayloadData
 	}
@@ -166,8 +323,16 @@ func CheckDPoPTokenPayloadDataJWT(dpopDataJWT *joseUtils.DataJWT, accessToken, h
 		return &ErrDPoPMismatchHttpMethod
 	}
 
-	if httpURL != nil && *httpURL != dpopPayload.HttpURL {
-		return &ErrDPoPMismatchHttpURL
+	if httpURL != nil {
+		expectedURL, err := NormalizeDPoPHttpURL(*httpURL)
+		if err != nil {
+			return &ErrDPoPMismatchHttpURL
+		}
+
+		proofURL, err := NormalizeDPoPHttpURL(dpopPayload.HttpURL)
+		if err != nil || proofURL != expectedURL {
+			return &ErrDPoPMismatchHttpURL
+		}
 	}
 
 	if accessToken != nil {
@@ -182,14 +347,104 @@ func CheckDPoPTokenPayloadDataJWT(dpopDataJWT *joseUtils.DataJWT, accessToken, h
 		}
 	}
 
-	expired := joseUtils.CheckNotExpiredDateEpochUNIX(dpopPayload.IssuedAt)
-	if expired {
+	if options != nil && options.NonceValidator != nil {
+		if dpopPayload.Nonce == "" {
+			return &ErrDPoPNonceMissing
+		}
+		if !options.NonceValidator.ValidNonce(dpopPayload.Nonce) {
+			return &ErrDPoPInvalidNonce
+		}
+	}
+
+	now := options.now()
+	issuedAt := time.Unix(dpopPayload.IssuedAt, 0)
+	if issuedAt.After(now.Add(options.getLeeway())) {
+		return &ErrDPoPIssuedInTheFuture
+	}
+	if now.Sub(issuedAt) > options.getMaxAge()+options.getLeeway() {
 		return &ErrDPoPExpired
 	}
 
 	// done!
 	return nil
+}
+
+// checkDPoPReplay stores the "jti" of the verified DPoP proof in the replay cache until "iat" plus the window
+// and returns ErrDPoPReplayed if it was already stored. The "jti" is scoped by the "jkt" of the DPoP key,
+// so the proofs of different clients cannot collide.
+func checkDPoPReplay(dpopDataJWT *joseUtils.DataJWT, publicJWK *jwkUtils.JWK, replayCache joseUtils.ReplayCache, window time.Duration) (errMsg *string) {
+	if replayCache == nil {
+		return nil
+	}
+	if dpopDataJWT == nil {
+		return &ErrDPoPInvalid
+	}
+
+	payloadBytes, _ := json.Marshal(dpopDataJWT.Payload)
+	dpopPayload := &DPoPPayload{}
+	err := json.Unmarshal(payloadBytes, dpopPayload)
+	if err != nil {
+		return &ErrDPoPInvalid
+	}
+
+	if dpopPayload.JSONTokenID == "" {
+		return &ErrDPoPMissingRequiredPayloadData
+	}
+
+	thumbprint, err := jwkUtils.CalculateThumbprint(publicJWK, crypto.SHA256)
+	if err != nil {
+		return &ErrDPoPUnsupportedKey
+	}
+
+	expiresAt := joseUtils.GetReplayExpiration(0, dpopPayload.IssuedAt, window)
+	err = replayCache.CheckAndStore(DPoPHeaderType+":"+thumbprint+":"+dpopPayload.JSONTokenID, expiresAt)
+	if errors.Is(err, joseUtils.ErrReplayDetected) {
+		return &ErrDPoPReplayed
+	}
+	if err != nil {
+		return &ErrDPoPInvalid
+	}
+
+	// done!
+	return nil
+}
+
+func (options *DPoPVerificationOptions) isAllowedAlgorithm(alg string) bool {
+	allowedAlgorithms := DPoPAllowedAlgorithms
+	if options != nil && len(options.AllowedAlgorithms) > 0 {
+		allowedAlgorithms = options.AllowedAlgorithms
+	}
+
+	if alg == joseUtils.AlgorithmNone || strings.HasPrefix(alg, "HS") {
+		return false
+	}
+	for _, allowedAlgorithm := range allowedAlgorithms {
+		if alg == allowedAlgorithm {
+			return true
+		}
+	}
+	return false
+}
 
+func (options *DPoPVerificationOptions) getMaxAge() time.Duration {
+	if options == nil || options.MaxAge == 0 {
+		return DPoPMaxAge
+	}
+	return options.MaxAge
+}
+
+func (options *DPoPVerificationOptions) getLeeway() time.Duration {
+	if options == nil || options.Leeway == 0 {
+		return DPoPLeeway
+	}
+	return options.Leeway
+}
+
+func (options *DPoPVerificationOptions) now() time.Time {
+	if options == nil || options.Now == nil {
+		return time.Now()
+	}
+	return options.Now()
 }
 
 // A DPoP proof MAY contain other JOSE header parameters or claims as
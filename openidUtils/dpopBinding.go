@@ -0,0 +1,160 @@
+package openidUtils
+
+import (
+	"crypto"
+	"encoding/json"
+	"strings"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/joseUtils"
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+)
+
+// TokenTypeDPoP is the "token_type" of a DPoP-bound access token in the token response
+// and the scheme of the "Authorization" and "WWW-Authenticate" headers (RFC 9449, sections 5 and 7.1).
+const TokenTypeDPoP = "DPoP"
+
+var (
+	ErrDPoPKeyBindingMissing = `the access token is not bound to a DPoP key`
+	ErrDPoPInvalidKeyBinding = `invalid DPoP key binding`
+
+	// error codes for the "WWW-Authenticate" challenge (RFC 9449, sections 7.1 and 12.2)
+	ErrOpenidInvalidDPoPProof = "invalid_dpop_proof"
+	ErrOpenidUseDPoPNonce     = "use_dpop_nonce"
+)
+
+// Confirmation is the "cnf" claim (RFC 7800) of a sender-constrained access token.
+//   - "jkt": the JWK SHA-256 Thumbprint (RFC 7638) of the DPoP public key, base64url encoded (RFC 9449, section 6.1).
+type Confirmation struct {
+	JWKThumbprint string `json:"jkt,omitempty" bson:"jkt,omitempty"`
+}
+
+// DPoPChallenge has the parameters of the "WWW-Authenticate" challenge for the DPoP scheme (RFC 9449, section 7.1),
+// e.g.: DPoP error="invalid_token", error_description="Invalid DPoP key binding", algs="ES256"
+//   - Realm and Scope: OPTIONAL, as per [RFC6750], section 3.
+//   - Error: OPTIONAL, e.g.: "invalid_token", "invalid_dpop_proof" or "use_dpop_nonce".
+//   - ErrorDescription: OPTIONAL, a human-readable description of the error.
+//   - Algorithms: the "algs" supported for the DPoP proofs (space-delimited).
+type DPoPChallenge struct {
+	Realm            string
+	Scope            string
+	Error            string
+	ErrorDescription string
+	Algorithms       []string
+}
+
+// String returns the value of the "WWW-Authenticate" header (the parameters are only added if they are not empty).
+func (challenge DPoPChallenge) String() string {
+	var parameters []string
+	addParameter := func(name, value string) {
+		if value != "" {
+			parameters = append(parameters, name+"="+quoteChallengeValue(value))
+		}
+	}
+
+	addParameter("realm", challenge.Realm)
+	addParameter("scope", challenge.Scope)
+	addParameter("error", challenge.Error)
+	addParameter("error_description", challenge.ErrorDescription)
+	addParameter("algs", strings.Join(challenge.Algorithms, " "))
+
+	if len(parameters) == 0 {
+		return TokenTypeDPoP
+	}
+	return TokenTypeDPoP + " " + strings.Join(parameters, ", ")
+}
+
+// NewDPoPChallenge returns the "WWW-Authenticate" challenge with the error (if any) and the DPoPAllowedAlgorithms.
+func NewDPoPChallenge(errorCode, errorDescription string) DPoPChallenge {
+	return DPoPChallenge{
+		Error:            errorCode,
+		ErrorDescription: errorDescription,
+		Algorithms:       DPoPAllowedAlgorithms,
+	}
+}
+
+// GetDPoPKeyThumbprint returns the "jkt" (JWK SHA-256 Thumbprint) of the public key in the "jwk" header of the DPoP proof
+// and error message (can be empty). The DPoP proof must be verified before (see VerifyCompactDPoP).
+func GetDPoPKeyThumbprint(dpopDataJWT *joseUtils.DataJWT) (string, string) {
+	if dpopDataJWT == nil {
+		return "", ErrDPoPInvalid
+	}
+
+	headerBytes, _ := json.Marshal(dpopDataJWT.Header)
+	dpopHeader := &DPoPHeader{}
+	err := json.Unmarshal(headerBytes, dpopHeader)
+	if err != nil {
+		return "", ErrDPoPInvalid
+	}
+
+	if dpopHeader.JSONWebKey.Kty == "" || dpopHeader.JSONWebKey.HasPrivateMembers() {
+		return "", ErrDPoPUnsupportedKey
+	}
+
+	thumbprint, err := jwkUtils.CalculateThumbprint(&dpopHeader.JSONWebKey, crypto.SHA256)
+	if err != nil {
+		return "", ErrDPoPUnsupportedKey
+	}
+
+	return thumbprint, ""
+}
+
+// BindToDPoPProof sets the "cnf" claim of the access token with the "jkt" of the key of the (verified) DPoP proof
+// and returns error message (can be empty). The token response must have the "DPoP" token type (see CreateResponseDPoPToken).
+func (payload *AccessTokenPayload) BindToDPoPProof(dpopDataJWT *joseUtils.DataJWT) string {
+	thumbprint, errMsg := GetDPoPKeyThumbprint(dpopDataJWT)
+	if errMsg != "" {
+		return errMsg
+	}
+
+	payload.Confirmation = &Confirmation{JWKThumbprint: thumbprint}
+	return ""
+}
+
+// CreateResponseDPoPToken returns the standardized OAuth2 properties for a DPoP-bound access token ("token_type" is "DPoP").
+var CreateResponseDPoPToken = func(accessToken, idToken, scope *string, expiration int) (*ResponseOauthAccessToken, error) {
+	responseOAuthAccessToken, err := CreateResponseOAuthToken(accessToken, idToken, scope, expiration)
+	if err != nil {
+		return nil, err
+	}
+
+	responseOAuthAccessToken.TokenType = TokenTypeDPoP
+	return responseOAuthAccessToken, nil
+}
+
+// checkDPoPKeyBinding returns error message (can be empty) after checking the DPoP proof for the access token
+// and that the access token is bound to the key of the DPoP proof.
+func checkDPoPKeyBinding(accessTokenDataJWT *joseUtils.DataJWT, compactJWT, dpopCompactJWT *string, httpMethod, httpURL *string, dpopOptions *DPoPVerificationOptions) string {
+	confirmedThumbprint := getConfirmationThumbprint(accessTokenDataJWT)
+	if confirmedThumbprint == "" {
+		return ErrDPoPKeyBindingMissing
+	}
+
+	dpopDataJWT, errMsg := VerifyCompactDPoP(dpopCompactJWT, compactJWT, httpMethod, httpURL, dpopOptions)
+	if errMsg != "" {
+		return errMsg
+	}
+
+	thumbprint, errMsg := GetDPoPKeyThumbprint(dpopDataJWT)
+	if errMsg != "" {
+		return errMsg
+	}
+	if thumbprint != confirmedThumbprint {
+		return ErrDPoPInvalidKeyBinding
+	}
+
+	return ""
+}
+
+// getConfirmationThumbprint returns the "jkt" in the "cnf" claim of the access token (empty if it does not exist).
+func getConfirmationThumbprint(accessTokenDataJWT *joseUtils.DataJWT) string {
+	confirmation, _ := accessTokenDataJWT.Payload["cnf"].(map[string]interface{})
+	thumbprint, _ := confirmation["jkt"].(string)
+	return thumbprint
+}
+
+// quoteChallengeValue returns the quoted-string of an auth-param value (RFC 9110, section 5.6.4).
+func quoteChallengeValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return `"` + value + `"`
+}
@@ -0,0 +1,103 @@
+package openidUtils
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/joseUtils"
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func calculateTestAccessTokenHash(accessToken string) string {
+	accessTokenHash := sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(accessTokenHash[:])
+}
+
+func TestBindToDPoPProof(t *testing.T) {
+	issuerKey, err := jwkUtils.GenerateJWK(joseUtils.AlgorithmES256, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+	dpopKey, err := jwkUtils.GenerateJWK(joseUtils.AlgorithmES256, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+
+	// the authorization server binds the access token to the key of the DPoP proof in the token request
+	tokenRequestDPoP := joseUtils.GetDataJWT(newTestCompactDPoP(t, dpopKey, nil, newTestDPoPPayload(time.Now())))
+	now := time.Now().Unix()
+	accessTokenPayload := &AccessTokenPayload{
+		Issuer:      "did:example:issuer",
+		Subject:     "did:example:alice",
+		Audience:    testAccessTokenAudience,
+		ClientID:    testAccessTokenClientID,
+		Expiry:      now + 300,
+		IssuedAt:    now,
+		JSONTokenID: "at-1",
+	}
+	require.Empty(t, accessTokenPayload.BindToDPoPProof(tokenRequestDPoP))
+
+	expectedThumbprint, err := jwkUtils.CalculateThumbprint(dpopKey, crypto.SHA256)
+	require.NoError(t, err)
+	assert.Equal(t, expectedThumbprint, accessTokenPayload.Confirmation.JWKThumbprint)
+
+	claims := map[string]interface{}{
+		"iss":       accessTokenPayload.Issuer,
+		"sub":       accessTokenPayload.Subject,
+		"aud":       accessTokenPayload.Audience,
+		"client_id": accessTokenPayload.ClientID,
+		"exp":       accessTokenPayload.Expiry,
+		"iat":       accessTokenPayload.IssuedAt,
+		"nbf":       accessTokenPayload.IssuedAt,
+		"jti":       accessTokenPayload.JSONTokenID,
+		"cnf":       accessTokenPayload.Confirmation,
+	}
+	accessToken, err := joseUtils.SignCompactJWT(joseUtils.Headers{"typ": AccessTokenHeaderType}, claims, issuerKey)
+	require.NoError(t, err)
+
+	// the resource server requires a DPoP proof of the same key for the access token
+	payload := newTestDPoPPayload(time.Now())
+	payload["ath"] = calculateTestAccessTokenHash(accessToken)
+	httpMethod, httpURL := testDPoPHttpMethod, testDPoPHttpURL
+	resolveKey := NewJWKeySetAccessTokenKeyResolver(jwkUtils.CreateJWKeySet(&[]jwkUtils.JWK{jwkUtils.ExportPublicJWK(issuerKey)}))
+
+	dpopProof := newTestCompactDPoP(t, dpopKey, nil, payload)
+	dataJWT, err := VerifyCompactDPoPAccessToken(&accessToken, dpopProof, &httpMethod, &httpURL, resolveKey, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "at-1", dataJWT.Payload["jti"])
+
+	otherKey, err := jwkUtils.GenerateJWK(joseUtils.AlgorithmES256, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+	otherProof := newTestCompactDPoP(t, otherKey, nil, payload)
+	_, err = VerifyCompactDPoPAccessToken(&accessToken, otherProof, &httpMethod, &httpURL, resolveKey, nil, nil)
+	requireAccessTokenError(t, err, ErrDPoPInvalidKeyBinding, "cnf")
+
+	// a bearer access token (without "cnf") is rejected
+	delete(claims, "cnf")
+	bearerToken, err := joseUtils.SignCompactJWT(joseUtils.Headers{"typ": AccessTokenHeaderType}, claims, issuerKey)
+	require.NoError(t, err)
+	_, err = VerifyCompactDPoPAccessToken(&bearerToken, dpopProof, &httpMethod, &httpURL, resolveKey, nil, nil)
+	requireAccessTokenError(t, err, ErrDPoPKeyBindingMissing, "cnf")
+}
+
+func TestDPoPChallenge(t *testing.T) {
+	assert.Equal(t, `DPoP algs="ES256 PS256"`, DPoPChallenge{Algorithms: []string{"ES256", "PS256"}}.String())
+
+	challenge := DPoPChallenge{
+		Error:            ErrOpenidInvalidToken,
+		ErrorDescription: `Invalid "DPoP" key binding`,
+		Algorithms:       []string{"ES256"},
+	}
+	assert.Equal(t, `DPoP error="invalid_token", error_description="Invalid \"DPoP\" key binding", algs="ES256"`, challenge.String())
+
+	assert.Equal(t, "DPoP", DPoPChallenge{}.String())
+	assert.Contains(t, NewDPoPChallenge(ErrOpenidUseDPoPNonce, "").String(), `DPoP error="use_dpop_nonce", algs="ES256 `)
+}
+
+func TestCreateResponseDPoPToken(t *testing.T) {
+	accessToken, scope := "Kz~8mXK1EalYznwH-LC-1fBAo.4Ljp~zsPE_NeO.gxU", "openid"
+	response, err := CreateResponseDPoPToken(&accessToken, nil, &scope, 300)
+	require.NoError(t, err)
+	assert.Equal(t, TokenTypeDPoP, response.TokenType)
+}
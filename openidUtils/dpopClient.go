@@ -0,0 +1,235 @@
+package openidUtils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/httpUtils"
+	"github.com/Universal-Health-Chain/common-utils-golang/joseUtils"
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+	"github.com/google/uuid"
+)
+
+var ErrDPoPProofKey = errors.New("the DPoP proof requires a private key")
+
+// NewDPoPProof returns a DPoP proof (a compact "dpop+jwt") signed with the private key, which public key is in the "jwk" header.
+// The payload has a fresh "jti", the current "iat", "htm" and "htu" (without the query and fragment parts) and optionally:
+// - "ath": the SHA-256 hash of the access token, base64url encoded (if the access token is not empty).
+// - "nonce": the last value of the "DPoP-Nonce" HTTP header provided by the server (if not empty).
+func NewDPoPProof(key *jwkUtils.JWK, htm, htu, accessToken, nonce string) (string, error) {
+	if key == nil || !key.HasPrivateMembers() {
+		return "", ErrDPoPProofKey
+	}
+
+	publicJWK := jwkUtils.ExportPublicJWK(key)
+	return newDPoPProof(&publicJWK, htm, htu, accessToken, nonce, func(headers joseUtils.Headers, payload DPoPPayload) (string, error) {
+		return joseUtils.SignCompactJWT(headers, payload, key)
+	})
+}
+
+// NewDPoPProofWithKeyManager is the same as NewDPoPProof but the DPoP proof is signed by the key manager.
+func NewDPoPProofWithKeyManager(keyManager joseUtils.KeyManager, kid, htm, htu, accessToken, nonce string) (string, error) {
+	if keyManager == nil {
+		return "", ErrDPoPProofKey
+	}
+
+	publicJWK, err := keyManager.PublicJWK(kid)
+	if err != nil {
+		return "", err
+	}
+
+	return newDPoPProof(publicJWK, htm, htu, accessToken, nonce, func(headers joseUtils.Headers, payload DPoPPayload) (string, error) {
+		return joseUtils.SignCompactJWTWithKeyManager(headers, payload, keyManager, kid)
+	})
+}
+
+// newDPoPProof creates the headers and the payload of the DPoP proof and signs it.
+func newDPoPProof(publicJWK *jwkUtils.JWK, htm, htu, accessToken, nonce string, sign func(headers joseUtils.Headers, payload DPoPPayload) (string, error)) (string, error) {
+	httpURL, err := NormalizeDPoPHttpURL(htu)
+	if err != nil {
+		return "", err
+	}
+
+	headers := joseUtils.Headers{
+		joseUtils.HeaderType: DPoPHeaderType,
+		"jwk":                publicJWK,
+	}
+
+	randomUUID, err := uuid.NewRandom()
+	if err != nil {
+		return "", err
+	}
+
+	payload := DPoPPayload{
+		HttpMethod:  htm,
+		HttpURL:     httpURL,
+		IssuedAt:    time.Now().Unix(),
+		JSONTokenID: randomUUID.String(),
+		Nonce:       nonce,
+	}
+
+	if accessToken != "" {
+		accessTokenHashBytes := sha256.Sum256([]byte(accessToken))
+		payload.AccessTokenHash = base64.RawURLEncoding.EncodeToString(accessTokenHashBytes[:])
+	}
+
+	return sign(headers, payload)
+}
+
+// DPoPTransport is an http.RoundTripper which attaches a new DPoP proof to every request (the "DPoP" HTTP header)
+// and, if there is an access token, the "Authorization: DPoP <access token>" HTTP header.
+// It remembers the last "DPoP-Nonce" received and it retries the request once when the server responds
+// with the "use_dpop_nonce" error and a nonce which is not the one in the DPoP proof of the request (RFC 9449, sections 8 and 9).
+//   - Base: the transport used to send the requests (http.DefaultTransport if nil).
+//   - Key: the private key of the client to sign the DPoP proofs.
+//   - AccessToken: the DPoP-bound access token (empty for the requests to the token endpoint).
+//   - KeyManager and KeyID: the key manager and the "kid" of the client key, used instead of Key if KeyManager is not nil.
+type DPoPTransport struct {
+	Base        http.RoundTripper
+	Key         *jwkUtils.JWK
+	AccessToken string
+
+	// the client key in a key manager (e.g.: a KMS or a HSM)
+	KeyManager joseUtils.KeyManager
+	KeyID      string
+
+	mutex sync.Mutex
+	nonce string
+}
+
+// NewDPoPTransport returns a DPoPTransport using the http.DefaultTransport.
+func NewDPoPTransport(key *jwkUtils.JWK, accessToken string) *DPoPTransport {
+	return &DPoPTransport{
+		Key:         key,
+		AccessToken: accessToken,
+	}
+}
+
+// NewDPoPTransportWithKeyManager returns a DPoPTransport using the http.DefaultTransport and the key of the key manager.
+func NewDPoPTransportWithKeyManager(keyManager joseUtils.KeyManager, kid, accessToken string) *DPoPTransport {
+	return &DPoPTransport{
+		AccessToken: accessToken,
+		KeyManager:  keyManager,
+		KeyID:       kid,
+	}
+}
+
+// RoundTrip sends the request (a copy of it with the DPoP headers) and retries it once with the nonce if required.
+// The request can only be retried if it has no body or its body can be read again (http.Request.GetBody).
+func (transport *DPoPTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	response, usedNonce, err := transport.sendRequest(request)
+	if err != nil {
+		return response, err
+	}
+
+	// the nonce stored by another request can be the one required, so it is compared with the nonce of this request
+	nonce := transport.updateNonce(response)
+	if nonce == "" || nonce == usedNonce || !isUseDPoPNonceResponse(response) {
+		return response, nil
+	}
+
+	if request.Body != nil && request.GetBody == nil {
+		return response, nil
+	}
+
+	_, _ = io.Copy(io.Discard, response.Body)
+	_ = response.Body.Close()
+	response, _, err = transport.sendRequest(request)
+	return response, err
+}
+
+// Nonce returns the last "DPoP-Nonce" received from the server.
+func (transport *DPoPTransport) Nonce() string {
+	transport.mutex.Lock()
+	defer transport.mutex.Unlock()
+
+	return transport.nonce
+}
+
+// sendRequest sends the request with a new DPoP proof and returns the response and the nonce used in the DPoP proof.
+func (transport *DPoPTransport) sendRequest(request *http.Request) (*http.Response, string, error) {
+	dpopRequest := request.Clone(request.Context())
+	if request.Body != nil && request.GetBody != nil {
+		body, err := request.GetBody()
+		if err != nil {
+			return nil, "", err
+		}
+		dpopRequest.Body = body
+	}
+
+	nonce := transport.Nonce()
+	dpopProof, err := transport.newDPoPProof(request, nonce)
+	if err != nil {
+		return nil, "", err
+	}
+
+	dpopRequest.Header.Set(httpUtils.HeaderEnumDPoP, dpopProof)
+	if transport.AccessToken != "" {
+		dpopRequest.Header.Set(httpUtils.HeaderEnumAuthorization, TokenTypeDPoP+" "+transport.AccessToken)
+	}
+
+	base := transport.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	response, err := base.RoundTrip(dpopRequest)
+	return response, nonce, err
+}
+
+// newDPoPProof returns the DPoP proof of the request with the nonce, signed with the Key or the key manager.
+func (transport *DPoPTransport) newDPoPProof(request *http.Request, nonce string) (string, error) {
+	if transport.KeyManager != nil {
+		return NewDPoPProofWithKeyManager(transport.KeyManager, transport.KeyID, request.Method, request.URL.String(), transport.AccessToken, nonce)
+	}
+	return NewDPoPProof(transport.Key, request.Method, request.URL.String(), transport.AccessToken, nonce)
+}
+
+// updateNonce stores the "DPoP-Nonce" of the response (if any) and returns it.
+func (transport *DPoPTransport) updateNonce(response *http.Response) string {
+	nonce := httpUtils.GetDPoPNonceHeader(response.Header)
+	if nonce == "" {
+		return ""
+	}
+
+	transport.mutex.Lock()
+	defer transport.mutex.Unlock()
+
+	transport.nonce = nonce
+	return nonce
+}
+
+// isUseDPoPNonceResponse reports whether the response has the "use_dpop_nonce" error:
+// in the "WWW-Authenticate" challenge of a resource server or in the JSON body of an authorization server.
+// The body is read and restored to check the error.
+func isUseDPoPNonceResponse(response *http.Response) bool {
+	switch response.StatusCode {
+	case http.StatusUnauthorized:
+		return strings.Contains(response.Header.Get(httpUtils.HeaderEnumWWWAuthenticate), `error="`+ErrOpenidUseDPoPNonce+`"`)
+	case http.StatusBadRequest:
+		if response.Body == nil {
+			return false
+		}
+
+		bodyBytes, err := io.ReadAll(response.Body)
+		_ = response.Body.Close()
+		response.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+		if err != nil {
+			return false
+		}
+
+		errorResponse := struct {
+			Error string `json:"error"`
+		}{}
+		return json.Unmarshal(bodyBytes, &errorResponse) == nil && errorResponse.Error == ErrOpenidUseDPoPNonce
+	default:
+		return false
+	}
+}
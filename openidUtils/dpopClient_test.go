@@ -0,0 +1,174 @@
+package openidUtils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/httpUtils"
+	"github.com/Universal-Health-Chain/common-utils-golang/joseUtils"
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDPoPProof(t *testing.T) {
+	key, err := jwkUtils.GenerateJWK(joseUtils.AlgorithmES256, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+
+	accessToken, httpMethod, httpURL := testDPoPAccessToken, testDPoPHttpMethod, testDPoPHttpURL
+	compactDPoP, err := NewDPoPProof(key, httpMethod, httpURL+"?query=1", accessToken, "nonce-value")
+	require.NoError(t, err)
+
+	dataJWT, errMsg := VerifyCompactDPoP(&compactDPoP, &accessToken, &httpMethod, &httpURL, nil)
+	require.Empty(t, errMsg)
+	assert.Equal(t, DPoPHeaderType, dataJWT.Header["typ"])
+	assert.Equal(t, testDPoPHttpURL, dataJWT.Payload["htu"])
+	assert.Equal(t, calculateTestAccessTokenHash(accessToken), dataJWT.Payload["ath"])
+	assert.Equal(t, "nonce-value", dataJWT.Payload["nonce"])
+	assert.NotEmpty(t, dataJWT.Payload["jti"])
+
+	// every proof has a fresh "jti"
+	otherCompactDPoP, err := NewDPoPProof(key, httpMethod, httpURL, "", "")
+	require.NoError(t, err)
+	otherDataJWT, errMsg := VerifyCompactDPoP(&otherCompactDPoP, nil, &httpMethod, &httpURL, nil)
+	require.Empty(t, errMsg)
+	assert.NotEqual(t, dataJWT.Payload["jti"], otherDataJWT.Payload["jti"])
+	assert.NotContains(t, otherDataJWT.Payload, "ath")
+	assert.NotContains(t, otherDataJWT.Payload, "nonce")
+
+	publicJWK := jwkUtils.ExportPublicJWK(key)
+	_, err = NewDPoPProof(&publicJWK, httpMethod, httpURL, "", "")
+	assert.ErrorIs(t, err, ErrDPoPProofKey)
+
+	_, err = NewDPoPProof(key, httpMethod, "/token", "", "")
+	assert.ErrorIs(t, err, ErrInvalidHttpURL)
+}
+
+func TestNewDPoPProofWithKeyManager(t *testing.T) {
+	keyManager := joseUtils.NewInMemoryKeyManager()
+	kid, err := keyManager.Create(joseUtils.AlgorithmES256, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+
+	accessToken, httpMethod, httpURL := testDPoPAccessToken, testDPoPHttpMethod, testDPoPHttpURL
+	compactDPoP, err := NewDPoPProofWithKeyManager(keyManager, kid, httpMethod, httpURL, accessToken, "")
+	require.NoError(t, err)
+
+	dataJWT, errMsg := VerifyCompactDPoP(&compactDPoP, &accessToken, &httpMethod, &httpURL, nil)
+	require.Empty(t, errMsg)
+	publicJWK, err := keyManager.PublicJWK(kid)
+	require.NoError(t, err)
+	assert.Equal(t, publicJWK.Kid, dataJWT.Header["kid"])
+	assert.Equal(t, calculateTestAccessTokenHash(accessToken), dataJWT.Payload["ath"])
+
+	_, err = NewDPoPProofWithKeyManager(keyManager, "unknown", httpMethod, httpURL, "", "")
+	assert.ErrorIs(t, err, joseUtils.ErrKeyManagerKeyNotFound)
+	_, err = NewDPoPProofWithKeyManager(nil, kid, httpMethod, httpURL, "", "")
+	assert.ErrorIs(t, err, ErrDPoPProofKey)
+}
+
+func TestDPoPTransport(t *testing.T) {
+	key, err := jwkUtils.GenerateJWK(joseUtils.AlgorithmES256, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+	nonceProvider, err := NewDPoPNonceProvider(testNonceKey, 0)
+	require.NoError(t, err)
+
+	for name, wwwAuthenticate := range map[string]string{
+		"authorization server": "",
+		"resource server":      NewDPoPChallenge(ErrOpenidUseDPoPNonce, "").String(),
+	} {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			accessToken := strings.TrimPrefix(r.Header.Get(httpUtils.HeaderEnumAuthorization), TokenTypeDPoP+" ")
+			compactDPoP := r.Header.Get(httpUtils.HeaderEnumDPoP)
+			httpURL := "http://" + r.Host + r.URL.Path
+
+			options := &DPoPVerificationOptions{NonceValidator: nonceProvider}
+			_, errMsg := VerifyCompactDPoP(&compactDPoP, &accessToken, &r.Method, &httpURL, options)
+			if IsDPoPNonceError(errMsg) {
+				httpUtils.HttpResponseUseDPoPNonce(w, nonceProvider.NewNonce(), wwwAuthenticate, errMsg)
+				return
+			}
+			if errMsg != "" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		transport := NewDPoPTransport(key, testDPoPAccessToken)
+		client := &http.Client{Transport: transport}
+
+		response, err := client.Post(server.URL+"/resource", "text/plain", strings.NewReader("body"))
+		require.NoError(t, err, name)
+		_ = response.Body.Close()
+		assert.Equal(t, http.StatusOK, response.StatusCode, name)
+		assert.Equal(t, 2, requests, name)
+		assert.Equal(t, nonceProvider.NewNonce(), transport.Nonce(), name)
+
+		// the next requests use the stored nonce
+		response, err = client.Get(server.URL + "/resource")
+		require.NoError(t, err, name)
+		_ = response.Body.Close()
+		assert.Equal(t, http.StatusOK, response.StatusCode, name)
+		assert.Equal(t, 3, requests, name)
+
+		server.Close()
+	}
+}
+
+func TestDPoPTransport_RetryOnce(t *testing.T) {
+	key, err := jwkUtils.GenerateJWK(joseUtils.AlgorithmEdDSA, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+
+	// the server always requires a new nonce
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		httpUtils.HttpResponseUseDPoPNonce(w, "nonce-"+string(rune('0'+requests)), "", "")
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewDPoPTransport(key, "")}
+	response, err := client.Get(server.URL)
+	require.NoError(t, err)
+	_ = response.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	assert.Equal(t, 2, requests)
+}
+
+func TestDPoPTransport_RetryWithNonceStoredByAnotherRequest(t *testing.T) {
+	key, err := jwkUtils.GenerateJWK(joseUtils.AlgorithmES256, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+	transport := NewDPoPTransport(key, "")
+
+	// another request stores the new nonce before the response to this request is received
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		compactDPoP := r.Header.Get(httpUtils.HeaderEnumDPoP)
+		dataJWT, errMsg := VerifyCompactDPoP(&compactDPoP, nil, nil, nil, nil)
+		if !assert.Empty(t, errMsg) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if dataJWT.Payload["nonce"] != "nonce-1" {
+			transport.mutex.Lock()
+			transport.nonce = "nonce-1"
+			transport.mutex.Unlock()
+			httpUtils.HttpResponseUseDPoPNonce(w, "nonce-1", "", "")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: transport}
+	response, err := client.Get(server.URL)
+	require.NoError(t, err)
+	_ = response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, 2, requests)
+}
@@ -0,0 +1,100 @@
+package openidUtils
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/contentUtils"
+)
+
+// DPoPNonceWindow is the default rotation window of the server-provided nonces (RFC 9449, section 8).
+const DPoPNonceWindow = 5 * time.Minute
+
+const (
+	dpopNonceMinKeyLength = 32
+	dpopNonceWindowLength = 8 // the window index is an unsigned 64-bit big-endian integer
+	dpopNonceMACContext   = "dpop-nonce:"
+)
+
+var (
+	ErrDPoPNonceMissing = `the DPoP token has no "nonce" provided by the server`
+	ErrDPoPInvalidNonce = `invalid or expired "nonce" in the DPoP token`
+
+	ErrInvalidNonceKey = errors.New("the nonce key must have at least 32 bytes")
+)
+
+// DPoPNonceValidator checks the "nonce" of a DPoP proof (see DPoPVerificationOptions).
+type DPoPNonceValidator interface {
+	ValidNonce(nonce string) bool
+}
+
+// DPoPNonceProvider issues stateless nonces for the "DPoP-Nonce" HTTP header, so several instances of the
+// authorization server or resource server can check them by sharing the same key and window.
+// A nonce is the base64url encoding of the current window index and its MAC (see contentUtils.ComputeMAC),
+// so it changes every window and it is valid for the current and the previous windows.
+type DPoPNonceProvider struct {
+	key    []byte
+	window time.Duration
+	now    func() time.Time
+}
+
+// NewDPoPNonceProvider returns a nonce provider for the secret key (at least 32 bytes)
+// and the rotation window (DPoPNonceWindow if zero).
+func NewDPoPNonceProvider(key []byte, window time.Duration) (*DPoPNonceProvider, error) {
+	if len(key) < dpopNonceMinKeyLength {
+		return nil, ErrInvalidNonceKey
+	}
+
+	if window <= 0 {
+		window = DPoPNonceWindow
+	}
+
+	return &DPoPNonceProvider{
+		key:    append([]byte{}, key...),
+		window: window,
+		now:    time.Now,
+	}, nil
+}
+
+// NewNonce returns the nonce of the current window, to be sent in the "DPoP-Nonce" HTTP header.
+func (provider *DPoPNonceProvider) NewNonce() string {
+	return provider.getNonce(provider.getWindowIndex())
+}
+
+// ValidNonce reports whether the nonce was issued in the current or the previous window.
+func (provider *DPoPNonceProvider) ValidNonce(nonce string) bool {
+	nonceBytes, err := base64.RawURLEncoding.DecodeString(nonce)
+	if err != nil || len(nonceBytes) <= dpopNonceWindowLength {
+		return false
+	}
+
+	windowIndex := binary.BigEndian.Uint64(nonceBytes[:dpopNonceWindowLength])
+	currentWindowIndex := provider.getWindowIndex()
+	if windowIndex != currentWindowIndex && windowIndex+1 != currentWindowIndex {
+		return false
+	}
+
+	expectedNonceBytes, _ := base64.RawURLEncoding.DecodeString(provider.getNonce(windowIndex))
+	return hmac.Equal(nonceBytes, expectedNonceBytes)
+}
+
+func (provider *DPoPNonceProvider) getNonce(windowIndex uint64) string {
+	windowBytes := make([]byte, dpopNonceWindowLength)
+	binary.BigEndian.PutUint64(windowBytes, windowIndex)
+
+	mac := contentUtils.ComputeMAC(append([]byte(dpopNonceMACContext), windowBytes...), provider.key)
+	return base64.RawURLEncoding.EncodeToString(append(windowBytes, mac...))
+}
+
+func (provider *DPoPNonceProvider) getWindowIndex() uint64 {
+	return uint64(provider.now().UnixNano() / int64(provider.window))
+}
+
+// IsDPoPNonceError reports whether the error message of the DPoP checker means that the client has to retry
+// with the nonce of the "DPoP-Nonce" HTTP header, so the server responds with the "use_dpop_nonce" error.
+func IsDPoPNonceError(errMsg string) bool {
+	return errMsg == ErrDPoPNonceMissing || errMsg == ErrDPoPInvalidNonce
+}
@@ -0,0 +1,67 @@
+package openidUtils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/joseUtils"
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testNonceKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestDPoPNonceProvider(t *testing.T) {
+	provider, err := NewDPoPNonceProvider(testNonceKey, time.Minute)
+	require.NoError(t, err)
+
+	now := time.Unix(1700000000, 0)
+	provider.now = func() time.Time { return now }
+
+	nonce := provider.NewNonce()
+	assert.True(t, provider.ValidNonce(nonce))
+	assert.Equal(t, nonce, provider.NewNonce())
+
+	// the nonce is rotated every window, but the previous one is still valid
+	now = now.Add(time.Minute)
+	assert.NotEqual(t, nonce, provider.NewNonce())
+	assert.True(t, provider.ValidNonce(nonce))
+
+	now = now.Add(time.Minute)
+	assert.False(t, provider.ValidNonce(nonce))
+
+	// a nonce of another key or a tampered one is not valid
+	otherProvider, err := NewDPoPNonceProvider([]byte("abcdef0123456789abcdef0123456789"), time.Minute)
+	require.NoError(t, err)
+	otherProvider.now = provider.now
+	assert.False(t, provider.ValidNonce(otherProvider.NewNonce()))
+	assert.False(t, provider.ValidNonce(provider.NewNonce()[:20]))
+	assert.False(t, provider.ValidNonce("not base64url!"))
+
+	_, err = NewDPoPNonceProvider([]byte("short"), 0)
+	assert.ErrorIs(t, err, ErrInvalidNonceKey)
+}
+
+func TestVerifyCompactDPoP_Nonce(t *testing.T) {
+	key, err := jwkUtils.GenerateJWK(joseUtils.AlgorithmES256, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+	provider, err := NewDPoPNonceProvider(testNonceKey, 0)
+	require.NoError(t, err)
+	options := &DPoPVerificationOptions{NonceValidator: provider}
+
+	payload := newTestDPoPPayload(time.Now())
+	_, errMsg := VerifyCompactDPoP(newTestCompactDPoP(t, key, nil, payload), nil, nil, nil, options)
+	assert.Equal(t, ErrDPoPNonceMissing, errMsg)
+	assert.True(t, IsDPoPNonceError(errMsg))
+
+	payload["nonce"] = "eyJ7S_zG.eyJH0-Z.HX4w-7v"
+	_, errMsg = VerifyCompactDPoP(newTestCompactDPoP(t, key, nil, payload), nil, nil, nil, options)
+	assert.Equal(t, ErrDPoPInvalidNonce, errMsg)
+	assert.True(t, IsDPoPNonceError(errMsg))
+
+	payload["nonce"] = provider.NewNonce()
+	_, errMsg = VerifyCompactDPoP(newTestCompactDPoP(t, key, nil, payload), nil, nil, nil, options)
+	assert.Empty(t, errMsg)
+	assert.False(t, IsDPoPNonceError(errMsg))
+}
@@ -0,0 +1,187 @@
+package openidUtils
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/joseUtils"
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testDPoPAccessToken = "Kz~8mXK1EalYznwH-LC-1fBAo.4Ljp~zsPE_NeO.gxU"
+	testDPoPHttpMethod  = "GET"
+	testDPoPHttpURL     = "https://resource.example.org/protectedresource"
+)
+
+func newTestDPoPPayload(issuedAt time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"jti": "e1j3V_bKic8-LAEB",
+		"htm": testDPoPHttpMethod,
+		"htu": testDPoPHttpURL,
+		"iat": issuedAt.Unix(),
+		"ath": calculateTestAccessTokenHash(testDPoPAccessToken),
+	}
+}
+
+func newTestCompactDPoP(t *testing.T, key *jwkUtils.JWK, headers joseUtils.Headers, payload map[string]interface{}) *string {
+	publicJWK := jwkUtils.ExportPublicJWK(key)
+	dpopHeaders := joseUtils.Headers{"typ": DPoPHeaderType, "jwk": &publicJWK}
+	for name, value := range headers {
+		dpopHeaders[name] = value
+	}
+
+	compactDPoP, err := joseUtils.SignCompactJWT(dpopHeaders, payload, key)
+	require.NoError(t, err)
+	return &compactDPoP
+}
+
+func TestVerifyCompactDPoP(t *testing.T) {
+	key, err := jwkUtils.GenerateJWK(joseUtils.AlgorithmES256, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+
+	accessToken, httpMethod := testDPoPAccessToken, testDPoPHttpMethod
+	compactDPoP := newTestCompactDPoP(t, key, nil, newTestDPoPPayload(time.Now()))
+
+	dataJWT, errMsg := CheckCompactDPoP(compactDPoP, &accessToken, &httpMethod, nil)
+	require.Empty(t, errMsg)
+	assert.Equal(t, "e1j3V_bKic8-LAEB", dataJWT.Payload["jti"])
+
+	// the "htu" is compared after the normalization
+	httpURL := "HTTPS://Resource.Example.org:443/protectedresource?query=1#fragment"
+	_, errMsg = CheckCompactDPoP(compactDPoP, &accessToken, &httpMethod, &httpURL)
+	assert.Empty(t, errMsg)
+
+	httpURL = "https://resource.example.org/otherresource"
+	_, errMsg = CheckCompactDPoP(compactDPoP, &accessToken, &httpMethod, &httpURL)
+	assert.Equal(t, ErrDPoPMismatchHttpURL, errMsg)
+
+	otherAccessToken := "other"
+	_, errMsg = CheckCompactDPoP(compactDPoP, &otherAccessToken, &httpMethod, nil)
+	assert.Equal(t, ErrDPoPAccessTokenMismatch, errMsg)
+
+	// the signature must be valid for the "jwk" header
+	otherKey, err := jwkUtils.GenerateJWK(joseUtils.AlgorithmES256, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+	otherPublicJWK := jwkUtils.ExportPublicJWK(otherKey)
+	forgedDPoP := newTestCompactDPoP(t, key, joseUtils.Headers{"jwk": &otherPublicJWK}, newTestDPoPPayload(time.Now()))
+	_, errMsg = CheckCompactDPoP(forgedDPoP, &accessToken, &httpMethod, nil)
+	assert.Equal(t, ErrDPoPInvalidSignature, errMsg)
+}
+
+func TestVerifyCompactDPoP_Header(t *testing.T) {
+	key, err := jwkUtils.GenerateJWK(joseUtils.AlgorithmES256, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+	payload := newTestDPoPPayload(time.Now())
+
+	// the "jwk" header cannot contain the private key
+	compactDPoP := newTestCompactDPoP(t, key, joseUtils.Headers{"jwk": key}, payload)
+	_, errMsg := CheckCompactDPoP(compactDPoP, nil, nil, nil)
+	assert.Equal(t, ErrDPoPUnsupportedKey, errMsg)
+
+	compactDPoP = newTestCompactDPoP(t, key, joseUtils.Headers{"typ": "JWT"}, payload)
+	_, errMsg = CheckCompactDPoP(compactDPoP, nil, nil, nil)
+	assert.Equal(t, ErrDPoPInvalidType, errMsg)
+
+	// the "alg" must be in the allow-list
+	compactDPoP = newTestCompactDPoP(t, key, nil, payload)
+	_, errMsg = VerifyCompactDPoP(compactDPoP, nil, nil, nil, &DPoPVerificationOptions{AllowedAlgorithms: []string{joseUtils.AlgorithmEdDSA}})
+	assert.Equal(t, ErrDPoPUnsupportedAlgorithm, errMsg)
+
+	rsaKey, err := jwkUtils.GenerateJWK(joseUtils.AlgorithmRS256, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+	compactDPoP = newTestCompactDPoP(t, rsaKey, nil, payload)
+	_, errMsg = CheckCompactDPoP(compactDPoP, nil, nil, nil)
+	assert.Equal(t, ErrDPoPUnsupportedAlgorithm, errMsg)
+	_, errMsg = VerifyCompactDPoP(compactDPoP, nil, nil, nil, &DPoPVerificationOptions{AllowedAlgorithms: []string{joseUtils.AlgorithmRS256}})
+	assert.Empty(t, errMsg)
+
+	// the "jwk" cannot be used with the "alg"
+	dataJWT := joseUtils.GetDataJWT(newTestCompactDPoP(t, key, nil, payload))
+	dataJWT.Header["alg"] = joseUtils.AlgorithmES384
+	assert.Equal(t, &ErrDPoPUnsupportedKey, CheckDPoPTokenHeaderDataJWT(dataJWT))
+
+	delete(dataJWT.Header, "jwk")
+	assert.Equal(t, &ErrDPoPUnsupportedKey, CheckDPoPTokenHeaderDataJWT(dataJWT))
+}
+
+func TestVerifyCompactDPoP_IssuedAt(t *testing.T) {
+	key, err := jwkUtils.GenerateJWK(joseUtils.AlgorithmEdDSA, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+	options := &DPoPVerificationOptions{MaxAge: time.Minute, Leeway: 5 * time.Second, Now: func() time.Time { return now }}
+
+	_, errMsg := VerifyCompactDPoP(newTestCompactDPoP(t, key, nil, newTestDPoPPayload(now.Add(-time.Minute))), nil, nil, nil, options)
+	assert.Empty(t, errMsg)
+
+	_, errMsg = VerifyCompactDPoP(newTestCompactDPoP(t, key, nil, newTestDPoPPayload(now.Add(-2*time.Minute))), nil, nil, nil, options)
+	assert.Equal(t, ErrDPoPExpired, errMsg)
+
+	_, errMsg = VerifyCompactDPoP(newTestCompactDPoP(t, key, nil, newTestDPoPPayload(now.Add(time.Minute))), nil, nil, nil, options)
+	assert.Equal(t, ErrDPoPIssuedInTheFuture, errMsg)
+
+	payload := newTestDPoPPayload(now)
+	delete(payload, "iat")
+	_, errMsg = VerifyCompactDPoP(newTestCompactDPoP(t, key, nil, payload), nil, nil, nil, options)
+	assert.Equal(t, ErrDPoPMissingRequiredPayloadData, errMsg)
+}
+
+func TestCheckCompactDPoPWithReplayCache(t *testing.T) {
+	key, err := jwkUtils.GenerateJWK(joseUtils.AlgorithmES256, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+	payload := newTestDPoPPayload(time.Now())
+	compactDPoP := newTestCompactDPoP(t, key, nil, payload)
+
+	replayCache := joseUtils.NewInMemoryReplayCache(0)
+	_, errMsg := CheckCompactDPoPWithReplayCache(compactDPoP, nil, nil, nil, replayCache)
+	assert.Empty(t, errMsg)
+
+	_, errMsg = CheckCompactDPoPWithReplayCache(compactDPoP, nil, nil, nil, replayCache)
+	assert.Equal(t, ErrDPoPReplayed, errMsg)
+
+	// the "jti" is scoped by the DPoP key
+	otherKey, err := jwkUtils.GenerateJWK(joseUtils.AlgorithmES256, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+	_, errMsg = CheckCompactDPoPWithReplayCache(newTestCompactDPoP(t, otherKey, nil, payload), nil, nil, nil, replayCache)
+	assert.Empty(t, errMsg)
+
+	// a proof with a wrong signature does not use up the "jti"
+	newPayload := newTestDPoPPayload(time.Now())
+	newPayload["jti"] = "3n9Rb_DqX2kT-WmQ"
+	forgedDPoP := newTestCompactDPoP(t, key, nil, newPayload)
+	parts := strings.Split(*forgedDPoP, ".")
+	otherDPoP := newTestCompactDPoP(t, otherKey, nil, newPayload)
+	parts[2] = strings.Split(*otherDPoP, ".")[2]
+	forgedCompactDPoP := strings.Join(parts, ".")
+	_, errMsg = CheckCompactDPoPWithReplayCache(&forgedCompactDPoP, nil, nil, nil, replayCache)
+	assert.Equal(t, ErrDPoPInvalidSignature, errMsg)
+
+	_, errMsg = CheckCompactDPoPWithReplayCache(newTestCompactDPoP(t, key, nil, newPayload), nil, nil, nil, replayCache)
+	assert.Empty(t, errMsg)
+
+	// the replay cache is optional
+	_, errMsg = CheckDataDPoP(joseUtils.GetDataJWT(compactDPoP), nil, nil, nil)
+	assert.Empty(t, errMsg)
+}
+
+func TestNormalizeDPoPHttpURL(t *testing.T) {
+	testCases := map[string]string{
+		"https://server.example.com/token":                  "https://server.example.com/token",
+		"HTTPS://Server.Example.COM:443/token?a=b#fragment": "https://server.example.com/token",
+		"http://server.example.com:80":                      "http://server.example.com/",
+		"https://server.example.com:8443/Token":             "https://server.example.com:8443/Token",
+		"https://[::1]:443/token":                           "https://[::1]/token",
+	}
+
+	for rawURL, expectedURL := range testCases {
+		normalizedURL, err := NormalizeDPoPHttpURL(rawURL)
+		require.NoError(t, err, rawURL)
+		assert.Equal(t, expectedURL, normalizedURL)
+	}
+
+	_, err := NormalizeDPoPHttpURL("/token")
+	assert.ErrorIs(t, err, ErrInvalidHttpURL)
+}
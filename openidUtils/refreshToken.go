@@ -0,0 +1,304 @@
+package openidUtils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/joseUtils"
+	"github.com/google/uuid"
+)
+
+const (
+	// RefreshTokenSlidingLifetime is the default lifetime of a refresh token since it was issued or rotated.
+	RefreshTokenSlidingLifetime = 14 * 24 * time.Hour
+	// RefreshTokenAbsoluteLifetime is the default lifetime of a refresh token family since the first token was issued.
+	RefreshTokenAbsoluteLifetime = 90 * 24 * time.Hour
+
+	refreshTokenSize = 32 // random bytes of the opaque refresh tokens
+)
+
+// ErrOpenidInvalidGrant is the error code of the token endpoint for an invalid, expired or revoked refresh token (RFC 6749, section 5.2).
+var ErrOpenidInvalidGrant = "invalid_grant"
+
+var (
+	ErrRefreshTokenNotFound      = errors.New("refresh token not found")
+	ErrRefreshTokenInvalid       = errors.New("invalid refresh token")
+	ErrRefreshTokenExpired       = errors.New("the refresh token is expired")
+	ErrRefreshTokenRevoked       = errors.New("the refresh token is revoked")
+	ErrRefreshTokenReused        = errors.New("the refresh token was already used, so its family has been revoked")
+	ErrRefreshTokenKeyBinding    = errors.New("the refresh token requires a DPoP proof of the bound key")
+	ErrRefreshTokenServiceConfig = errors.New("invalid refresh token service")
+)
+
+// RefreshTokenRecord is the persisted data of an opaque refresh token (the token itself is not stored):
+//   - ID: the SHA-256 hash of the refresh token, base64url encoded.
+//   - FamilyID: the refresh tokens rotated from the same grant share it, so they can be revoked together.
+//   - ClientID, Subject and Scope: the grant of the refresh token.
+//   - Confirmation: the "jkt" of the DPoP key of a public client (RFC 9449, section 5).
+//   - IssuedAt and Expiry: the lifetime of this refresh token (sliding, in Unix seconds).
+//   - FamilyExpiry: the absolute lifetime of the family (Unix seconds), the rotated tokens never expire later.
+//   - Used: the refresh token was already rotated, so using it again means it has been stolen.
+//   - Revoked: the family was revoked.
+type RefreshTokenRecord struct {
+	ID           string        `json:"id" bson:"id"`
+	FamilyID     string        `json:"family_id" bson:"family_id"`
+	ClientID     string        `json:"client_id" bson:"client_id"`
+	Subject      string        `json:"sub,omitempty" bson:"sub,omitempty"`
+	Scope        string        `json:"scope,omitempty" bson:"scope,omitempty"`
+	Confirmation *Confirmation `json:"cnf,omitempty" bson:"cnf,omitempty"`
+	IssuedAt     int64         `json:"iat" bson:"iat"`
+	Expiry       int64         `json:"exp" bson:"exp"`
+	FamilyExpiry int64         `json:"family_exp" bson:"family_exp"`
+	Used         bool          `json:"used,omitempty" bson:"used,omitempty"`
+	Revoked      bool          `json:"revoked,omitempty" bson:"revoked,omitempty"`
+}
+
+// RefreshTokenGrant is the grant of a new refresh token family:
+//   - ClientID: REQUIRED.
+//   - Subject and Scope: OPTIONAL.
+//   - PublicClient: the refresh token is bound to the DPoP key of the token request, because the client cannot authenticate.
+type RefreshTokenGrant struct {
+	ClientID     string
+	Subject      string
+	Scope        string
+	PublicClient bool
+}
+
+// RefreshTokenStore persists the refresh token records (e.g.: storageUtils.VaultRefreshTokenStore).
+//   - GetRefreshToken returns ErrRefreshTokenNotFound if the ID does not exist.
+//   - PutRefreshToken creates or updates the record.
+//   - GetRefreshTokenFamily returns every record of the family.
+type RefreshTokenStore interface {
+	GetRefreshToken(id string) (*RefreshTokenRecord, error)
+	PutRefreshToken(record *RefreshTokenRecord) error
+	GetRefreshTokenFamily(familyID string) ([]*RefreshTokenRecord, error)
+}
+
+// RefreshTokenService issues and rotates opaque refresh tokens (OAuth 2.0 Security BCP, section 4.14.2):
+// every refresh token can be used only once and it is rotated by a new one of the same family.
+// If a used refresh token is presented again the whole family is revoked (reuse detection).
+//   - Store: the persistence of the refresh token records.
+//   - SlidingLifetime: the lifetime of every refresh token (RefreshTokenSlidingLifetime if zero).
+//   - AbsoluteLifetime: the lifetime of the family (RefreshTokenAbsoluteLifetime if zero).
+//   - Now: returns the current time (time.Now if nil).
+//
+// Note: the rotation is locked in the service instance, but not in the store itself.
+type RefreshTokenService struct {
+	Store            RefreshTokenStore
+	SlidingLifetime  time.Duration
+	AbsoluteLifetime time.Duration
+	Now              func() time.Time
+
+	mutex sync.Mutex
+}
+
+// NewRefreshTokenService returns a refresh token service for the store and the lifetimes (the defaults if zero).
+func NewRefreshTokenService(store RefreshTokenStore, slidingLifetime, absoluteLifetime time.Duration) (*RefreshTokenService, error) {
+	if store == nil {
+		return nil, ErrRefreshTokenServiceConfig
+	}
+
+	return &RefreshTokenService{
+		Store:            store,
+		SlidingLifetime:  slidingLifetime,
+		AbsoluteLifetime: absoluteLifetime,
+	}, nil
+}
+
+// IssueRefreshToken returns a new refresh token (a new family) and its record.
+// The DPoP proof of the token request (already verified, see VerifyCompactDPoP) is required for a public client.
+func (service *RefreshTokenService) IssueRefreshToken(grant RefreshTokenGrant, dpopDataJWT *joseUtils.DataJWT) (string, *RefreshTokenRecord, error) {
+	if grant.ClientID == "" {
+		return "", nil, fmt.Errorf("%w: the client is required", ErrRefreshTokenInvalid)
+	}
+
+	var confirmation *Confirmation
+	if grant.PublicClient {
+		thumbprint, errMsg := GetDPoPKeyThumbprint(dpopDataJWT)
+		if errMsg != "" {
+			return "", nil, fmt.Errorf("%w: %s", ErrRefreshTokenKeyBinding, errMsg)
+		}
+		confirmation = &Confirmation{JWKThumbprint: thumbprint}
+	}
+
+	familyUUID, err := uuid.NewRandom()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := service.now()
+	record := &RefreshTokenRecord{
+		FamilyID:     familyUUID.String(),
+		ClientID:     grant.ClientID,
+		Subject:      grant.Subject,
+		Scope:        grant.Scope,
+		Confirmation: confirmation,
+		FamilyExpiry: now.Add(service.getAbsoluteLifetime()).Unix(),
+	}
+
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	return service.createRefreshToken(record, now)
+}
+
+// RotateRefreshToken returns a new refresh token of the same family and its record after checking the refresh token:
+// - it exists, it was issued to the client and it is not expired or revoked.
+// - it was not used before, else the family is revoked and ErrRefreshTokenReused is returned.
+// - the DPoP proof of the token request (already verified) has the bound key (if any).
+//
+// The used refresh token cannot be used again.
+func (service *RefreshTokenService) RotateRefreshToken(refreshToken, clientID string, dpopDataJWT *joseUtils.DataJWT) (string, *RefreshTokenRecord, error) {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	record, err := service.getRefreshTokenRecord(refreshToken)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if record.ClientID != clientID {
+		return "", nil, ErrRefreshTokenInvalid
+	}
+	if record.Revoked {
+		return "", nil, ErrRefreshTokenRevoked
+	}
+	if record.Used {
+		if err = service.revokeRefreshTokenFamily(record.FamilyID); err != nil {
+			return "", nil, err
+		}
+		return "", nil, ErrRefreshTokenReused
+	}
+
+	now := service.now()
+	if now.Unix() >= record.Expiry || now.Unix() >= record.FamilyExpiry {
+		return "", nil, ErrRefreshTokenExpired
+	}
+
+	if record.Confirmation != nil {
+		thumbprint, errMsg := GetDPoPKeyThumbprint(dpopDataJWT)
+		if errMsg != "" || thumbprint != record.Confirmation.JWKThumbprint {
+			return "", nil, ErrRefreshTokenKeyBinding
+		}
+	}
+
+	record.Used = true
+	if err = service.Store.PutRefreshToken(record); err != nil {
+		return "", nil, err
+	}
+
+	rotatedRecord := &RefreshTokenRecord{
+		FamilyID:     record.FamilyID,
+		ClientID:     record.ClientID,
+		Subject:      record.Subject,
+		Scope:        record.Scope,
+		Confirmation: record.Confirmation,
+		FamilyExpiry: record.FamilyExpiry,
+	}
+	return service.createRefreshToken(rotatedRecord, now)
+}
+
+// RevokeRefreshToken revokes the family of the refresh token (e.g.: for a token revocation request, RFC 7009).
+func (service *RefreshTokenService) RevokeRefreshToken(refreshToken string) error {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	record, err := service.getRefreshTokenRecord(refreshToken)
+	if err != nil {
+		return err
+	}
+
+	return service.revokeRefreshTokenFamily(record.FamilyID)
+}
+
+// RevokeRefreshTokenFamily revokes every refresh token of the family (e.g.: when the grant is revoked).
+func (service *RefreshTokenService) RevokeRefreshTokenFamily(familyID string) error {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	return service.revokeRefreshTokenFamily(familyID)
+}
+
+// GetRefreshTokenID returns the ID of the record of the refresh token: the SHA-256 hash, base64url encoded.
+func GetRefreshTokenID(refreshToken string) string {
+	refreshTokenHash := sha256.Sum256([]byte(refreshToken))
+	return base64.RawURLEncoding.EncodeToString(refreshTokenHash[:])
+}
+
+// createRefreshToken stores the record with a new random refresh token and the sliding lifetime (until the family expiry).
+func (service *RefreshTokenService) createRefreshToken(record *RefreshTokenRecord, now time.Time) (string, *RefreshTokenRecord, error) {
+	refreshTokenBytes := make([]byte, refreshTokenSize)
+	if _, err := rand.Read(refreshTokenBytes); err != nil {
+		return "", nil, err
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(refreshTokenBytes)
+
+	record.ID = GetRefreshTokenID(refreshToken)
+	record.IssuedAt = now.Unix()
+	record.Expiry = now.Add(service.getSlidingLifetime()).Unix()
+	if record.Expiry > record.FamilyExpiry {
+		record.Expiry = record.FamilyExpiry
+	}
+
+	if err := service.Store.PutRefreshToken(record); err != nil {
+		return "", nil, err
+	}
+	return refreshToken, record, nil
+}
+
+func (service *RefreshTokenService) getRefreshTokenRecord(refreshToken string) (*RefreshTokenRecord, error) {
+	if refreshToken == "" {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	record, err := service.Store.GetRefreshToken(GetRefreshTokenID(refreshToken))
+	if errors.Is(err, ErrRefreshTokenNotFound) {
+		return nil, ErrRefreshTokenInvalid
+	}
+	return record, err
+}
+
+func (service *RefreshTokenService) revokeRefreshTokenFamily(familyID string) error {
+	records, err := service.Store.GetRefreshTokenFamily(familyID)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if record.Revoked {
+			continue
+		}
+
+		record.Revoked = true
+		if err = service.Store.PutRefreshToken(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (service *RefreshTokenService) getSlidingLifetime() time.Duration {
+	if service.SlidingLifetime <= 0 {
+		return RefreshTokenSlidingLifetime
+	}
+	return service.SlidingLifetime
+}
+
+func (service *RefreshTokenService) getAbsoluteLifetime() time.Duration {
+	if service.AbsoluteLifetime <= 0 {
+		return RefreshTokenAbsoluteLifetime
+	}
+	return service.AbsoluteLifetime
+}
+
+func (service *RefreshTokenService) now() time.Time {
+	if service.Now == nil {
+		return time.Now()
+	}
+	return service.Now()
+}
@@ -0,0 +1,151 @@
+package openidUtils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/joseUtils"
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testRefreshTokenStore is a RefreshTokenStore in memory.
+type testRefreshTokenStore map[string]RefreshTokenRecord
+
+func (store testRefreshTokenStore) GetRefreshToken(id string) (*RefreshTokenRecord, error) {
+	record, found := store[id]
+	if !found {
+		return nil, ErrRefreshTokenNotFound
+	}
+	return &record, nil
+}
+
+func (store testRefreshTokenStore) PutRefreshToken(record *RefreshTokenRecord) error {
+	store[record.ID] = *record
+	return nil
+}
+
+func (store testRefreshTokenStore) GetRefreshTokenFamily(familyID string) ([]*RefreshTokenRecord, error) {
+	var records []*RefreshTokenRecord
+	for _, record := range store {
+		if record.FamilyID == familyID {
+			recordCopy := record
+			records = append(records, &recordCopy)
+		}
+	}
+	return records, nil
+}
+
+func newTestRefreshTokenService(t *testing.T, now *time.Time) (*RefreshTokenService, testRefreshTokenStore) {
+	store := testRefreshTokenStore{}
+	service, err := NewRefreshTokenService(store, time.Hour, 3*time.Hour)
+	require.NoError(t, err)
+	service.Now = func() time.Time { return *now }
+	return service, store
+}
+
+func TestRefreshTokenService_Rotate(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	service, store := newTestRefreshTokenService(t, &now)
+
+	grant := RefreshTokenGrant{ClientID: testAccessTokenClientID, Subject: "did:example:alice", Scope: "openid offline_access"}
+	refreshToken, record, err := service.IssueRefreshToken(grant, nil)
+	require.NoError(t, err)
+	assert.Equal(t, GetRefreshTokenID(refreshToken), record.ID)
+	assert.Equal(t, now.Add(time.Hour).Unix(), record.Expiry)
+	assert.Equal(t, now.Add(3*time.Hour).Unix(), record.FamilyExpiry)
+	assert.Nil(t, record.Confirmation)
+	assert.NotContains(t, store, refreshToken, "the refresh token is not stored")
+
+	_, _, err = service.RotateRefreshToken(refreshToken, "did:example:other#key-1", nil)
+	assert.ErrorIs(t, err, ErrRefreshTokenInvalid)
+
+	now = now.Add(30 * time.Minute)
+	rotatedToken, rotatedRecord, err := service.RotateRefreshToken(refreshToken, testAccessTokenClientID, nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, refreshToken, rotatedToken)
+	assert.Equal(t, record.FamilyID, rotatedRecord.FamilyID)
+	assert.Equal(t, grant.Scope, rotatedRecord.Scope)
+	assert.Equal(t, now.Add(time.Hour).Unix(), rotatedRecord.Expiry, "sliding lifetime")
+
+	// reusing the rotated refresh token revokes the family
+	_, _, err = service.RotateRefreshToken(refreshToken, testAccessTokenClientID, nil)
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+	_, _, err = service.RotateRefreshToken(rotatedToken, testAccessTokenClientID, nil)
+	assert.ErrorIs(t, err, ErrRefreshTokenRevoked)
+
+	_, _, err = service.RotateRefreshToken("unknown", testAccessTokenClientID, nil)
+	assert.ErrorIs(t, err, ErrRefreshTokenInvalid)
+}
+
+func TestRefreshTokenService_Lifetimes(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	service, _ := newTestRefreshTokenService(t, &now)
+	grant := RefreshTokenGrant{ClientID: testAccessTokenClientID}
+
+	// sliding lifetime
+	refreshToken, _, err := service.IssueRefreshToken(grant, nil)
+	require.NoError(t, err)
+	now = now.Add(time.Hour)
+	_, _, err = service.RotateRefreshToken(refreshToken, testAccessTokenClientID, nil)
+	assert.ErrorIs(t, err, ErrRefreshTokenExpired)
+
+	// absolute lifetime: the rotated tokens never expire after the family
+	refreshToken, record, err := service.IssueRefreshToken(grant, nil)
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		now = now.Add(50 * time.Minute)
+		refreshToken, record, err = service.RotateRefreshToken(refreshToken, testAccessTokenClientID, nil)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, record.Expiry, record.FamilyExpiry)
+	}
+	assert.Equal(t, record.FamilyExpiry, record.Expiry)
+
+	now = time.Unix(record.FamilyExpiry, 0)
+	_, _, err = service.RotateRefreshToken(refreshToken, testAccessTokenClientID, nil)
+	assert.ErrorIs(t, err, ErrRefreshTokenExpired)
+}
+
+func TestRefreshTokenService_DPoPBinding(t *testing.T) {
+	now := time.Now()
+	service, _ := newTestRefreshTokenService(t, &now)
+	dpopKey, err := jwkUtils.GenerateJWK(joseUtils.AlgorithmES256, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+	dpopProof := joseUtils.GetDataJWT(newTestCompactDPoP(t, dpopKey, nil, newTestDPoPPayload(now)))
+
+	// a public client requires a DPoP proof
+	grant := RefreshTokenGrant{ClientID: testAccessTokenClientID, PublicClient: true}
+	_, _, err = service.IssueRefreshToken(grant, nil)
+	assert.ErrorIs(t, err, ErrRefreshTokenKeyBinding)
+
+	refreshToken, record, err := service.IssueRefreshToken(grant, dpopProof)
+	require.NoError(t, err)
+	require.NotNil(t, record.Confirmation)
+
+	otherKey, err := jwkUtils.GenerateJWK(joseUtils.AlgorithmES256, jwkUtils.JWKeySignType)
+	require.NoError(t, err)
+	otherProof := joseUtils.GetDataJWT(newTestCompactDPoP(t, otherKey, nil, newTestDPoPPayload(now)))
+	_, _, err = service.RotateRefreshToken(refreshToken, testAccessTokenClientID, otherProof)
+	assert.ErrorIs(t, err, ErrRefreshTokenKeyBinding)
+
+	// the failed rotation does not use the refresh token
+	_, rotatedRecord, err := service.RotateRefreshToken(refreshToken, testAccessTokenClientID, dpopProof)
+	require.NoError(t, err)
+	assert.Equal(t, record.Confirmation, rotatedRecord.Confirmation)
+}
+
+func TestRefreshTokenService_Revoke(t *testing.T) {
+	now := time.Now()
+	service, _ := newTestRefreshTokenService(t, &now)
+
+	refreshToken, _, err := service.IssueRefreshToken(RefreshTokenGrant{ClientID: testAccessTokenClientID}, nil)
+	require.NoError(t, err)
+	require.NoError(t, service.RevokeRefreshToken(refreshToken))
+
+	_, _, err = service.RotateRefreshToken(refreshToken, testAccessTokenClientID, nil)
+	assert.ErrorIs(t, err, ErrRefreshTokenRevoked)
+
+	_, err = NewRefreshTokenService(nil, 0, 0)
+	assert.ErrorIs(t, err, ErrRefreshTokenServiceConfig)
+}
@@ -0,0 +1,161 @@
+package storageUtils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/joseUtils"
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+	"github.com/Universal-Health-Chain/common-utils-golang/openidUtils"
+
+	ariesStorage "github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+const (
+	// refreshTokenFamilyIndexName is the indexed attribute (protected by the HMAC key) to query the refresh token families.
+	refreshTokenFamilyIndexName = "refreshTokenFamily"
+	// refreshTokenMinHmacKeyLength is the minimum size of the HMAC key for the document IDs and the indexes.
+	refreshTokenMinHmacKeyLength = 32
+)
+
+// VaultRefreshTokenStore is an openidUtils.RefreshTokenStore on top of a vault of a SecureStorage (e.g.: PrivateStorage):
+//   - the ID of every document is generated deterministically from the refresh token ID with the HMAC key.
+//   - the family ID is an indexed attribute protected with the HMAC key.
+//   - the record is encrypted in the JWE of the document for the public key of the encryption key.
+type VaultRefreshTokenStore struct {
+	storage       SecureStorage
+	vaultID       string
+	hmacKey       []byte
+	encryptionKey *jwkUtils.JWK
+}
+
+// NewVaultRefreshTokenStore returns a refresh token store for an existing vault (see SecureStorage.CreateNewVault),
+// the HMAC key (at least 32 bytes) and the private key to encrypt and decrypt the records (e.g.: X25519 or EC P-256).
+func NewVaultRefreshTokenStore(storage SecureStorage, vaultID string, hmacKey []byte, encryptionKey *jwkUtils.JWK) (*VaultRefreshTokenStore, error) {
+	if storage == nil || vaultID == "" {
+		return nil, errors.New("the secure storage and the vault ID are required")
+	}
+
+	if len(hmacKey) < refreshTokenMinHmacKeyLength {
+		return nil, fmt.Errorf("the HMAC key must have at least %d bytes", refreshTokenMinHmacKeyLength)
+	}
+
+	if encryptionKey == nil || encryptionKey.D == nil {
+		return nil, jwkUtils.ErrMissingKeyMaterial
+	}
+
+	vaultExists, err := storage.VaultExists(vaultID)
+	if err != nil {
+		return nil, err
+	}
+	if !vaultExists {
+		return nil, fmt.Errorf("the vault %s does not exist", vaultID)
+	}
+
+	return &VaultRefreshTokenStore{
+		storage:       storage,
+		vaultID:       vaultID,
+		hmacKey:       append([]byte{}, hmacKey...),
+		encryptionKey: encryptionKey,
+	}, nil
+}
+
+// GetRefreshToken returns the record with the ID or openidUtils.ErrRefreshTokenNotFound.
+func (store *VaultRefreshTokenStore) GetRefreshToken(id string) (*openidUtils.RefreshTokenRecord, error) {
+	documentBytes, err := store.storage.Get(store.vaultID, store.getDocumentID(id))
+	if errors.Is(err, ariesStorage.ErrDataNotFound) {
+		return nil, openidUtils.ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var encryptedDocument EncryptedDocument
+	if err = json.Unmarshal(documentBytes, &encryptedDocument); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal encrypted document bytes: %w", err)
+	}
+
+	record, err := store.decryptRecord(encryptedDocument)
+	if err != nil {
+		return nil, err
+	}
+
+	// the record cannot be moved to the document of another ID
+	if record.ID != id {
+		return nil, openidUtils.ErrRefreshTokenNotFound
+	}
+	return record, nil
+}
+
+// PutRefreshToken encrypts and stores the record, indexed by its family ID.
+func (store *VaultRefreshTokenStore) PutRefreshToken(record *openidUtils.RefreshTokenRecord) error {
+	recordBytes, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	publicKey := jwkUtils.ExportPublicJWK(store.encryptionKey)
+	jwe, err := joseUtils.EncryptJWE(recordBytes, []*jwkUtils.JWK{&publicKey}, nil)
+	if err != nil {
+		return err
+	}
+
+	jweBytes, err := jwe.SerializeMultiRecipientBytes(json.Marshal)
+	if err != nil {
+		return err
+	}
+
+	familyIndex := []models.IndexedAttribute{{Name: refreshTokenFamilyIndexName, Value: record.FamilyID}}
+	encryptedDocument := EncryptedDocument{
+		ID:                          store.getDocumentID(record.ID),
+		IndexedAttributeCollections: []models.IndexedAttributeCollection{CreateIndexedHmacData(familyIndex, store.hmacKey)},
+		JWE:                         jweBytes,
+	}
+
+	return store.storage.Put(store.vaultID, encryptedDocument)
+}
+
+// GetRefreshTokenFamily returns the records of the family.
+func (store *VaultRefreshTokenStore) GetRefreshTokenFamily(familyID string) ([]*openidUtils.RefreshTokenRecord, error) {
+	encryptedDocuments, err := store.storage.Query(store.vaultID, ComposeGenericQuery(refreshTokenFamilyIndexName, familyID, store.hmacKey))
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*openidUtils.RefreshTokenRecord, 0, len(encryptedDocuments))
+	for _, encryptedDocument := range encryptedDocuments {
+		record, err := store.decryptRecord(encryptedDocument)
+		if err != nil {
+			return nil, err
+		}
+
+		if record.FamilyID == familyID {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+func (store *VaultRefreshTokenStore) getDocumentID(id string) string {
+	return GenerateIdentifierDeterministicallyForEDV(id, store.hmacKey)
+}
+
+func (store *VaultRefreshTokenStore) decryptRecord(encryptedDocument EncryptedDocument) (*openidUtils.RefreshTokenRecord, error) {
+	jwe, err := joseUtils.DeserializeJWE(string(encryptedDocument.JWE))
+	if err != nil {
+		return nil, err
+	}
+
+	recordBytes, err := joseUtils.DecryptJWE(jwe, store.encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &openidUtils.RefreshTokenRecord{}
+	if err = json.Unmarshal(recordBytes, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
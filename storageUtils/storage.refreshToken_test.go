@@ -0,0 +1,84 @@
+package storageUtils
+
+import (
+	"testing"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/jwkUtils"
+	"github.com/Universal-Health-Chain/common-utils-golang/openidUtils"
+	ariesStorageMem "github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edv/pkg/restapi/edv/models"
+)
+
+var testRefreshTokenHmacKey = []byte("0123456789abcdef0123456789abcdef")
+
+func newTestVaultRefreshTokenStore(t *testing.T) *VaultRefreshTokenStore {
+	storageService := NewStorageService(ariesStorageMem.NewProvider())
+	privateStorage, err := CreatePrivateStorageInMemoryStorageService(*storageService, "config", "documents", 100)
+	require.NoError(t, err)
+	require.NoError(t, privateStorage.CreateNewVault("refreshTokens", &models.DataVaultConfiguration{ReferenceID: "refreshTokens"}))
+
+	encryptionKey, err := jwkUtils.GenerateJWK(jwkUtils.AlgorithmX25519, "")
+	require.NoError(t, err)
+
+	store, err := NewVaultRefreshTokenStore(privateStorage, "refreshTokens", testRefreshTokenHmacKey, encryptionKey)
+	require.NoError(t, err)
+
+	_, err = NewVaultRefreshTokenStore(privateStorage, "unknown", testRefreshTokenHmacKey, encryptionKey)
+	assert.Error(t, err)
+	return store
+}
+
+func TestVaultRefreshTokenStore(t *testing.T) {
+	store := newTestVaultRefreshTokenStore(t)
+
+	record := &openidUtils.RefreshTokenRecord{ID: "id-1", FamilyID: "family-1", ClientID: "client", Expiry: 1700000000}
+	require.NoError(t, store.PutRefreshToken(record))
+	require.NoError(t, store.PutRefreshToken(&openidUtils.RefreshTokenRecord{ID: "id-2", FamilyID: "family-1"}))
+	require.NoError(t, store.PutRefreshToken(&openidUtils.RefreshTokenRecord{ID: "id-3", FamilyID: "family-2"}))
+
+	storedRecord, err := store.GetRefreshToken("id-1")
+	require.NoError(t, err)
+	assert.Equal(t, record, storedRecord)
+
+	// the records are encrypted
+	documentBytes, err := store.storage.Get(store.vaultID, store.getDocumentID("id-1"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(documentBytes), "family-1")
+
+	record.Used = true
+	require.NoError(t, store.PutRefreshToken(record))
+	storedRecord, err = store.GetRefreshToken("id-1")
+	require.NoError(t, err)
+	assert.True(t, storedRecord.Used)
+
+	_, err = store.GetRefreshToken("unknown")
+	assert.ErrorIs(t, err, openidUtils.ErrRefreshTokenNotFound)
+
+	family, err := store.GetRefreshTokenFamily("family-1")
+	require.NoError(t, err)
+	assert.Len(t, family, 2)
+}
+
+func TestVaultRefreshTokenStore_ReuseDetection(t *testing.T) {
+	service, err := openidUtils.NewRefreshTokenService(newTestVaultRefreshTokenStore(t), 0, 0)
+	require.NoError(t, err)
+
+	grant := openidUtils.RefreshTokenGrant{ClientID: "client"}
+	refreshToken, _, err := service.IssueRefreshToken(grant, nil)
+	require.NoError(t, err)
+	rotatedToken, _, err := service.RotateRefreshToken(refreshToken, "client", nil)
+	require.NoError(t, err)
+
+	_, _, err = service.RotateRefreshToken(refreshToken, "client", nil)
+	assert.ErrorIs(t, err, openidUtils.ErrRefreshTokenReused)
+	_, _, err = service.RotateRefreshToken(rotatedToken, "client", nil)
+	assert.ErrorIs(t, err, openidUtils.ErrRefreshTokenRevoked)
+
+	// other families are not revoked
+	otherToken, _, err := service.IssueRefreshToken(grant, nil)
+	require.NoError(t, err)
+	_, _, err = service.RotateRefreshToken(otherToken, "client", nil)
+	assert.NoError(t, err)
+}
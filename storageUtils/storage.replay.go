@@ -0,0 +1,153 @@
+package storageUtils
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/joseUtils"
+
+	ariesStorage "github.com/hyperledger/aries-framework-go/spi/storage"
+)
+
+const (
+	// ReplayStoreName is the default name of the store for the replay cache.
+	ReplayStoreName = "replay"
+	// replayExpirationTagName is the tag with the expiration time (Unix seconds) of every entry.
+	replayExpirationTagName = "replayExpiresAt"
+)
+
+// StorageReplayCache is a joseUtils.ReplayCache on top of an Aries storage provider,
+// so the identifiers of the tokens already received are kept when the service restarts.
+// The expired entries are ignored when checking and they can be removed by calling EvictExpired periodically.
+// Note: the Aries stores have no atomic insert-if-absent, so CheckAndStore is only atomic within this cache
+// (a mutex is held between the Get and the Put). Several instances sharing the store can accept the same ID
+// if they receive it at the same time, so a replay is only fully prevented when a single instance checks the IDs.
+type StorageReplayCache struct {
+	store ariesStorage.Store
+	now   func() time.Time
+
+	mutex sync.Mutex
+}
+
+// NewStorageReplayCache opens (or creates) the store with the given name (ReplayStoreName if empty) in the provider.
+func NewStorageReplayCache(storageProvider ariesStorage.Provider, storeName string) (*StorageReplayCache, error) {
+	if storageProvider == nil {
+		return nil, errors.New("the storage provider cannot be nil")
+	}
+
+	if storeName == "" {
+		storeName = ReplayStoreName
+	}
+
+	store, err := storageProvider.OpenStore(storeName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the replay store: %w", err)
+	}
+
+	err = storageProvider.SetStoreConfig(storeName, ariesStorage.StoreConfiguration{TagNames: []string{replayExpirationTagName}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set the replay store configuration: %w", err)
+	}
+
+	return &StorageReplayCache{
+		store: store,
+		now:   time.Now,
+	}, nil
+}
+
+// CheckAndStore returns joseUtils.ErrReplayDetected if the ID was already stored and has not expired,
+// else it stores the ID until expiresAt. It is safe for concurrent use within the instance (see StorageReplayCache).
+func (cache *StorageReplayCache) CheckAndStore(id string, expiresAt time.Time) error {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	now := cache.now()
+
+	storedExpiration, err := cache.getExpiration(id)
+	if err != nil {
+		return err
+	}
+	if storedExpiration != nil && now.Before(*storedExpiration) {
+		return joseUtils.ErrReplayDetected
+	}
+
+	if !now.Before(expiresAt) {
+		return nil
+	}
+
+	expirationValue := strconv.FormatInt(expiresAt.Unix(), 10)
+	err = cache.store.Put(id, []byte(expirationValue), ariesStorage.Tag{Name: replayExpirationTagName, Value: expirationValue})
+	if err != nil {
+		return fmt.Errorf("failed to store the token ID: %w", err)
+	}
+	return nil
+}
+
+// EvictExpired removes the expired entries from the store.
+func (cache *StorageReplayCache) EvictExpired() error {
+	iterator, err := cache.store.Query(replayExpirationTagName)
+	if err != nil {
+		return fmt.Errorf("failed to query the replay store: %w", err)
+	}
+	defer iterator.Close()
+
+	now := cache.now()
+	var expiredIDs []string
+	for {
+		more, err := iterator.Next()
+		if err != nil {
+			return fmt.Errorf("failed to query the replay store: %w", err)
+		}
+		if !more {
+			break
+		}
+
+		id, err := iterator.Key()
+		if err != nil {
+			return fmt.Errorf("failed to query the replay store: %w", err)
+		}
+
+		value, err := iterator.Value()
+		if err != nil {
+			return fmt.Errorf("failed to query the replay store: %w", err)
+		}
+
+		expiration, err := parseReplayExpiration(value)
+		if err != nil || !now.Before(*expiration) {
+			expiredIDs = append(expiredIDs, id)
+		}
+	}
+
+	for _, id := range expiredIDs {
+		if err = cache.store.Delete(id); err != nil {
+			return fmt.Errorf("failed to delete the expired token ID: %w", err)
+		}
+	}
+	return nil
+}
+
+// getExpiration returns the stored expiration time of the ID or nil if it does not exist.
+func (cache *StorageReplayCache) getExpiration(id string) (*time.Time, error) {
+	value, err := cache.store.Get(id)
+	if err != nil {
+		if errors.Is(err, ariesStorage.ErrDataNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unexpected error while checking the token ID: %w", err)
+	}
+
+	return parseReplayExpiration(value)
+}
+
+func parseReplayExpiration(value []byte) (*time.Time, error) {
+	seconds, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expiration time in the replay store: %w", err)
+	}
+
+	expiration := time.Unix(seconds, 0)
+	return &expiration, nil
+}
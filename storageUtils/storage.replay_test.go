@@ -0,0 +1,68 @@
+package storageUtils
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Universal-Health-Chain/common-utils-golang/joseUtils"
+	ariesStorageMem "github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageReplayCache(t *testing.T) {
+	provider := ariesStorageMem.NewProvider()
+	cache, err := NewStorageReplayCache(provider, "")
+	require.NoError(t, err)
+
+	now := time.Unix(1700000000, 0)
+	cache.now = func() time.Time { return now }
+
+	require.NoError(t, cache.CheckAndStore("dpop+jwt:jti-1", now.Add(time.Minute)))
+	assert.ErrorIs(t, cache.CheckAndStore("dpop+jwt:jti-1", now.Add(time.Minute)), joseUtils.ErrReplayDetected)
+	require.NoError(t, cache.CheckAndStore("jti-2", now.Add(time.Hour)))
+
+	// another instance sharing the same provider detects a later replay (not a concurrent one, see StorageReplayCache)
+	otherCache, err := NewStorageReplayCache(provider, ReplayStoreName)
+	require.NoError(t, err)
+	otherCache.now = cache.now
+	assert.ErrorIs(t, otherCache.CheckAndStore("jti-2", now.Add(time.Hour)), joseUtils.ErrReplayDetected)
+
+	// an expired ID is not stored
+	require.NoError(t, cache.CheckAndStore("jti-3", now))
+	_, err = cache.store.Get("jti-3")
+	assert.Error(t, err)
+
+	now = now.Add(time.Minute)
+	require.NoError(t, cache.EvictExpired())
+	_, err = cache.store.Get("dpop+jwt:jti-1")
+	assert.Error(t, err)
+	_, err = cache.store.Get("jti-2")
+	assert.NoError(t, err)
+	assert.NoError(t, cache.CheckAndStore("dpop+jwt:jti-1", now.Add(time.Minute)))
+
+	_, err = NewStorageReplayCache(nil, "")
+	assert.Error(t, err)
+}
+
+func TestStorageReplayCache_Concurrent(t *testing.T) {
+	cache, err := NewStorageReplayCache(ariesStorageMem.NewProvider(), "")
+	require.NoError(t, err)
+
+	var accepted int32
+	var waitGroup sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			if cache.CheckAndStore("jti-1", time.Now().Add(time.Minute)) == nil {
+				atomic.AddInt32(&accepted, 1)
+			}
+		}()
+	}
+	waitGroup.Wait()
+
+	assert.Equal(t, int32(1), accepted)
+}